@@ -0,0 +1,63 @@
+// rotate_keys 使用当前主密钥重新加密数据库中的敏感字段
+//
+// 轮换步骤：
+//  1. 生成新密钥：go run ./cmd/rotate_keys -generate
+//  2. 将原主密钥移到 security.encryption.previous_keys（或 VULNARK_PREVIOUS_KEYS），
+//     新密钥设为 master_key 并使用新的 key_id
+//  3. 执行 go run ./cmd/rotate_keys 重新加密，确认无误后即可删除旧密钥
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/spf13/viper"
+	"github.com/vulnark/vulnark/secret"
+	"github.com/vulnark/vulnark/utils"
+)
+
+func main() {
+	configPath := flag.String("config", "./config", "配置文件目录")
+	dryRun := flag.Bool("dry-run", false, "只统计需要重新加密的记录，不写入数据库")
+	generate := flag.Bool("generate", false, "生成新的主密钥并退出")
+	flag.Parse()
+
+	if *generate {
+		key, err := secret.GenerateKey()
+		if err != nil {
+			log.Fatalf("生成主密钥失败: %v", err)
+		}
+		fmt.Println(key)
+		return
+	}
+
+	viper.SetConfigName("config")
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath(*configPath)
+	if err := viper.ReadInConfig(); err != nil {
+		log.Fatalf("无法读取配置文件: %v", err)
+	}
+
+	if err := secret.Validate(); err != nil {
+		log.Fatalf("加密主密钥配置错误: %v", err)
+	}
+	if !secret.Enabled() {
+		log.Fatalf("未配置加密主密钥（security.encryption.master_key 或 VULNARK_MASTER_KEY）")
+	}
+
+	utils.InitDB()
+	defer utils.CloseDB()
+
+	stats, err := utils.RotateSecrets(*dryRun)
+	if err != nil {
+		log.Fatalf("重新加密失败: %v", err)
+	}
+
+	action := "已重新加密"
+	if *dryRun {
+		action = "需要重新加密"
+	}
+	fmt.Printf("%s: 扫描任务 %d 条，扫描器连接配置 %d 条，系统设置 %d 条（当前主密钥: %s）\n",
+		action, stats.ScanTasks, stats.ScannerProfiles, stats.Settings, secret.PrimaryKeyID())
+}
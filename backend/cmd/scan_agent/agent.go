@@ -0,0 +1,467 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/scanner"
+)
+
+const (
+	retryInterval       = 10 * time.Second // 连接服务端失败后的重试间隔
+	reportTimeout       = 30 * time.Second // 上报事件、结果和结束状态的超时时间
+	eventFlushInterval  = 2 * time.Second  // 扫描事件的上报间隔
+	eventBatchSize      = 500              // 每次上报的最大事件数
+	maxPendingEvents    = 5000             // 上报失败时缓存的最大事件数
+	maxScanLogSize      = 60 * 1024        // 扫描日志上限，与服务端保存上限一致
+	completeMaxAttempts = 3                // 上报扫描结束的最大尝试次数
+)
+
+// agent 扫描代理，按并发数运行多个领取作业的工作协程
+type agent struct {
+	client      *client
+	types       []models.ScannerType
+	concurrency int
+	hostname    string
+	config      *scanner.AgentConfig
+
+	mu      sync.Mutex
+	running map[uint]*runningScan
+}
+
+// runningScan 代理上正在执行的扫描
+type runningScan struct {
+	cancel context.CancelFunc
+
+	mu          sync.Mutex
+	keepPartial bool
+	abandoned   bool // 服务端已结束该次执行，不再上报
+	shutdown    bool // 代理停止导致的取消
+	stopped     bool
+}
+
+// stop 取消扫描，由执行流程停止扫描器上的扫描
+func (s *runningScan) stop(apply func(s *runningScan)) {
+	s.mu.Lock()
+	apply(s)
+	s.stopped = true
+	s.mu.Unlock()
+	s.cancel()
+}
+
+// isStopped 扫描是否已被取消
+func (s *runningScan) isStopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopped
+}
+
+// state 取消原因
+func (s *runningScan) state() (keepPartial, abandoned, shutdown bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keepPartial, s.abandoned, s.shutdown
+}
+
+func newAgent(c *client, types []models.ScannerType, concurrency int) *agent {
+	hostname, _ := os.Hostname()
+	return &agent{
+		client:      c,
+		types:       types,
+		concurrency: concurrency,
+		hostname:    hostname,
+		running:     make(map[uint]*runningScan),
+	}
+}
+
+// run 注册代理并领取执行扫描作业，ctx 结束后停止正在执行的扫描并返回
+func (a *agent) run(ctx context.Context) {
+	for {
+		config, err := a.client.register(ctx, a.registration())
+		if err == nil {
+			a.config = config
+			break
+		}
+		log.Printf("扫描代理注册失败，%s后重试: %v", retryInterval, err)
+		if !sleep(ctx, retryInterval) {
+			return
+		}
+	}
+	log.Printf("扫描代理已注册: agent_id=%d, name=%s, zone=%s, types=%v, concurrency=%d",
+		a.config.AgentID, a.config.Name, a.config.Zone, a.types, a.concurrency)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.heartbeatLoop(ctx)
+	}()
+	for i := 0; i < a.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.worker(ctx)
+		}()
+	}
+
+	<-ctx.Done()
+	log.Printf("扫描代理正在停止，取消正在执行的扫描")
+	a.mu.Lock()
+	for _, scan := range a.running {
+		scan.stop(func(s *runningScan) { s.shutdown = true })
+	}
+	a.mu.Unlock()
+	wg.Wait()
+	log.Printf("扫描代理已停止")
+}
+
+// registration 注册和心跳时上报的信息
+func (a *agent) registration() *scanner.AgentRegistration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	runs := make([]uint, 0, len(a.running))
+	for runID := range a.running {
+		runs = append(runs, runID)
+	}
+	return &scanner.AgentRegistration{
+		Hostname:     a.hostname,
+		Version:      agentVersion,
+		ScannerTypes: a.types,
+		RunningRuns:  runs,
+	}
+}
+
+// heartbeatLoop 定期发送心跳，并取消服务端要求取消的扫描
+func (a *agent) heartbeatLoop(ctx context.Context) {
+	interval := time.Duration(a.config.HeartbeatInterval) * time.Second
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cancels, err := a.client.heartbeat(ctx, a.registration())
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("发送心跳失败: %v", err)
+			}
+			continue
+		}
+		for _, c := range cancels {
+			a.cancelRun(c.RunID, c.KeepPartial)
+		}
+	}
+}
+
+// worker 长轮询领取扫描作业并执行
+func (a *agent) worker(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := a.client.poll(ctx, a.config.PollWait)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("领取扫描作业失败，%s后重试: %v", retryInterval, err)
+			sleep(ctx, retryInterval)
+			continue
+		}
+		if job == nil {
+			continue
+		}
+		a.execute(ctx, job)
+	}
+}
+
+// cancelRun 取消正在执行的扫描
+func (a *agent) cancelRun(runID uint, keepPartial bool) {
+	a.mu.Lock()
+	scan, ok := a.running[runID]
+	a.mu.Unlock()
+	if !ok || scan.isStopped() {
+		return
+	}
+	log.Printf("服务端要求取消扫描: run_id=%d, keep_partial=%v", runID, keepPartial)
+	scan.stop(func(s *runningScan) { s.keepPartial = keepPartial })
+}
+
+// abandonRun 服务端已结束该次执行，停止扫描且不再上报
+func (a *agent) abandonRun(runID uint) {
+	a.mu.Lock()
+	scan, ok := a.running[runID]
+	a.mu.Unlock()
+	if !ok {
+		return
+	}
+	log.Printf("扫描作业已在服务端结束，停止扫描: run_id=%d", runID)
+	scan.stop(func(s *runningScan) { s.abandoned = true })
+}
+
+// execute 在本地执行扫描作业，上传结果并上报结束状态，ctx 为代理的运行上下文
+func (a *agent) execute(ctx context.Context, job *scanner.AgentJob) {
+	runCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	scan := &runningScan{cancel: cancel}
+	a.mu.Lock()
+	a.running[job.RunID] = scan
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		delete(a.running, job.RunID)
+		a.mu.Unlock()
+	}()
+
+	// 领取作业时代理恰好开始停止，停止流程可能已遍历过正在执行的扫描
+	if ctx.Err() != nil {
+		scan.stop(func(s *runningScan) { s.shutdown = true })
+	}
+
+	log.Printf("开始执行扫描作业: task_id=%d, run_id=%d, type=%s", job.TaskID, job.RunID, job.Job.Type)
+
+	reporter := newEventReporter(a.client, job.RunID, a.cancelRun, a.abandonRun)
+	var (
+		logMu    sync.Mutex
+		logBuf   strings.Builder
+		progress int
+	)
+	job.Job.Logf = func(format string, args ...interface{}) {
+		message := fmt.Sprintf(format, args...)
+		log.Printf("[扫描任务 %d] %s", job.TaskID, message)
+
+		logMu.Lock()
+		defer logMu.Unlock()
+		reporter.add(scanner.AgentEvent{Type: models.ScanEventLog, Progress: progress, Message: message})
+		line := time.Now().Format("2006-01-02 15:04:05") + " " + message + "\n"
+		if logBuf.Len()+len(line) <= maxScanLogSize {
+			logBuf.WriteString(line)
+		}
+	}
+
+	var (
+		results []models.ScanResult
+		err     error
+	)
+	driver, err := scanner.Lookup(job.Job.Type)
+	if err == nil {
+		var lastMessage string
+		results, err = scanner.Run(runCtx, driver, job.Job, scanner.RunOptions{
+			PollInterval: time.Duration(a.config.PollInterval) * time.Second,
+			OnStatus: func(status *scanner.Status) {
+				logMu.Lock()
+				defer logMu.Unlock()
+				if status.Progress != progress || status.Message != lastMessage {
+					reporter.add(scanner.AgentEvent{Type: models.ScanEventProgress, Progress: status.Progress, Message: status.Message})
+				}
+				progress, lastMessage = status.Progress, status.Message
+			},
+			KeepPartial: func() bool {
+				keepPartial, _, _ := scan.state()
+				return keepPartial
+			},
+		})
+	}
+
+	_, abandoned, shutdown := scan.state()
+	cancelled := runCtx.Err() != nil || errors.Is(err, scanner.ErrScanCancelled)
+	completion := scanner.AgentCompletion{State: scanner.StateCompleted}
+	switch {
+	case abandoned:
+		reporter.close()
+		return
+	case shutdown:
+		completion.State = scanner.StateFailed
+		completion.Error = "扫描代理已停止"
+		completion.FailureReason = models.ScanFailureAgent
+		results = nil
+	case cancelled:
+		completion.State = scanner.StateCancelled
+	case err != nil:
+		completion.State = scanner.StateFailed
+		completion.Error = err.Error()
+		completion.FailureReason = scanner.ClassifyError(err)
+		results = nil
+	}
+
+	if err := a.uploadResults(job.RunID, results); err != nil {
+		if errors.Is(err, errRunFinished) {
+			reporter.close()
+			return
+		}
+		completion.State = scanner.StateFailed
+		completion.Error = "上传扫描结果失败: " + err.Error()
+		completion.FailureReason = models.ScanFailureAgent
+	}
+
+	reporter.close()
+	logMu.Lock()
+	completion.Progress = progress
+	completion.ScanLog = logBuf.String()
+	logMu.Unlock()
+
+	for attempt := 1; attempt <= completeMaxAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+		err = a.client.complete(ctx, job.RunID, &completion)
+		cancel()
+		if err == nil || errors.Is(err, errRunFinished) {
+			break
+		}
+		log.Printf("上报扫描结束失败（第%d次）: run_id=%d, err=%v", attempt, job.RunID, err)
+		time.Sleep(retryInterval)
+	}
+
+	log.Printf("扫描作业执行结束: task_id=%d, run_id=%d, state=%s, results=%d",
+		job.TaskID, job.RunID, completion.State, len(results))
+}
+
+// uploadResults 按服务端指定的批大小上传扫描结果
+func (a *agent) uploadResults(runID uint, results []models.ScanResult) error {
+	size := a.config.ResultBatchSize
+	if size <= 0 {
+		size = 200
+	}
+	for start := 0; start < len(results); start += size {
+		end := start + size
+		if end > len(results) {
+			end = len(results)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+		err := a.client.results(ctx, runID, &scanner.AgentResultBatch{Results: results[start:end]})
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eventReporter 在后台定期批量上报扫描事件
+type eventReporter struct {
+	client     *client
+	runID      uint
+	onCancel   func(runID uint, keepPartial bool)
+	onFinished func(runID uint)
+
+	mu      sync.Mutex
+	events  []scanner.AgentEvent
+	dropped int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newEventReporter(c *client, runID uint, onCancel func(uint, bool), onFinished func(uint)) *eventReporter {
+	r := &eventReporter{
+		client:     c,
+		runID:      runID,
+		onCancel:   onCancel,
+		onFinished: onFinished,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go r.loop()
+	return r
+}
+
+// add 追加待上报的事件，缓存已满时丢弃并在下次上报时说明
+func (r *eventReporter) add(event scanner.AgentEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.events) >= maxPendingEvents {
+		r.dropped++
+		return
+	}
+	r.events = append(r.events, event)
+}
+
+// close 上报剩余的事件并停止后台上报
+func (r *eventReporter) close() {
+	close(r.stop)
+	<-r.done
+}
+
+func (r *eventReporter) loop() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(eventFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			r.flush()
+			return
+		case <-ticker.C:
+			r.flush()
+		}
+	}
+}
+
+// flush 上报缓存的事件，失败时保留事件等待下次上报
+func (r *eventReporter) flush() {
+	for {
+		r.mu.Lock()
+		if r.dropped > 0 && len(r.events) < maxPendingEvents {
+			r.events = append(r.events, scanner.AgentEvent{
+				Type:    models.ScanEventLog,
+				Message: fmt.Sprintf("上报失败，已丢弃%d条扫描事件", r.dropped),
+			})
+			r.dropped = 0
+		}
+		n := len(r.events)
+		if n > eventBatchSize {
+			n = eventBatchSize
+		}
+		batch := append([]scanner.AgentEvent(nil), r.events[:n]...)
+		r.mu.Unlock()
+		if len(batch) == 0 {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+		cancelReq, err := r.client.events(ctx, r.runID, batch)
+		cancel()
+		if errors.Is(err, errRunFinished) {
+			r.onFinished(r.runID)
+			return
+		}
+		if err != nil {
+			log.Printf("上报扫描事件失败: run_id=%d, err=%v", r.runID, err)
+			return
+		}
+
+		r.mu.Lock()
+		r.events = r.events[n:]
+		r.mu.Unlock()
+		if cancelReq != nil {
+			r.onCancel(cancelReq.RunID, cancelReq.KeepPartial)
+		}
+	}
+}
+
+// sleep 等待指定时间，ctx 结束时返回 false
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
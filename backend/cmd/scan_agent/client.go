@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vulnark/vulnark/scanner"
+)
+
+// errRunFinished 服务端已结束该次执行，代理应放弃对应的扫描
+var errRunFinished = errors.New("扫描作业已在服务端结束")
+
+// client 访问服务端扫描代理接口的客户端
+type client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// envelope 服务端接口的统一响应格式
+type envelope struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func newClient(server, token string, insecure bool) *client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: insecure}
+
+	return &client{
+		baseURL: strings.TrimRight(server, "/") + "/api/v1/agent",
+		token:   token,
+		// 长轮询的等待时间由服务端控制，这里只限制单次请求的最长时间
+		http: &http.Client{Transport: transport, Timeout: 2 * time.Minute},
+	}
+}
+
+// call 调用服务端接口，out 不为 nil 时解析响应中的 data，服务端没有返回内容时返回 false
+func (c *client) call(ctx context.Context, method, path string, body, out interface{}) (bool, error) {
+	var resp envelope
+	err := scanner.Do(ctx, c.http, scanner.Request{
+		Method: method,
+		URL:    c.baseURL + path,
+		Header: map[string]string{"Authorization": "Bearer " + c.token},
+		Body:   body,
+		Result: &resp,
+	})
+	if err != nil {
+		var httpErr *scanner.HTTPError
+		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusConflict {
+			return false, errRunFinished
+		}
+		return false, err
+	}
+	if len(resp.Data) == 0 || string(resp.Data) == "null" {
+		return false, nil
+	}
+	if out != nil {
+		if err := json.Unmarshal(resp.Data, out); err != nil {
+			return false, fmt.Errorf("解析响应失败: %v", err)
+		}
+	}
+	return true, nil
+}
+
+// register 注册代理并获取运行参数
+func (c *client) register(ctx context.Context, reg *scanner.AgentRegistration) (*scanner.AgentConfig, error) {
+	var config scanner.AgentConfig
+	if _, err := c.call(ctx, http.MethodPost, "/register", reg, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// heartbeat 发送心跳，返回需要取消的扫描
+func (c *client) heartbeat(ctx context.Context, reg *scanner.AgentRegistration) ([]scanner.AgentCancel, error) {
+	var resp scanner.AgentHeartbeatResponse
+	if _, err := c.call(ctx, http.MethodPost, "/heartbeat", reg, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Cancel, nil
+}
+
+// poll 长轮询领取扫描作业，等待期间没有作业时返回 nil
+func (c *client) poll(ctx context.Context, wait int) (*scanner.AgentJob, error) {
+	var job scanner.AgentJob
+	ok, err := c.call(ctx, http.MethodGet, fmt.Sprintf("/jobs/poll?wait=%d", wait), nil, &job)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// events 上报扫描事件，扫描已被取消时返回取消请求
+func (c *client) events(ctx context.Context, runID uint, events []scanner.AgentEvent) (*scanner.AgentCancel, error) {
+	var resp scanner.AgentEventResponse
+	_, err := c.call(ctx, http.MethodPost, fmt.Sprintf("/jobs/%d/events", runID), scanner.AgentEventBatch{Events: events}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Cancel, nil
+}
+
+// results 上传一批扫描结果
+func (c *client) results(ctx context.Context, runID uint, batch *scanner.AgentResultBatch) error {
+	_, err := c.call(ctx, http.MethodPost, fmt.Sprintf("/jobs/%d/results", runID), batch, nil)
+	return err
+}
+
+// complete 上报扫描结束
+func (c *client) complete(ctx context.Context, runID uint, completion *scanner.AgentCompletion) error {
+	_, err := c.call(ctx, http.MethodPost, fmt.Sprintf("/jobs/%d/complete", runID), completion, nil)
+	return err
+}
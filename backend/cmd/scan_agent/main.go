@@ -0,0 +1,67 @@
+// scan_agent 扫描代理，部署在服务端无法直接访问的隔离网段内执行扫描
+//
+// 管理员先在服务端创建扫描代理并指定网络区域，获得代理令牌；代理启动后向服务端注册并定期发送心跳，
+// 通过长轮询领取指定了该网络区域的扫描任务，在本地使用扫描器驱动执行扫描并分批上传扫描结果：
+//
+//	VULNARK_AGENT_TOKEN=vsa_xxx go run ./cmd/scan_agent -server https://vulnark.example.com
+//
+// 代理只主动访问服务端，不需要开放任何入站端口。
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/scanner"
+)
+
+// agentVersion 扫描代理版本，注册时上报给服务端
+const agentVersion = "1.0.0"
+
+func main() {
+	server := flag.String("server", os.Getenv("VULNARK_SERVER"), "服务端地址，如 https://vulnark.example.com（或 VULNARK_SERVER）")
+	token := flag.String("token", "", "扫描代理令牌，建议通过环境变量 VULNARK_AGENT_TOKEN 传入，避免出现在进程列表中")
+	concurrency := flag.Int("concurrency", 2, "最大并发扫描数")
+	types := flag.String("types", "", "本代理执行的扫描器类型，逗号分隔，默认为全部已注册的类型")
+	insecure := flag.Bool("insecure", false, "不校验服务端HTTPS证书")
+	mock := flag.Bool("mock", false, "为尚未接入真实驱动的扫描器类型启用模拟驱动，仅用于演示")
+	flag.Parse()
+
+	if *token == "" {
+		*token = os.Getenv("VULNARK_AGENT_TOKEN")
+	}
+	if *server == "" || *token == "" {
+		log.Fatalf("必须指定服务端地址（-server）和扫描代理令牌（VULNARK_AGENT_TOKEN）")
+	}
+	if *concurrency <= 0 {
+		*concurrency = 1
+	}
+
+	if *mock {
+		scanner.EnableMockDriver()
+	}
+
+	scannerTypes := scanner.Types()
+	if *types != "" {
+		scannerTypes = nil
+		for _, t := range scanner.SplitList(*types) {
+			scannerType := models.ScannerType(strings.ToLower(t))
+			if _, err := scanner.Lookup(scannerType); err != nil {
+				log.Fatalf("%v", err)
+			}
+			scannerTypes = append(scannerTypes, scannerType)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	a := newAgent(newClient(*server, *token, *insecure), scannerTypes, *concurrency)
+	a.run(ctx)
+}
@@ -131,7 +131,8 @@ func PerformRiskAssessment(c *gin.Context) {
 		"message": "success",
 		"data":    response,
 	})
-	fmt.Println("[AI风险评估] ================ 请求处理完成 ================\n")
+	fmt.Println("[AI风险评估] ================ 请求处理完成 ================")
+	fmt.Println()
 }
 
 // calculateContextualScore 计算上下文风险分数
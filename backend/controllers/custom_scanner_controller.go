@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/scanner"
+	"github.com/vulnark/vulnark/utils"
+)
+
+// CustomScannerController 自定义扫描器定义控制器
+type CustomScannerController struct{}
+
+// CustomScannerRequest 创建/更新自定义扫描器定义的请求
+type CustomScannerRequest struct {
+	Name            string `json:"name" binding:"required"`
+	Description     string `json:"description"`
+	CommandTemplate string `json:"command_template" binding:"required"`
+	OutputParser    string `json:"output_parser"`
+	MappingConfig   string `json:"mapping_config"`
+	Timeout         int    `json:"timeout"`
+	Enabled         *bool  `json:"enabled"`
+}
+
+// validate 校验命令模板和解析配置
+func (r *CustomScannerRequest) validate() error {
+	args, err := scanner.SplitCommand(r.CommandTemplate)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errors.New("命令模板不能为空")
+	}
+	if strings.Contains(args[0], "{") {
+		return errors.New("命令模板的可执行文件不能包含占位符")
+	}
+
+	switch models.CustomScannerParser(r.OutputParser) {
+	case "":
+		r.OutputParser = string(models.CustomParserJSONLines)
+	case models.CustomParserJSONLines, models.CustomParserSARIF:
+	case models.CustomParserMapping:
+		if _, err := scanner.ParseMappingConfig(r.MappingConfig); err != nil {
+			return err
+		}
+	default:
+		return errors.New("不支持的输出解析方式: " + r.OutputParser)
+	}
+
+	if r.Timeout < 0 {
+		return errors.New("超时时间不能为负数")
+	}
+	if r.Timeout == 0 {
+		r.Timeout = 3600
+	}
+	return nil
+}
+
+// ListCustomScanners 获取自定义扫描器定义列表
+func (c *CustomScannerController) ListCustomScanners(ctx *gin.Context) {
+	var scanners []models.CustomScanner
+	query := utils.DB.Order("name ASC")
+	if ctx.Query("enabled") == "true" {
+		query = query.Where("enabled = ?", true)
+	}
+	if err := query.Find(&scanners).Error; err != nil {
+		log.Printf("获取自定义扫描器列表失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取自定义扫描器列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取自定义扫描器列表成功",
+		"data":    scanners,
+	})
+}
+
+// GetCustomScanner 获取自定义扫描器定义详情
+func (c *CustomScannerController) GetCustomScanner(ctx *gin.Context) {
+	var def models.CustomScanner
+	if err := utils.DB.First(&def, ctx.Param("id")).Error; err != nil {
+		respondCustomScannerNotFound(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取自定义扫描器成功",
+		"data":    def,
+	})
+}
+
+// CreateCustomScanner 创建自定义扫描器定义
+func (c *CustomScannerController) CreateCustomScanner(ctx *gin.Context) {
+	var req CustomScannerRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if err := req.validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	def := models.CustomScanner{
+		Name:            req.Name,
+		Description:     req.Description,
+		CommandTemplate: req.CommandTemplate,
+		OutputParser:    models.CustomScannerParser(req.OutputParser),
+		MappingConfig:   req.MappingConfig,
+		Timeout:         req.Timeout,
+		Enabled:         req.Enabled == nil || *req.Enabled,
+		CreatedBy:       ctx.GetUint("user_id"),
+	}
+	if err := utils.DB.Create(&def).Error; err != nil {
+		log.Printf("创建自定义扫描器失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "创建自定义扫描器失败: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"code":    200,
+		"message": "自定义扫描器创建成功",
+		"data":    def,
+	})
+}
+
+// UpdateCustomScanner 更新自定义扫描器定义
+func (c *CustomScannerController) UpdateCustomScanner(ctx *gin.Context) {
+	var def models.CustomScanner
+	if err := utils.DB.First(&def, ctx.Param("id")).Error; err != nil {
+		respondCustomScannerNotFound(ctx, err)
+		return
+	}
+
+	var req CustomScannerRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if err := req.validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	def.Name = req.Name
+	def.Description = req.Description
+	def.CommandTemplate = req.CommandTemplate
+	def.OutputParser = models.CustomScannerParser(req.OutputParser)
+	def.MappingConfig = req.MappingConfig
+	def.Timeout = req.Timeout
+	if req.Enabled != nil {
+		def.Enabled = *req.Enabled
+	}
+
+	if err := utils.DB.Save(&def).Error; err != nil {
+		log.Printf("更新自定义扫描器失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "更新自定义扫描器失败: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "自定义扫描器更新成功",
+		"data":    def,
+	})
+}
+
+// DeleteCustomScanner 删除自定义扫描器定义，仍被扫描任务引用时不允许删除
+func (c *CustomScannerController) DeleteCustomScanner(ctx *gin.Context) {
+	var def models.CustomScanner
+	if err := utils.DB.First(&def, ctx.Param("id")).Error; err != nil {
+		respondCustomScannerNotFound(ctx, err)
+		return
+	}
+
+	var count int
+	utils.DB.Model(&models.ScanTask{}).
+		Where("type = ? AND custom_scanner_id = ?", models.ScannerTypeCustom, def.ID).
+		Count(&count)
+	if count > 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "该扫描器仍被扫描任务使用，请先停用或修改相关任务",
+		})
+		return
+	}
+
+	if err := utils.DB.Delete(&def).Error; err != nil {
+		log.Printf("删除自定义扫描器失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除自定义扫描器失败: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "自定义扫描器删除成功",
+	})
+}
+
+// respondCustomScannerNotFound 返回查询自定义扫描器定义失败的响应
+func respondCustomScannerNotFound(ctx *gin.Context, err error) {
+	if gorm.IsRecordNotFoundError(err) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "自定义扫描器不存在",
+		})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{
+		"code":    500,
+		"message": "获取自定义扫描器失败: " + err.Error(),
+	})
+}
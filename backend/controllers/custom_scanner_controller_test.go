@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/utils"
+)
+
+func TestCreateCustomScannerDisabled(t *testing.T) {
+	useTestDB(t, &models.CustomScanner{})
+	controller := &CustomScannerController{}
+
+	for _, enabled := range []bool{false, true} {
+		w := performJSON(controller.CreateCustomScanner, http.MethodPost, "/api/v1/admin/custom-scanners", gin.H{
+			"name":             fmt.Sprintf("nuclei-%v", enabled),
+			"command_template": "nuclei -l {target_file} -jsonl -o {output_file}",
+			"enabled":          enabled,
+		})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("enabled=%v: 状态码 = %d, body = %s", enabled, w.Code, w.Body.String())
+		}
+
+		var resp struct {
+			Data models.CustomScanner `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+		var stored models.CustomScanner
+		if err := utils.DB.First(&stored, resp.Data.ID).Error; err != nil {
+			t.Fatalf("读取自定义扫描器失败: %v", err)
+		}
+		if resp.Data.Enabled != enabled || stored.Enabled != enabled {
+			t.Errorf("enabled=%v: 响应中为 %v，数据库中为 %v", enabled, resp.Data.Enabled, stored.Enabled)
+		}
+	}
+}
@@ -63,6 +63,20 @@ type PriorityVuln struct {
 	DiscoveredAt string `json:"discoveredAt"`
 }
 
+// 失败的扫描执行
+type FailedScanData struct {
+	RunID         uint                     `json:"runId"`
+	TaskID        uint                     `json:"taskId"`
+	TaskName      string                   `json:"taskName"`
+	ScannerType   models.ScannerType       `json:"scannerType"`
+	Trigger       models.ScanTrigger       `json:"trigger"`
+	Attempt       int                      `json:"attempt"`
+	FailureReason models.ScanFailureReason `json:"failureReason"`
+	Summary       string                   `json:"summary"`
+	TaskStatus    models.ScanTaskStatus    `json:"taskStatus"` // 任务当前状态，排队中表示正在自动重试
+	FailedAt      *time.Time               `json:"failedAt"`
+}
+
 // GetDashboardStats 获取仪表盘统计数据
 func (dc *DashboardController) GetDashboardStats(c *gin.Context) {
 	var stats DashboardStats
@@ -649,3 +663,69 @@ func getStatusLabel(status string) string {
 	}
 	return status
 }
+
+// GetFailedScans 获取最近N天失败的扫描执行及按失败原因的统计
+func (dc *DashboardController) GetFailedScans(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days < 1 || days > 365 {
+		days = 7
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		limit = 50
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	var rows []FailedScanData
+	err = utils.DB.Table("scan_runs").
+		Select("scan_runs.id AS run_id, scan_runs.scan_task_id AS task_id, scan_tasks.name AS task_name, "+
+			"scan_tasks.type AS scanner_type, scan_runs.trigger, scan_runs.attempt, scan_runs.failure_reason, "+
+			"scan_runs.result_summary AS summary, scan_tasks.status AS task_status, scan_runs.completed_at AS failed_at").
+		Joins("JOIN scan_tasks ON scan_tasks.id = scan_runs.scan_task_id AND scan_tasks.deleted_at IS NULL").
+		Where("scan_runs.status = ? AND scan_runs.completed_at >= ?", models.ScanTaskStatusFailed, since).
+		Order("scan_runs.completed_at DESC").
+		Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取失败的扫描失败: " + err.Error(),
+		})
+		return
+	}
+	if rows == nil {
+		rows = []FailedScanData{}
+	}
+
+	var counts []struct {
+		FailureReason models.ScanFailureReason
+		Count         int
+	}
+	utils.DB.Model(&models.ScanRun{}).
+		Select("failure_reason, COUNT(*) AS count").
+		Where("status = ? AND completed_at >= ?", models.ScanTaskStatusFailed, since).
+		Group("failure_reason").
+		Scan(&counts)
+
+	total := 0
+	byReason := make(map[models.ScanFailureReason]int)
+	for _, item := range counts {
+		reason := item.FailureReason
+		if reason == "" {
+			reason = models.ScanFailureUnknown
+		}
+		byReason[reason] += item.Count
+		total += item.Count
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取失败的扫描成功",
+		"data": gin.H{
+			"days":     days,
+			"total":    total,
+			"byReason": byReason,
+			"runs":     rows,
+		},
+	})
+}
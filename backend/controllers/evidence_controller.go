@@ -0,0 +1,368 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/vulnark/vulnark/evidence"
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/utils"
+)
+
+// EvidenceController 扫描结果和漏洞的证据
+type EvidenceController struct{}
+
+// inlineEvidenceTypes 可以在浏览器中直接展示的证据类型，其余类型一律作为附件下载，
+// 避免扫描器抓取的HTML响应等内容在本站点下被浏览器执行
+var inlineEvidenceTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"text/plain": true,
+}
+
+// ListScanResultEvidence 获取扫描结果的证据
+func (c *EvidenceController) ListScanResultEvidence(ctx *gin.Context) {
+	var result models.ScanResult
+	err := utils.DB.Where("id = ? AND scan_task_id = ?", ctx.Param("result_id"), ctx.Param("id")).First(&result).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "扫描结果不存在",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取扫描结果失败: " + err.Error(),
+		})
+		return
+	}
+
+	var items []models.Evidence
+	if err := utils.DB.Where("scan_result_id = ?", result.ID).Order("id ASC").Find(&items).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取证据失败: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    items,
+	})
+}
+
+// ListVulnerabilityEvidence 获取漏洞的证据，包括导入的扫描结果带来的证据
+func (c *EvidenceController) ListVulnerabilityEvidence(ctx *gin.Context) {
+	var vuln models.Vulnerability
+	if err := utils.DB.Select("id").First(&vuln, ctx.Param("id")).Error; err != nil {
+		respondVulnerabilityNotFound(ctx, err)
+		return
+	}
+
+	var items []models.Evidence
+	if err := utils.DB.Where("vulnerability_id = ?", vuln.ID).Order("id ASC").Find(&items).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取证据失败: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    items,
+	})
+}
+
+// UploadVulnerabilityEvidence 为漏洞上传证据文件，表单字段 file 为文件，kind 为证据类型（默认 other）
+func (c *EvidenceController) UploadVulnerabilityEvidence(ctx *gin.Context) {
+	var vuln models.Vulnerability
+	if err := utils.DB.Select("id").First(&vuln, ctx.Param("id")).Error; err != nil {
+		respondVulnerabilityNotFound(ctx, err)
+		return
+	}
+
+	kind := models.EvidenceKind(ctx.DefaultPostForm("kind", string(models.EvidenceOther)))
+	if !models.ValidEvidenceKind(kind) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "不支持的证据类型: " + string(kind),
+		})
+		return
+	}
+
+	header, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请上传文件: " + err.Error(),
+		})
+		return
+	}
+	if header.Size > evidence.MaxSize() {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": fmt.Sprintf("文件超过大小上限（%dMB）", evidence.MaxSize()>>20),
+		})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "读取上传文件失败: " + err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, evidence.MaxSize()+1))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "读取上传文件失败: " + err.Error(),
+		})
+		return
+	}
+
+	name := ctx.PostForm("name")
+	if name == "" {
+		name = filepath.Base(header.Filename)
+	}
+	item, err := storeEvidence(models.ScanEvidence{
+		Kind:        kind,
+		Name:        name,
+		ContentType: header.Header.Get("Content-Type"),
+		Data:        data,
+	}, 0, vuln.ID, ctx.GetUint("user_id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, evidence.ErrTooLarge) {
+			status = http.StatusBadRequest
+		}
+		ctx.JSON(status, gin.H{
+			"code":    status,
+			"message": "保存证据失败: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "证据上传成功",
+		"data":    item,
+	})
+}
+
+// DownloadEvidence 下载证据内容
+func (c *EvidenceController) DownloadEvidence(ctx *gin.Context) {
+	var item models.Evidence
+	if err := utils.DB.First(&item, ctx.Param("id")).Error; err != nil {
+		respondEvidenceNotFound(ctx, err)
+		return
+	}
+
+	file, err := evidence.Open(item.SHA256)
+	if err != nil {
+		log.Printf("打开证据文件失败: evidence_id=%d, err=%v", item.ID, err)
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "证据文件不存在",
+		})
+		return
+	}
+	defer file.Close()
+
+	contentType := item.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	disposition := "attachment"
+	if inlineEvidenceTypes[mediaType] && ctx.Query("download") == "" {
+		disposition = "inline"
+	}
+	name := item.Name
+	if name == "" {
+		name = fmt.Sprintf("evidence-%d", item.ID)
+	}
+
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Length", strconv.FormatInt(item.Size, 10))
+	ctx.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": name}))
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Header("Content-Security-Policy", "default-src 'none'; sandbox")
+	ctx.Header("ETag", `"`+item.SHA256+`"`)
+	ctx.Status(http.StatusOK)
+	if _, err := io.Copy(ctx.Writer, file); err != nil {
+		log.Printf("发送证据内容失败: evidence_id=%d, err=%v", item.ID, err)
+	}
+}
+
+// DeleteEvidence 删除证据，仅上传者和管理员可以删除
+func (c *EvidenceController) DeleteEvidence(ctx *gin.Context) {
+	var item models.Evidence
+	if err := utils.DB.First(&item, ctx.Param("id")).Error; err != nil {
+		respondEvidenceNotFound(ctx, err)
+		return
+	}
+	if role, _ := ctx.Get("role"); role != string(models.RoleAdmin) && (item.CreatedBy == 0 || item.CreatedBy != ctx.GetUint("user_id")) {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "只有上传者和管理员可以删除证据",
+		})
+		return
+	}
+
+	if err := utils.DB.Delete(&item).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除证据失败: " + err.Error(),
+		})
+		return
+	}
+	releaseEvidenceContent(item.SHA256)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "证据删除成功",
+	})
+}
+
+// storeEvidence 保存证据内容并创建元数据记录
+func storeEvidence(item models.ScanEvidence, resultID, vulnID, userID uint) (*models.Evidence, error) {
+	digest, err := evidence.Save(item.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := strings.TrimSpace(item.ContentType)
+	if contentType == "" {
+		contentType = http.DetectContentType(item.Data)
+	}
+	kind := item.Kind
+	if !models.ValidEvidenceKind(kind) {
+		kind = models.EvidenceOther
+	}
+
+	record := models.Evidence{
+		ScanResultID:    resultID,
+		VulnerabilityID: vulnID,
+		Kind:            kind,
+		Name:            truncateString(item.Name, 255),
+		ContentType:     truncateString(contentType, 100),
+		Size:            int64(len(item.Data)),
+		SHA256:          digest,
+		CreatedBy:       userID,
+	}
+	if err := utils.DB.Create(&record).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// saveResultEvidence 保存扫描结果携带的证据，单个证据保存失败不影响结果
+func saveResultEvidence(result *models.ScanResult) {
+	for _, item := range result.Evidence {
+		if len(item.Data) == 0 {
+			continue
+		}
+		if _, err := storeEvidence(item, result.ID, 0, 0); err != nil {
+			log.Printf("保存扫描结果证据失败: result_id=%d, kind=%s, err=%v", result.ID, item.Kind, err)
+		}
+	}
+	result.Evidence = nil
+}
+
+// linkResultEvidence 将扫描结果的证据关联到导入后的漏洞
+func linkResultEvidence(resultID, vulnID uint) {
+	err := utils.DB.Model(&models.Evidence{}).
+		Where("scan_result_id = ?", resultID).
+		UpdateColumn("vulnerability_id", vulnID).Error
+	if err != nil {
+		log.Printf("关联证据到漏洞失败: result_id=%d, vuln_id=%d, err=%v", resultID, vulnID, err)
+	}
+}
+
+// deleteResultEvidence 删除 results 查询选中的扫描结果的证据，已关联到漏洞的证据随漏洞保留
+func deleteResultEvidence(results *gorm.DB) {
+	query := utils.DB.Model(&models.Evidence{}).
+		Where("scan_result_id IN (?) AND vulnerability_id = ?", results.Select("id").QueryExpr(), 0)
+
+	var digests []string
+	if err := query.Pluck("DISTINCT sha256", &digests).Error; err != nil {
+		log.Printf("查询扫描结果证据失败: %v", err)
+		return
+	}
+	if len(digests) == 0 {
+		return
+	}
+	if err := query.Delete(&models.Evidence{}).Error; err != nil {
+		log.Printf("删除扫描结果证据失败: %v", err)
+		return
+	}
+	for _, digest := range digests {
+		releaseEvidenceContent(digest)
+	}
+}
+
+// releaseEvidenceContent 没有证据记录再引用该内容时删除文件
+func releaseEvidenceContent(digest string) {
+	var count int
+	if err := utils.DB.Model(&models.Evidence{}).Where("sha256 = ?", digest).Count(&count).Error; err != nil || count > 0 {
+		return
+	}
+	if err := evidence.Remove(digest); err != nil {
+		log.Printf("删除证据文件失败: sha256=%s, err=%v", digest, err)
+	}
+}
+
+func respondEvidenceNotFound(ctx *gin.Context, err error) {
+	if gorm.IsRecordNotFoundError(err) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "证据不存在",
+		})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{
+		"code":    500,
+		"message": "获取证据失败: " + err.Error(),
+	})
+}
+
+func respondVulnerabilityNotFound(ctx *gin.Context, err error) {
+	if gorm.IsRecordNotFoundError(err) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "漏洞不存在",
+		})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{
+		"code":    500,
+		"message": "获取漏洞失败: " + err.Error(),
+	})
+}
+
+// truncateString 按字节截断字符串，不截断多字节字符
+func truncateString(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return strings.ToValidUTF8(s[:max], "")
+}
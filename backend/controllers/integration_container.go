@@ -0,0 +1,444 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/utils"
+)
+
+// imageRef 扫描报告中的容器镜像信息
+type imageRef struct {
+	Name   string // 扫描时指定的镜像，如 registry.example.com/app:1.2.0
+	Digest string // 镜像摘要，优先使用仓库中的清单摘要，未推送的镜像使用镜像ID
+	OS     string // 操作系统，如 "alpine 3.15.0"
+}
+
+// trivyReport Trivy JSON 报告（trivy image/fs --format json，SchemaVersion 2）
+type trivyReport struct {
+	ArtifactName string `json:"ArtifactName"`
+	ArtifactType string `json:"ArtifactType"`
+	Metadata     struct {
+		OS struct {
+			Family string `json:"Family"`
+			Name   string `json:"Name"`
+		} `json:"OS"`
+		ImageID     string   `json:"ImageID"`
+		RepoDigests []string `json:"RepoDigests"`
+	} `json:"Metadata"`
+	Results []struct {
+		Target          string `json:"Target"`
+		Class           string `json:"Class"`
+		Type            string `json:"Type"`
+		Vulnerabilities []struct {
+			VulnerabilityID  string   `json:"VulnerabilityID"`
+			PkgName          string   `json:"PkgName"`
+			PkgPath          string   `json:"PkgPath"`
+			InstalledVersion string   `json:"InstalledVersion"`
+			FixedVersion     string   `json:"FixedVersion"`
+			Title            string   `json:"Title"`
+			Description      string   `json:"Description"`
+			Severity         string   `json:"Severity"`
+			SeveritySource   string   `json:"SeveritySource"`
+			CweIDs           []string `json:"CweIDs"`
+			PrimaryURL       string   `json:"PrimaryURL"`
+			References       []string `json:"References"`
+			CVSS             map[string]struct {
+				V2Vector string  `json:"V2Vector"`
+				V3Vector string  `json:"V3Vector"`
+				V2Score  float64 `json:"V2Score"`
+				V3Score  float64 `json:"V3Score"`
+			} `json:"CVSS"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+// 处理Trivy JSON格式的扫描结果
+//
+// 镜像扫描的结果关联到以镜像摘要为标识的容器镜像资产，资产不存在时自动创建。
+func processTrivyResult(data []byte, integration models.CIIntegration) ([]models.Vulnerability, error) {
+	var report trivyReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+
+	var image *imageRef
+	if report.ArtifactType == "container_image" {
+		image = &imageRef{
+			Name:   report.ArtifactName,
+			Digest: repoDigest(report.Metadata.RepoDigests, report.Metadata.ImageID),
+			OS:     strings.TrimSpace(report.Metadata.OS.Family + " " + report.Metadata.OS.Name),
+		}
+	}
+	assets := containerImageAssets(image)
+
+	now := time.Now()
+	var vulnerabilities []models.Vulnerability
+	for _, result := range report.Results {
+		for _, tv := range result.Vulnerabilities {
+			// 按严重程度来源选择CVSS，没有时取分值最高的来源
+			var score float64
+			var vector string
+			if cvss, ok := tv.CVSS[tv.SeveritySource]; ok && (cvss.V3Score > 0 || cvss.V2Score > 0) {
+				score, vector = cvssScore(cvss.V3Score, cvss.V3Vector, cvss.V2Score, cvss.V2Vector)
+			} else {
+				for _, cvss := range tv.CVSS {
+					if s, v := cvssScore(cvss.V3Score, cvss.V3Vector, cvss.V2Score, cvss.V2Vector); s > score {
+						score, vector = s, v
+					}
+				}
+			}
+
+			title := tv.Title
+			if title == "" {
+				title = fmt.Sprintf("%s: %s", tv.VulnerabilityID, tv.PkgName)
+			}
+			location := result.Target
+			if tv.PkgPath != "" {
+				location = tv.PkgPath
+			}
+			references := tv.References
+			if tv.PrimaryURL != "" {
+				references = append([]string{tv.PrimaryURL}, references...)
+			}
+
+			vuln := componentVulnerability(componentFinding{
+				ID:               tv.VulnerabilityID,
+				Title:            title,
+				Description:      tv.Description,
+				Severity:         tv.Severity,
+				Package:          tv.PkgName,
+				InstalledVersion: tv.InstalledVersion,
+				FixedVersion:     tv.FixedVersion,
+				CWEs:             tv.CweIDs,
+				CVSS:             score,
+				Vector:           vector,
+				Location:         location,
+				References:       references,
+				Scanner:          "Trivy",
+			}, image)
+			vuln.Source = "trivy"
+			vuln.DiscoveredAt = now
+			vuln.Assets = assets
+			vuln.Fingerprint = ciFingerprint(integration.ID, "trivy", componentTarget(image, report.ArtifactName), location, tv.PkgName, tv.VulnerabilityID)
+			vulnerabilities = append(vulnerabilities, vuln)
+		}
+	}
+
+	return vulnerabilities, nil
+}
+
+// grypeCVSS Grype报告中的CVSS信息
+type grypeCVSS struct {
+	Version string `json:"version"`
+	Vector  string `json:"vector"`
+	Metrics struct {
+		BaseScore float64 `json:"baseScore"`
+	} `json:"metrics"`
+}
+
+// grypeReport Grype JSON 报告（grype -o json）
+type grypeReport struct {
+	Matches []struct {
+		Vulnerability struct {
+			ID          string      `json:"id"`
+			Severity    string      `json:"severity"`
+			Description string      `json:"description"`
+			URLs        []string    `json:"urls"`
+			CVSS        []grypeCVSS `json:"cvss"`
+			Fix         struct {
+				Versions []string `json:"versions"`
+				State    string   `json:"state"`
+			} `json:"fix"`
+		} `json:"vulnerability"`
+		RelatedVulnerabilities []struct {
+			ID          string      `json:"id"`
+			Description string      `json:"description"`
+			URLs        []string    `json:"urls"`
+			CVSS        []grypeCVSS `json:"cvss"`
+		} `json:"relatedVulnerabilities"`
+		Artifact struct {
+			Name      string `json:"name"`
+			Version   string `json:"version"`
+			Type      string `json:"type"`
+			Locations []struct {
+				Path string `json:"path"`
+			} `json:"locations"`
+		} `json:"artifact"`
+	} `json:"matches"`
+	Source struct {
+		Type   string          `json:"type"`
+		Target json.RawMessage `json:"target"` // 镜像扫描时为对象，目录扫描时为路径字符串
+	} `json:"source"`
+	Distro struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"distro"`
+	Descriptor struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"descriptor"`
+}
+
+// 处理Grype JSON格式的扫描结果
+//
+// 镜像扫描的结果关联到以镜像摘要为标识的容器镜像资产，资产不存在时自动创建。
+// Grype按GHSA等编号报告的漏洞从关联漏洞中补充CVE编号、描述和CVSS。
+func processGrypeResult(data []byte, integration models.CIIntegration) ([]models.Vulnerability, error) {
+	var report grypeReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+
+	targetName := ""
+	var image *imageRef
+	if report.Source.Type == "image" {
+		var target struct {
+			UserInput      string   `json:"userInput"`
+			ImageID        string   `json:"imageID"`
+			ManifestDigest string   `json:"manifestDigest"`
+			RepoDigests    []string `json:"repoDigests"`
+		}
+		if err := json.Unmarshal(report.Source.Target, &target); err != nil {
+			return nil, fmt.Errorf("解析镜像信息失败: %v", err)
+		}
+		image = &imageRef{
+			Name:   target.UserInput,
+			Digest: repoDigest(target.RepoDigests, target.ImageID),
+			OS:     strings.TrimSpace(report.Distro.Name + " " + report.Distro.Version),
+		}
+		targetName = target.UserInput
+	} else {
+		_ = json.Unmarshal(report.Source.Target, &targetName)
+	}
+	assets := containerImageAssets(image)
+	scanner := strings.TrimSpace("Grype " + report.Descriptor.Version)
+
+	now := time.Now()
+	vulnerabilities := make([]models.Vulnerability, 0, len(report.Matches))
+	for _, match := range report.Matches {
+		gv := match.Vulnerability
+
+		cve := ""
+		if cvePattern.MatchString(gv.ID) {
+			cve = gv.ID
+		}
+		description := gv.Description
+		references := append([]string{}, gv.URLs...)
+		cvss := gv.CVSS
+		for _, related := range match.RelatedVulnerabilities {
+			if cve == "" && cvePattern.MatchString(related.ID) {
+				cve = related.ID
+			}
+			if description == "" {
+				description = related.Description
+			}
+			if len(cvss) == 0 {
+				cvss = related.CVSS
+			}
+			references = append(references, related.URLs...)
+		}
+
+		score, vector := grypeCVSSScore(cvss)
+
+		location := ""
+		if len(match.Artifact.Locations) > 0 {
+			location = match.Artifact.Locations[0].Path
+		}
+		fixed := ""
+		if gv.Fix.State == "fixed" {
+			fixed = strings.Join(gv.Fix.Versions, ", ")
+		}
+
+		vuln := componentVulnerability(componentFinding{
+			ID:               gv.ID,
+			CVE:              cve,
+			Title:            fmt.Sprintf("%s: %s", gv.ID, match.Artifact.Name),
+			Description:      description,
+			Severity:         gv.Severity,
+			Package:          match.Artifact.Name,
+			InstalledVersion: match.Artifact.Version,
+			FixedVersion:     fixed,
+			CVSS:             score,
+			Vector:           vector,
+			Location:         location,
+			References:       references,
+			Scanner:          scanner,
+		}, image)
+		vuln.Source = "grype"
+		vuln.DiscoveredAt = now
+		vuln.Assets = assets
+		vuln.Fingerprint = ciFingerprint(integration.ID, "grype", componentTarget(image, targetName), location, match.Artifact.Name, gv.ID)
+		vulnerabilities = append(vulnerabilities, vuln)
+	}
+
+	return vulnerabilities, nil
+}
+
+// grypeCVSSScore 优先取分值最高的CVSS v3，没有v3时取分值最高的v2
+func grypeCVSSScore(cvss []grypeCVSS) (float64, string) {
+	var v3Score, v2Score float64
+	var v3Vector, v2Vector string
+	for _, c := range cvss {
+		if strings.HasPrefix(c.Version, "3") {
+			if c.Metrics.BaseScore > v3Score {
+				v3Score, v3Vector = c.Metrics.BaseScore, c.Vector
+			}
+		} else if c.Metrics.BaseScore > v2Score {
+			v2Score, v2Vector = c.Metrics.BaseScore, c.Vector
+		}
+	}
+	return cvssScore(v3Score, v3Vector, v2Score, v2Vector)
+}
+
+// componentFinding 依赖组件或系统软件包的漏洞
+type componentFinding struct {
+	ID               string // 漏洞编号，如 CVE-2021-44228、GHSA-jfh8-c2jp-5v3q
+	CVE              string // 为空时取 ID 中的CVE编号
+	Title            string
+	Description      string
+	Severity         string
+	Package          string
+	InstalledVersion string
+	FixedVersion     string
+	CWEs             []string
+	CVSS             float64
+	Vector           string
+	Location         string // 依赖清单文件或软件包所在路径
+	References       []string
+	Scanner          string
+}
+
+// componentVulnerability 将组件漏洞转换为漏洞记录
+func componentVulnerability(f componentFinding, image *imageRef) models.Vulnerability {
+	cve := f.CVE
+	if cve == "" && cvePattern.MatchString(f.ID) {
+		cve = cvePattern.FindString(f.ID)
+	}
+	identifiers := uniqueStrings(append([]string{f.ID, cve}, f.CWEs...))
+
+	solution := "暂无修复版本"
+	if f.FixedVersion != "" {
+		solution = fmt.Sprintf("将 %s 升级到 %s", f.Package, f.FixedVersion)
+	}
+
+	vuln := models.Vulnerability{
+		Title:            truncateString(f.Title, 255),
+		CVE:              truncateString(strings.ToUpper(cve), 50),
+		Description:      strings.TrimSpace(fmt.Sprintf("%s\n\n受影响的组件: %s %s", f.Description, f.Package, f.InstalledVersion)),
+		Type:             models.TypeVulnerableComponent,
+		Severity:         componentSeverity(f.Severity),
+		Status:           models.StatusNew,
+		CVSS:             f.CVSS,
+		Vector:           truncateString(f.Vector, 255),
+		Location:         truncateString(f.Location, 500),
+		Identifiers:      strings.Join(nonEmpty(identifiers), ", "),
+		Component:        truncateString(f.Package, 255),
+		ComponentVersion: truncateString(f.InstalledVersion, 100),
+		FixedVersion:     truncateString(f.FixedVersion, 100),
+		Scanner:          truncateString(f.Scanner, 100),
+		Solution:         solution,
+		References:       strings.Join(uniqueStrings(nonEmpty(f.References)), "\n"),
+	}
+	if image != nil {
+		vuln.Image = truncateString(image.Name, 255)
+		vuln.OperatingSystem = truncateString(image.OS, 100)
+	}
+	return vuln
+}
+
+// componentSeverity 转换Trivy和Grype的严重程度，Negligible、Unknown 按信息级别处理
+func componentSeverity(severity string) models.Severity {
+	switch s := models.Severity(strings.ToLower(strings.TrimSpace(severity))); s {
+	case models.SeverityCritical, models.SeverityHigh, models.SeverityMedium, models.SeverityLow:
+		return s
+	}
+	return models.SeverityInfo
+}
+
+// cvssScore 优先使用CVSS v3分值
+func cvssScore(v3Score float64, v3Vector string, v2Score float64, v2Vector string) (float64, string) {
+	if v3Score > 0 {
+		return v3Score, v3Vector
+	}
+	return v2Score, v2Vector
+}
+
+// componentTarget 指纹中的扫描目标，镜像按不带标签的名称，升级镜像版本后仍能匹配到同一漏洞
+func componentTarget(image *imageRef, target string) string {
+	if image != nil {
+		return imageName(image.Name)
+	}
+	return target
+}
+
+// repoDigest 镜像摘要，优先使用仓库中的清单摘要（repo@sha256:...），没有时使用镜像ID
+func repoDigest(repoDigests []string, imageID string) string {
+	for _, d := range repoDigests {
+		if i := strings.Index(d, "@"); i >= 0 && i < len(d)-1 {
+			return d[i+1:]
+		}
+	}
+	return imageID
+}
+
+// containerImageAssets 查找或创建镜像对应的容器镜像资产，image 为 nil 或没有摘要时返回 nil
+func containerImageAssets(image *imageRef) []models.Asset {
+	if image == nil || image.Digest == "" {
+		return nil
+	}
+
+	now := time.Now()
+	var asset models.Asset
+	err := utils.DB.Where("identifier = ?", image.Digest).First(&asset).Error
+	if err == nil {
+		utils.DB.Model(&asset).UpdateColumn("last_scan", now)
+		return []models.Asset{asset}
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		log.Printf("查找容器镜像资产失败: digest=%s, err=%v", image.Digest, err)
+		return nil
+	}
+
+	name := image.Name
+	if name == "" {
+		name = image.Digest
+	}
+	version := ""
+	if base := imageName(name); len(base) < len(name) && name[len(base)] == ':' {
+		version = name[len(base)+1:]
+	}
+	asset = models.Asset{
+		Name:        truncateString(name, 255),
+		Type:        models.AssetTypeContainerImage,
+		Identifier:  image.Digest,
+		Status:      models.AssetStatusActive,
+		Description: "由CI镜像扫描结果自动创建",
+		OS:          truncateString(image.OS, 100),
+		Version:     truncateString(version, 50),
+		Importance:  models.ImportanceMedium,
+		LastScan:    &now,
+	}
+	if err := utils.DB.Create(&asset).Error; err != nil {
+		log.Printf("创建容器镜像资产失败: digest=%s, err=%v", image.Digest, err)
+		return nil
+	}
+	log.Printf("已创建容器镜像资产: id=%d, name=%s, digest=%s", asset.ID, asset.Name, asset.Identifier)
+	return []models.Asset{asset}
+}
+
+// nonEmpty 去除空字符串
+func nonEmpty(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/utils"
+)
+
+func TestGrypeCVSSScore(t *testing.T) {
+	entry := func(version, vector string, score float64) grypeCVSS {
+		c := grypeCVSS{Version: version, Vector: vector}
+		c.Metrics.BaseScore = score
+		return c
+	}
+	v3 := entry("3.1", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:N/A:N", 7.5)
+	v3Low := entry("3.0", "CVSS:3.0/AV:N/AC:H/PR:N/UI:N/S:U/C:L/I:N/A:N", 3.7)
+	v2 := entry("2.0", "AV:N/AC:L/Au:N/C:C/I:C/A:C", 10)
+
+	cases := []struct {
+		name   string
+		cvss   []grypeCVSS
+		score  float64
+		vector string
+	}{
+		{"v2在v3之后", []grypeCVSS{v3, v2}, 7.5, v3.Vector},
+		{"v2在v3之前", []grypeCVSS{v2, v3}, 7.5, v3.Vector},
+		{"取分值最高的v3", []grypeCVSS{v3Low, v2, v3}, 7.5, v3.Vector},
+		{"没有v3时使用v2", []grypeCVSS{v2}, 10, v2.Vector},
+		{"没有CVSS", nil, 0, ""},
+	}
+	for _, c := range cases {
+		score, vector := grypeCVSSScore(c.cvss)
+		if score != c.score || vector != c.vector {
+			t.Errorf("%s: = %v %q, want %v %q", c.name, score, vector, c.score, c.vector)
+		}
+	}
+}
+
+// testTrivyReport trivy image --format json 的报告
+const testTrivyReport = `{
+  "SchemaVersion": 2,
+  "CreatedAt": "2023-10-12T08:20:11.437913Z",
+  "ArtifactName": "registry.example.com/shop/api:2.3.1",
+  "ArtifactType": "container_image",
+  "Metadata": {
+    "OS": {"Family": "alpine", "Name": "3.16.2"},
+    "ImageID": "sha256:4f1b8c1e0a9d3c2b7e6f5a4d3c2b1a0f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b",
+    "DiffIDs": ["sha256:994393dc58e7931862558d06e46aa2bb17487044f670f310dffe1d24e4d1eec7"],
+    "RepoTags": ["registry.example.com/shop/api:2.3.1"],
+    "RepoDigests": ["registry.example.com/shop/api@sha256:9b2a6b8c3f1e4d7a0c5b2e8f1a4d7c0b3e6f9a2d5c8b1e4f7a0d3c6b9e2f5a8c"]
+  },
+  "Results": [
+    {
+      "Target": "registry.example.com/shop/api:2.3.1 (alpine 3.16.2)",
+      "Class": "os-pkgs",
+      "Type": "alpine",
+      "Vulnerabilities": [
+        {
+          "VulnerabilityID": "CVE-2023-0286",
+          "PkgName": "libcrypto3",
+          "InstalledVersion": "3.0.7-r0",
+          "FixedVersion": "3.0.8-r0",
+          "SeveritySource": "nvd",
+          "PrimaryURL": "https://avd.aquasec.com/nvd/cve-2023-0286",
+          "Title": "openssl: X.400 address type confusion in X.509 GeneralName",
+          "Description": "There is a type confusion vulnerability relating to X.400 address processing inside an X.509 GeneralName.",
+          "Severity": "HIGH",
+          "CweIDs": ["CWE-843"],
+          "CVSS": {
+            "nvd": {"V3Vector": "CVSS:3.1/AV:N/AC:H/PR:N/UI:N/S:U/C:H/I:N/A:H", "V3Score": 7.4},
+            "redhat": {"V3Vector": "CVSS:3.1/AV:N/AC:H/PR:N/UI:N/S:U/C:H/I:N/A:H", "V3Score": 7.4}
+          },
+          "References": ["https://www.openssl.org/news/secadv/20230207.txt", "https://avd.aquasec.com/nvd/cve-2023-0286"]
+        }
+      ]
+    },
+    {
+      "Target": "app/requirements.txt",
+      "Class": "lang-pkgs",
+      "Type": "pip",
+      "Vulnerabilities": [
+        {
+          "VulnerabilityID": "GHSA-j8r2-6x86-q33q",
+          "PkgName": "requests",
+          "PkgPath": "usr/lib/python3.10/site-packages/requests-2.28.1.dist-info/METADATA",
+          "InstalledVersion": "2.28.1",
+          "FixedVersion": "2.31.0",
+          "SeveritySource": "ghsa",
+          "Title": "Unintended leak of Proxy-Authorization header in requests",
+          "Severity": "MEDIUM",
+          "CVSS": {
+            "ghsa": {"V3Vector": "CVSS:3.1/AV:N/AC:H/PR:N/UI:R/S:C/C:H/I:N/A:N", "V3Score": 6.1},
+            "nvd": {"V2Vector": "AV:N/AC:M/Au:N/C:P/I:N/A:N", "V2Score": 4.3}
+          }
+        },
+        {
+          "VulnerabilityID": "CVE-2023-43804",
+          "PkgName": "urllib3",
+          "InstalledVersion": "1.26.12",
+          "SeveritySource": "ubuntu",
+          "Severity": "UNKNOWN",
+          "CVSS": {
+            "nvd": {"V2Score": 5.0, "V2Vector": "AV:N/AC:L/Au:N/C:P/I:N/A:N"},
+            "redhat": {"V3Score": 5.9, "V3Vector": "CVSS:3.1/AV:N/AC:H/PR:N/UI:N/S:U/C:H/I:N/A:N"}
+          }
+        }
+      ]
+    }
+  ]
+}`
+
+// testGrypeReport grype -o json 扫描镜像的报告
+const testGrypeReport = `{
+  "matches": [
+    {
+      "vulnerability": {
+        "id": "GHSA-jfh8-c2jp-5v3q",
+        "dataSource": "https://github.com/advisories/GHSA-jfh8-c2jp-5v3q",
+        "namespace": "github:language:java",
+        "severity": "Critical",
+        "urls": ["https://github.com/advisories/GHSA-jfh8-c2jp-5v3q"],
+        "description": "Remote code injection in Log4j",
+        "cvss": [],
+        "fix": {"versions": ["2.15.0"], "state": "fixed"}
+      },
+      "relatedVulnerabilities": [
+        {
+          "id": "CVE-2021-44228",
+          "dataSource": "https://nvd.nist.gov/vuln/detail/CVE-2021-44228",
+          "namespace": "nvd:cpe",
+          "severity": "Critical",
+          "urls": ["https://logging.apache.org/log4j/2.x/security.html"],
+          "description": "Apache Log4j2 JNDI features do not protect against attacker controlled LDAP and other JNDI related endpoints.",
+          "cvss": [
+            {"version": "2.0", "vector": "AV:N/AC:M/Au:N/C:C/I:C/A:C", "metrics": {"baseScore": 9.3, "exploitabilityScore": 8.6, "impactScore": 10}},
+            {"version": "3.1", "vector": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", "metrics": {"baseScore": 10, "exploitabilityScore": 3.9, "impactScore": 6}}
+          ]
+        }
+      ],
+      "matchDetails": [{"type": "exact-direct-match", "matcher": "java-matcher"}],
+      "artifact": {
+        "name": "log4j-core",
+        "version": "2.14.1",
+        "type": "java-archive",
+        "locations": [{"path": "/app/lib/log4j-core-2.14.1.jar", "layerID": "sha256:c3e1b2a4"}],
+        "purl": "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1"
+      }
+    },
+    {
+      "vulnerability": {
+        "id": "CVE-2022-48174",
+        "namespace": "alpine:distro:alpine:3.16",
+        "severity": "Negligible",
+        "urls": [],
+        "cvss": [
+          {"version": "2.0", "vector": "AV:N/AC:L/Au:N/C:C/I:C/A:C", "metrics": {"baseScore": 10}},
+          {"version": "3.1", "vector": "CVSS:3.1/AV:L/AC:L/PR:L/UI:N/S:U/C:N/I:N/A:L", "metrics": {"baseScore": 3.3}}
+        ],
+        "fix": {"versions": [], "state": "not-fixed"}
+      },
+      "relatedVulnerabilities": [],
+      "artifact": {"name": "busybox", "version": "1.35.0-r17", "type": "apk", "locations": [{"path": "/lib/apk/db/installed"}]}
+    }
+  ],
+  "source": {
+    "type": "image",
+    "target": {
+      "userInput": "registry.example.com/shop/api:2.3.1",
+      "imageID": "sha256:4f1b8c1e0a9d3c2b7e6f5a4d3c2b1a0f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b",
+      "manifestDigest": "sha256:6a1d3b5c",
+      "mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+      "tags": ["registry.example.com/shop/api:2.3.1"],
+      "repoDigests": ["registry.example.com/shop/api@sha256:9b2a6b8c3f1e4d7a0c5b2e8f1a4d7c0b3e6f9a2d5c8b1e4f7a0d3c6b9e2f5a8c"]
+    }
+  },
+  "distro": {"name": "alpine", "version": "3.16.2", "idLike": []},
+  "descriptor": {"name": "grype", "version": "0.72.0"}
+}`
+
+// testGrypeDirReport grype dir:. -o json 扫描目录的报告
+const testGrypeDirReport = `{
+  "matches": [
+    {
+      "vulnerability": {
+        "id": "GHSA-c2qf-rxjj-qqgw",
+        "severity": "Medium",
+        "urls": ["https://github.com/advisories/GHSA-c2qf-rxjj-qqgw"],
+        "cvss": [{"version": "3.1", "vector": "CVSS:3.1/AV:N/AC:H/PR:N/UI:N/S:U/C:N/I:N/A:H", "metrics": {"baseScore": 5.9}}],
+        "fix": {"versions": ["7.5.2"], "state": "fixed"}
+      },
+      "relatedVulnerabilities": [{"id": "CVE-2022-25883", "urls": ["https://nvd.nist.gov/vuln/detail/CVE-2022-25883"]}],
+      "artifact": {"name": "semver", "version": "7.3.8", "type": "npm", "locations": [{"path": "/package-lock.json"}]}
+    }
+  ],
+  "source": {"type": "directory", "target": "."},
+  "distro": {"name": "", "version": ""},
+  "descriptor": {"name": "grype", "version": "0.72.0"}
+}`
+
+const testImageDigest = "sha256:9b2a6b8c3f1e4d7a0c5b2e8f1a4d7c0b3e6f9a2d5c8b1e4f7a0d3c6b9e2f5a8c"
+
+func TestProcessTrivyResult(t *testing.T) {
+	useTestDB(t, &models.Asset{})
+
+	vulns, err := processTrivyResult([]byte(testTrivyReport), models.CIIntegration{ID: 6})
+	if err != nil {
+		t.Fatalf("processTrivyResult 返回错误: %v", err)
+	}
+	if len(vulns) != 3 {
+		t.Fatalf("漏洞数量 = %d, want 3", len(vulns))
+	}
+
+	ossl := vulns[0]
+	if ossl.CVE != "CVE-2023-0286" || ossl.Severity != models.SeverityHigh || ossl.Type != models.TypeVulnerableComponent ||
+		ossl.CVSS != 7.4 || ossl.Component != "libcrypto3" || ossl.FixedVersion != "3.0.8-r0" ||
+		ossl.Image != "registry.example.com/shop/api:2.3.1" || ossl.OperatingSystem != "alpine 3.16.2" ||
+		ossl.Location != "registry.example.com/shop/api:2.3.1 (alpine 3.16.2)" || ossl.Source != "trivy" {
+		t.Errorf("漏洞 = %+v", ossl)
+	}
+	if ossl.Identifiers != "CVE-2023-0286, CWE-843" || ossl.Solution != "将 libcrypto3 升级到 3.0.8-r0" {
+		t.Errorf("标识符 = %q, 修复建议 = %q", ossl.Identifiers, ossl.Solution)
+	}
+	// PrimaryURL 排在最前，重复的参考只保留一次
+	if ossl.References != "https://avd.aquasec.com/nvd/cve-2023-0286\nhttps://www.openssl.org/news/secadv/20230207.txt" {
+		t.Errorf("参考 = %q", ossl.References)
+	}
+
+	// CVSS 按严重程度来源选择，语言包的位置取 PkgPath
+	requests := vulns[1]
+	if requests.CVSS != 6.1 || requests.Vector != "CVSS:3.1/AV:N/AC:H/PR:N/UI:R/S:C/C:H/I:N/A:N" || requests.CVE != "" ||
+		requests.Location != "usr/lib/python3.10/site-packages/requests-2.28.1.dist-info/METADATA" {
+		t.Errorf("漏洞 = %v %q %q %q", requests.CVSS, requests.Vector, requests.CVE, requests.Location)
+	}
+	// 严重程度来源没有CVSS时取分值最高的来源，没有修复版本和标题时使用默认值
+	urllib := vulns[2]
+	if urllib.CVSS != 5.9 || urllib.Severity != models.SeverityInfo || urllib.Title != "CVE-2023-43804: urllib3" ||
+		urllib.Solution != "暂无修复版本" || urllib.Location != "app/requirements.txt" {
+		t.Errorf("漏洞 = %v %s %q %q %q", urllib.CVSS, urllib.Severity, urllib.Title, urllib.Solution, urllib.Location)
+	}
+
+	var assets []models.Asset
+	utils.DB.Find(&assets)
+	if len(assets) != 1 {
+		t.Fatalf("资产数量 = %d, want 1", len(assets))
+	}
+	asset := assets[0]
+	if asset.Type != models.AssetTypeContainerImage || asset.Identifier != testImageDigest ||
+		asset.Name != "registry.example.com/shop/api:2.3.1" || asset.Version != "2.3.1" || asset.OS != "alpine 3.16.2" {
+		t.Errorf("容器镜像资产 = %+v", asset)
+	}
+	for i, v := range vulns {
+		if len(v.Assets) != 1 || v.Assets[0].ID != asset.ID {
+			t.Errorf("漏洞 %d 应关联到容器镜像资产: %+v", i, v.Assets)
+		}
+	}
+
+	// 同一镜像升级标签后再次上报：复用资产，指纹不变
+	again, err := processTrivyResult([]byte(strings.Replace(testTrivyReport, `"ArtifactName": "registry.example.com/shop/api:2.3.1"`,
+		`"ArtifactName": "registry.example.com/shop/api:2.3.2"`, 1)), models.CIIntegration{ID: 6})
+	if err != nil {
+		t.Fatalf("processTrivyResult 返回错误: %v", err)
+	}
+	var count int
+	utils.DB.Model(&models.Asset{}).Count(&count)
+	if count != 1 || again[0].Assets[0].ID != asset.ID {
+		t.Errorf("相同摘要的镜像应复用资产，资产数量 = %d", count)
+	}
+	if again[0].Fingerprint != ossl.Fingerprint {
+		t.Error("镜像标签变化后指纹不应变化")
+	}
+}
+
+func TestProcessGrypeResult(t *testing.T) {
+	useTestDB(t, &models.Asset{})
+
+	vulns, err := processGrypeResult([]byte(testGrypeReport), models.CIIntegration{ID: 7})
+	if err != nil {
+		t.Fatalf("processGrypeResult 返回错误: %v", err)
+	}
+	if len(vulns) != 2 {
+		t.Fatalf("漏洞数量 = %d, want 2", len(vulns))
+	}
+
+	// GHSA 编号的漏洞从关联漏洞中补充CVE和CVSS
+	log4j := vulns[0]
+	if log4j.Title != "GHSA-jfh8-c2jp-5v3q: log4j-core" || log4j.CVE != "CVE-2021-44228" ||
+		log4j.Identifiers != "GHSA-jfh8-c2jp-5v3q, CVE-2021-44228" || log4j.Severity != models.SeverityCritical ||
+		log4j.CVSS != 10 || log4j.Vector != "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H" {
+		t.Errorf("漏洞 = %q %q %q %s %v %q", log4j.Title, log4j.CVE, log4j.Identifiers, log4j.Severity, log4j.CVSS, log4j.Vector)
+	}
+	if log4j.Description != "Remote code injection in Log4j\n\n受影响的组件: log4j-core 2.14.1" ||
+		log4j.FixedVersion != "2.15.0" || log4j.Location != "/app/lib/log4j-core-2.14.1.jar" ||
+		log4j.Scanner != "Grype 0.72.0" || log4j.Source != "grype" {
+		t.Errorf("漏洞 = %+v", log4j)
+	}
+	if !strings.Contains(log4j.References, "https://logging.apache.org/log4j/2.x/security.html") {
+		t.Errorf("参考 = %q", log4j.References)
+	}
+
+	// 未修复的漏洞不填修复版本，v3 分值低于 v2 时仍使用 v3
+	busybox := vulns[1]
+	if busybox.FixedVersion != "" || busybox.Severity != models.SeverityInfo || busybox.CVSS != 3.3 {
+		t.Errorf("漏洞 = %q %s %v", busybox.FixedVersion, busybox.Severity, busybox.CVSS)
+	}
+
+	var assets []models.Asset
+	utils.DB.Find(&assets)
+	if len(assets) != 1 || assets[0].Identifier != testImageDigest || assets[0].OS != "alpine 3.16.2" {
+		t.Fatalf("容器镜像资产 = %+v", assets)
+	}
+	if len(log4j.Assets) != 1 || log4j.Assets[0].ID != assets[0].ID || log4j.Image != "registry.example.com/shop/api:2.3.1" {
+		t.Errorf("漏洞应关联到容器镜像资产: %+v", log4j.Assets)
+	}
+}
+
+func TestProcessGrypeDirectoryResult(t *testing.T) {
+	useTestDB(t, &models.Asset{})
+
+	vulns, err := processGrypeResult([]byte(testGrypeDirReport), models.CIIntegration{ID: 7})
+	if err != nil {
+		t.Fatalf("processGrypeResult 返回错误: %v", err)
+	}
+	if len(vulns) != 1 {
+		t.Fatalf("漏洞数量 = %d, want 1", len(vulns))
+	}
+	v := vulns[0]
+	if v.CVE != "CVE-2022-25883" || v.CVSS != 5.9 || v.Image != "" || len(v.Assets) != 0 {
+		t.Errorf("漏洞 = %q %v %q %+v", v.CVE, v.CVSS, v.Image, v.Assets)
+	}
+
+	// 目录扫描不创建资产
+	var count int
+	utils.DB.Model(&models.Asset{}).Count(&count)
+	if count != 0 {
+		t.Errorf("资产数量 = %d, want 0", count)
+	}
+}
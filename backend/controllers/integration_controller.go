@@ -6,6 +6,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -64,6 +65,17 @@ func (i *IntegrationController) ReceiveScanResult(c *gin.Context) {
 		vulnerabilities, processErr = processGithubResult(body, integration)
 	case "custom":
 		vulnerabilities, processErr = processCustomResult(body, integration)
+	case "sarif":
+		vulnerabilities, processErr = processSarifResult(body, integration)
+	case "trivy":
+		vulnerabilities, processErr = processTrivyResult(body, integration)
+	case "grype":
+		vulnerabilities, processErr = processGrypeResult(body, integration)
+	case "cyclonedx", "spdx":
+		vulnerabilities, processErr = processSBOMResult(body, integration, integrationType, sbomUpload{
+			AssetID: c.Query("asset_id"),
+			Version: c.Query("version"),
+		})
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
@@ -83,23 +95,49 @@ func (i *IntegrationController) ReceiveScanResult(c *gin.Context) {
 	// 记录扫描结果
 	successCount := 0
 	errorCount := 0
+	var findings []gateFinding
 
 	for _, vuln := range vulnerabilities {
-		// 检查是否已存在相同漏洞
+		// 关联的资产在保存漏洞后单独追加，避免随漏洞一起被保存
+		assets := vuln.Assets
+		vuln.Assets = nil
+
+		// 检查是否已存在相同漏洞，带指纹的结果按指纹匹配，其余按CVE或标题匹配
 		var existingVuln models.Vulnerability
-		if err := utils.DB.Where("cve = ? OR title = ?", vuln.CVE, vuln.Title).First(&existingVuln).Error; err == nil {
-			// 更新现有漏洞
+		query := utils.DB.Where("cve = ? OR title = ?", vuln.CVE, vuln.Title)
+		if vuln.Fingerprint != "" {
+			query = utils.DB.Where("fingerprint = ?", vuln.Fingerprint).Order("id DESC")
+		}
+		if err := query.First(&existingVuln).Error; err == nil {
+			// 更新现有漏洞，按指纹匹配到的漏洞保留处理状态，已修复或已关闭的漏洞重新打开并标记为回归
 			existingVuln.UpdatedAt = time.Now()
-			existingVuln.Status = vuln.Status
+			regressed := false
+			if vuln.Fingerprint == "" {
+				existingVuln.Status = vuln.Status
+			} else if existingVuln.IsFixed() {
+				regressed = true
+				existingVuln.Status = models.StatusNew
+				existingVuln.IsRegression = true
+				existingVuln.FixedAt = nil
+				existingVuln.Notes = appendNote(existingVuln.Notes, fmt.Sprintf("[%s] CI集成「%s」再次上报该漏洞，已重新打开并标记为回归",
+					existingVuln.UpdatedAt.Format("2006-01-02 15:04:05"), integration.Name))
+			}
 			existingVuln.Description = vuln.Description
 			existingVuln.Severity = vuln.Severity
 			existingVuln.References = vuln.References
+			mergeCIFindingDetails(&existingVuln, &vuln)
 
 			if err := utils.DB.Save(&existingVuln).Error; err != nil {
 				log.Printf("更新漏洞失败: %v", err)
 				errorCount++
 				continue
 			}
+			linkCIFindingAssets(&existingVuln, assets)
+			if regressed {
+				updateRetestAssignments(existingVuln.ID, models.AssignmentStatusAccepted, 0,
+					fmt.Sprintf("复测未通过：CI集成「%s」再次上报该漏洞", integration.Name))
+			}
+			findings = append(findings, newGateFinding(&existingVuln, regressed))
 
 			successCount++
 		} else {
@@ -112,6 +150,8 @@ func (i *IntegrationController) ReceiveScanResult(c *gin.Context) {
 				errorCount++
 				continue
 			}
+			linkCIFindingAssets(&vuln, assets)
+			findings = append(findings, newGateFinding(&vuln, true))
 
 			successCount++
 		}
@@ -127,22 +167,63 @@ func (i *IntegrationController) ReceiveScanResult(c *gin.Context) {
 		ErrorCount:      errorCount,
 		ExecutedAt:      time.Now(),
 	}
+	gate := applyQualityGate(&integration, findings, &history)
 
 	if err := utils.DB.Create(&history).Error; err != nil {
 		log.Printf("记录集成历史失败: %v", err)
 	}
 
+	data := gin.H{
+		"total":         len(vulnerabilities),
+		"success_count": successCount,
+		"error_count":   errorCount,
+		"history_id":    history.ID,
+	}
+	// 配置了质量门禁时返回判断结果，流水线根据 gate.passed 决定是否中断构建
+	if gate != nil {
+		gate.HistoryID = history.ID
+		data["gate"] = gate
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "扫描结果处理成功",
-		"data": gin.H{
-			"total":         len(vulnerabilities),
-			"success_count": successCount,
-			"error_count":   errorCount,
-		},
+		"data":    data,
 	})
 }
 
+// mergeCIFindingDetails 用重新上报的结果更新已有漏洞的位置、组件等信息，上报中为空的字段保持不变
+func mergeCIFindingDetails(existing, vuln *models.Vulnerability) {
+	for _, field := range []struct{ dst, src *string }{
+		{&existing.Location, &vuln.Location},
+		{&existing.Identifiers, &vuln.Identifiers},
+		{&existing.Component, &vuln.Component},
+		{&existing.ComponentVersion, &vuln.ComponentVersion},
+		{&existing.FixedVersion, &vuln.FixedVersion},
+		{&existing.Image, &vuln.Image},
+		{&existing.OperatingSystem, &vuln.OperatingSystem},
+		{&existing.Scanner, &vuln.Scanner},
+		{&existing.Solution, &vuln.Solution},
+		{&existing.Vector, &vuln.Vector},
+	} {
+		if *field.src != "" {
+			*field.dst = *field.src
+		}
+	}
+	if vuln.CVSS > 0 {
+		existing.CVSS = vuln.CVSS
+	}
+}
+
+// linkCIFindingAssets 将漏洞关联到扫描结果所属的资产，已关联的资产不会重复添加
+func linkCIFindingAssets(vuln *models.Vulnerability, assets []models.Asset) {
+	if len(assets) == 0 {
+		return
+	}
+	if err := utils.DB.Model(vuln).Association("Assets").Append(assets).Error; err != nil {
+		log.Printf("关联漏洞资产失败: vuln_id=%d, err=%v", vuln.ID, err)
+	}
+}
+
 // 处理来自Jenkins的扫描结果
 func processJenkinsResult(data []byte, integration models.CIIntegration) ([]models.Vulnerability, error) {
 	var result struct {
@@ -177,54 +258,6 @@ func processJenkinsResult(data []byte, integration models.CIIntegration) ([]mode
 	return vulnerabilities, nil
 }
 
-// 处理来自GitLab CI的扫描结果
-func processGitlabResult(data []byte, integration models.CIIntegration) ([]models.Vulnerability, error) {
-	var result struct {
-		Vulnerabilities []struct {
-			Name        string `json:"name"`
-			Severity    string `json:"severity"`
-			Description string `json:"description"`
-			CVE         string `json:"cve"`
-			Solution    string `json:"solution"`
-			Location    struct {
-				File  string `json:"file"`
-				Start int    `json:"start_line"`
-				End   int    `json:"end_line"`
-			} `json:"location"`
-		} `json:"vulnerabilities"`
-	}
-
-	if err := json.Unmarshal(data, &result); err != nil {
-		return nil, err
-	}
-
-	var vulnerabilities []models.Vulnerability
-
-	for _, vuln := range result.Vulnerabilities {
-		locationInfo := ""
-		if vuln.Location.File != "" {
-			locationInfo = "文件: " + vuln.Location.File
-			if vuln.Location.Start > 0 {
-				locationInfo += ", 行: " + string(vuln.Location.Start)
-			}
-		}
-
-		v := models.Vulnerability{
-			Title:       vuln.Name,
-			Severity:    models.Severity(vuln.Severity),
-			Description: vuln.Description,
-			CVE:         vuln.CVE,
-			Solution:    vuln.Solution,
-			Status:      models.StatusNew,
-			Source:      "gitlab-ci",
-			References:  fmt.Sprintf("File: %s, Lines: %d-%d", vuln.Location.File, vuln.Location.Start, vuln.Location.End),
-		}
-		vulnerabilities = append(vulnerabilities, v)
-	}
-
-	return vulnerabilities, nil
-}
-
 // 处理来自GitHub Actions的扫描结果
 func processGithubResult(data []byte, integration models.CIIntegration) ([]models.Vulnerability, error) {
 	var result struct {
@@ -250,7 +283,7 @@ func processGithubResult(data []byte, integration models.CIIntegration) ([]model
 		if finding.Path != "" {
 			locationInfo = "文件: " + finding.Path
 			if finding.StartLine > 0 {
-				locationInfo += ", 行: " + string(finding.StartLine)
+				locationInfo += ", 行: " + strconv.Itoa(finding.StartLine)
 			}
 		}
 
@@ -346,6 +379,14 @@ func (i *IntegrationController) CreateIntegration(c *gin.Context) {
 		return
 	}
 
+	if _, err := integration.ParseQualityGate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
 	// 生成API密钥
 	integration.APIKey = utils.GenerateRandomString(32)
 	integration.CreatedAt = time.Now()
@@ -385,6 +426,7 @@ func (i *IntegrationController) UpdateIntegration(c *gin.Context) {
 		Description string `json:"description"`
 		Enabled     bool   `json:"enabled"`
 		Config      string `json:"config"`
+		QualityGate string `json:"quality_gate"`
 	}
 
 	if err := c.ShouldBindJSON(&updateData); err != nil {
@@ -400,8 +442,17 @@ func (i *IntegrationController) UpdateIntegration(c *gin.Context) {
 	integration.Description = updateData.Description
 	integration.Enabled = updateData.Enabled
 	integration.Config = updateData.Config
+	integration.QualityGate = updateData.QualityGate
 	integration.UpdatedAt = time.Now()
 
+	if _, err := integration.ParseQualityGate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
 	if err := utils.DB.Save(&integration).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/utils"
+)
+
+// testRegressionSarif 一条带部分指纹的SARIF结果，重复上报时按指纹匹配到同一个漏洞
+const testRegressionSarif = `{
+  "version": "2.1.0",
+  "runs": [{
+    "tool": {"driver": {"name": "Semgrep", "rules": [{"id": "sqli", "shortDescription": {"text": "SQL injection"},
+      "properties": {"security-severity": "8.1"}}]}},
+    "results": [{"ruleId": "sqli", "message": {"text": "Tainted SQL string"},
+      "locations": [{"physicalLocation": {"artifactLocation": {"uri": "app/db.py"}, "region": {"startLine": 12}}}],
+      "partialFingerprints": {"primaryLocationLineHash": "5a1b2c3d:1"}}]
+  }]
+}`
+
+// postCIResult 以集成的API密钥上报扫描结果，返回响应中的 data
+func postCIResult(t *testing.T, integration *models.CIIntegration, body string) map[string]interface{} {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/"+integration.Type, bytes.NewBufferString(body))
+	ctx.Request.Header.Set("X-API-Key", integration.APIKey)
+	ctx.Params = gin.Params{{Key: "type", Value: integration.Type}}
+	(&IntegrationController{}).ReceiveScanResult(ctx)
+	if w.Code != http.StatusOK {
+		t.Fatalf("状态码 = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	return resp.Data
+}
+
+func TestReceiveScanResultReopensFixedFindings(t *testing.T) {
+	useTestDB(t, &models.CIIntegration{}, &models.IntegrationHistory{}, &models.Vulnerability{}, &models.Asset{},
+		&models.VulnerabilityAssignment{}, &models.VulnerabilityAssignmentHistory{})
+	integration := models.CIIntegration{
+		Name:        "semgrep",
+		Type:        "sarif",
+		APIKey:      "ci-key",
+		Enabled:     true,
+		QualityGate: `{"max_new": {"high": 0}}`,
+	}
+	utils.DB.Create(&integration)
+
+	cases := []struct {
+		status     models.VulnStatus
+		wantStatus models.VulnStatus
+		regression bool
+	}{
+		{models.StatusFixed, models.StatusNew, true},
+		{models.StatusClosed, models.StatusNew, true},
+		{models.StatusInProgress, models.StatusInProgress, false},
+		{models.StatusFalsePositive, models.StatusFalsePositive, false},
+	}
+
+	postCIResult(t, &integration, testRegressionSarif)
+	var vuln models.Vulnerability
+	if err := utils.DB.First(&vuln).Error; err != nil {
+		t.Fatalf("首次上报未创建漏洞: %v", err)
+	}
+
+	for _, c := range cases {
+		fixedAt := time.Now()
+		utils.DB.Model(&vuln).Updates(map[string]interface{}{"status": c.status, "fixed_at": &fixedAt, "is_regression": false})
+
+		data := postCIResult(t, &integration, testRegressionSarif)
+		var got models.Vulnerability
+		utils.DB.First(&got, vuln.ID)
+		if got.Status != c.wantStatus || got.IsRegression != c.regression || (got.FixedAt == nil) != c.regression {
+			t.Errorf("%s: 状态 = %s, 回归 = %v, 修复时间 = %v", c.status, got.Status, got.IsRegression, got.FixedAt)
+		}
+
+		// 重新打开的漏洞按新增漏洞计入质量门禁
+		gate, _ := data["gate"].(map[string]interface{})
+		if passed, _ := gate["passed"].(bool); passed == c.regression {
+			t.Errorf("%s: 门禁结果 = %v", c.status, gate)
+		}
+	}
+
+	var count int
+	utils.DB.Model(&models.Vulnerability{}).Count(&count)
+	if count != 1 {
+		t.Errorf("重复上报应匹配到同一个漏洞，实际有 %d 个", count)
+	}
+}
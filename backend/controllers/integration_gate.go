@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/utils"
+)
+
+// 质量门禁判断结果
+const (
+	gatePassed = "passed"
+	gateFailed = "failed"
+)
+
+// acceptedVulnStatuses 接受风险的漏洞状态，门禁策略 ignore_accepted 时不计入
+var acceptedVulnStatuses = map[models.VulnStatus]bool{
+	models.StatusClosed:        true,
+	models.StatusFalsePositive: true,
+}
+
+// gateFinding 本次上报中参与门禁判断的漏洞
+type gateFinding struct {
+	ID        uint              `json:"id"`
+	Title     string            `json:"title"`
+	Severity  models.Severity   `json:"severity"`
+	Status    models.VulnStatus `json:"status"`
+	CVE       string            `json:"cve,omitempty"`
+	Component string            `json:"component,omitempty"`
+	Location  string            `json:"location,omitempty"`
+	New       bool              `json:"new"` // 本次上报新建的漏洞
+}
+
+func newGateFinding(vuln *models.Vulnerability, created bool) gateFinding {
+	return gateFinding{
+		ID:        vuln.ID,
+		Title:     vuln.Title,
+		Severity:  vuln.Severity,
+		Status:    vuln.Status,
+		CVE:       vuln.CVE,
+		Component: vuln.Component,
+		Location:  vuln.Location,
+		New:       created,
+	}
+}
+
+// gateViolation 违反的门禁规则
+type gateViolation struct {
+	Rule     string          `json:"rule"` // max_new, max_total
+	Severity models.Severity `json:"severity"`
+	Limit    int             `json:"limit"`
+	Actual   int             `json:"actual"`
+	Findings []gateFinding   `json:"findings"`
+}
+
+// gateDecision 质量门禁判断结果
+type gateDecision struct {
+	Passed     bool                      `json:"passed"`
+	Message    string                    `json:"message,omitempty"`
+	Policy     *models.QualityGatePolicy `json:"policy,omitempty"`
+	Violations []gateViolation           `json:"violations"`
+	HistoryID  uint                      `json:"history_id,omitempty"`
+}
+
+// status 历史记录中的门禁状态
+func (d *gateDecision) status() string {
+	if d.Passed {
+		return gatePassed
+	}
+	return gateFailed
+}
+
+// evaluateQualityGate 按门禁策略判断本次上报的漏洞，同一漏洞上报多次只计一次
+func evaluateQualityGate(policy *models.QualityGatePolicy, findings []gateFinding) *gateDecision {
+	seen := make(map[uint]bool)
+	newBySeverity := make(map[models.Severity][]gateFinding)
+	allBySeverity := make(map[models.Severity][]gateFinding)
+	for _, f := range findings {
+		if seen[f.ID] {
+			continue
+		}
+		seen[f.ID] = true
+		if policy.IgnoreAccepted && acceptedVulnStatuses[f.Status] {
+			continue
+		}
+		allBySeverity[f.Severity] = append(allBySeverity[f.Severity], f)
+		if f.New {
+			newBySeverity[f.Severity] = append(newBySeverity[f.Severity], f)
+		}
+	}
+
+	decision := &gateDecision{Passed: true, Policy: policy, Violations: []gateViolation{}}
+	for _, rule := range []struct {
+		name   string
+		limits map[models.Severity]int
+		counts map[models.Severity][]gateFinding
+	}{
+		{"max_new", policy.MaxNew, newBySeverity},
+		{"max_total", policy.MaxTotal, allBySeverity},
+	} {
+		// 按严重程度从高到低输出
+		for _, severity := range []models.Severity{
+			models.SeverityCritical, models.SeverityHigh, models.SeverityMedium, models.SeverityLow, models.SeverityInfo,
+		} {
+			limit, ok := rule.limits[severity]
+			if !ok || len(rule.counts[severity]) <= limit {
+				continue
+			}
+			decision.Passed = false
+			decision.Violations = append(decision.Violations, gateViolation{
+				Rule:     rule.name,
+				Severity: severity,
+				Limit:    limit,
+				Actual:   len(rule.counts[severity]),
+				Findings: rule.counts[severity],
+			})
+		}
+	}
+	if !decision.Passed {
+		decision.Message = "扫描结果未通过质量门禁"
+	}
+	return decision
+}
+
+// applyQualityGate 按集成的门禁策略判断本次上报的结果并记录到历史中，未配置策略时返回 nil
+//
+// 策略无效时门禁不通过，避免配置错误导致有漏洞的代码被放行。
+func applyQualityGate(integration *models.CIIntegration, findings []gateFinding, history *models.IntegrationHistory) *gateDecision {
+	policy, err := integration.ParseQualityGate()
+	if policy == nil && err == nil {
+		return nil
+	}
+
+	var decision *gateDecision
+	if err != nil {
+		log.Printf("集成的质量门禁策略无效: integration_id=%d, err=%v", integration.ID, err)
+		decision = &gateDecision{Passed: false, Message: err.Error(), Violations: []gateViolation{}}
+	} else {
+		decision = evaluateQualityGate(policy, findings)
+	}
+
+	history.GateStatus = decision.status()
+	if data, err := json.Marshal(decision); err == nil {
+		history.GateResult = string(data)
+	}
+	return decision
+}
+
+// GetGateDecision 获取最近一次上报（或 history_id 指定的上报）的质量门禁判断结果，通过API密钥认证
+func (i *IntegrationController) GetGateDecision(c *gin.Context) {
+	integrationType := c.Param("type")
+	apiKey := c.GetHeader("X-API-Key")
+	if apiKey == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "未提供API密钥",
+		})
+		return
+	}
+
+	var integration models.CIIntegration
+	if err := utils.DB.Where("api_key = ? AND type = ? AND enabled = ?", apiKey, integrationType, true).First(&integration).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "无效的API密钥或集成类型",
+		})
+		return
+	}
+
+	query := utils.DB.Where("integration_id = ?", integration.ID)
+	if historyID := c.Query("history_id"); historyID != "" {
+		query = query.Where("id = ?", historyID)
+	}
+	var history models.IntegrationHistory
+	if err := query.Order("id DESC").First(&history).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "没有上报记录",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取上报记录失败: " + err.Error(),
+		})
+		return
+	}
+	if history.GateStatus == "" {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "该次上报没有质量门禁判断结果，请先为集成配置质量门禁策略",
+		})
+		return
+	}
+
+	var decision gateDecision
+	if err := json.Unmarshal([]byte(history.GateResult), &decision); err != nil {
+		decision = gateDecision{Passed: history.GateStatus == gatePassed, Violations: []gateViolation{}}
+	}
+	decision.HistoryID = history.ID
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    decision,
+	})
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/vulnark/vulnark/models"
+)
+
+func TestEvaluateQualityGate(t *testing.T) {
+	findings := []gateFinding{
+		{ID: 1, Severity: models.SeverityCritical, Status: models.StatusNew, New: true},
+		{ID: 2, Severity: models.SeverityHigh, Status: models.StatusNew},
+		{ID: 3, Severity: models.SeverityHigh, Status: models.StatusFalsePositive},
+		{ID: 4, Severity: models.SeverityHigh, Status: models.StatusClosed, New: true},
+		{ID: 2, Severity: models.SeverityHigh, Status: models.StatusNew}, // 同一漏洞重复上报
+	}
+
+	cases := []struct {
+		name   string
+		policy models.QualityGatePolicy
+		passed bool
+		rules  []string // 违反的规则，格式为 rule:severity:actual
+	}{
+		{
+			name:   "新增严重漏洞不通过",
+			policy: models.QualityGatePolicy{MaxNew: map[models.Severity]int{models.SeverityCritical: 0}},
+			passed: false,
+			rules:  []string{"max_new:critical:1"},
+		},
+		{
+			name:   "未超过上限",
+			policy: models.QualityGatePolicy{MaxNew: map[models.Severity]int{models.SeverityCritical: 1, models.SeverityHigh: 1}},
+			passed: true,
+		},
+		{
+			name:   "重复上报只计一次",
+			policy: models.QualityGatePolicy{MaxTotal: map[models.Severity]int{models.SeverityHigh: 2}},
+			passed: false,
+			rules:  []string{"max_total:high:3"},
+		},
+		{
+			name: "不计入已接受风险的漏洞",
+			policy: models.QualityGatePolicy{
+				MaxNew:         map[models.Severity]int{models.SeverityHigh: 0},
+				MaxTotal:       map[models.Severity]int{models.SeverityHigh: 1},
+				IgnoreAccepted: true,
+			},
+			passed: true,
+		},
+		{
+			name: "按规则和严重程度排序",
+			policy: models.QualityGatePolicy{
+				MaxNew:   map[models.Severity]int{models.SeverityHigh: 0, models.SeverityCritical: 0},
+				MaxTotal: map[models.Severity]int{models.SeverityHigh: 0, models.SeverityCritical: 0},
+			},
+			passed: false,
+			rules:  []string{"max_new:critical:1", "max_new:high:1", "max_total:critical:1", "max_total:high:3"},
+		},
+		{
+			name:   "空策略通过",
+			passed: true,
+		},
+	}
+
+	for _, c := range cases {
+		policy := c.policy
+		decision := evaluateQualityGate(&policy, findings)
+		if decision.Passed != c.passed {
+			t.Errorf("%s: Passed = %v, want %v", c.name, decision.Passed, c.passed)
+		}
+		if decision.Passed != (decision.status() == gatePassed) || decision.Passed != (decision.Message == "") {
+			t.Errorf("%s: 状态和提示与判断结果不一致: %+v", c.name, decision)
+		}
+
+		var rules []string
+		for _, v := range decision.Violations {
+			rules = append(rules, v.Rule+":"+string(v.Severity)+":"+strconv.Itoa(v.Actual))
+			if v.Actual != len(v.Findings) {
+				t.Errorf("%s: %s 的漏洞数 %d 与列出的漏洞 %d 不一致", c.name, v.Rule, v.Actual, len(v.Findings))
+			}
+		}
+		if len(rules) != len(c.rules) {
+			t.Errorf("%s: 违反的规则 = %v, want %v", c.name, rules, c.rules)
+			continue
+		}
+		for i := range rules {
+			if rules[i] != c.rules[i] {
+				t.Errorf("%s: 违反的规则 = %v, want %v", c.name, rules, c.rules)
+				break
+			}
+		}
+	}
+}
+
+func TestParseQualityGate(t *testing.T) {
+	valid := models.CIIntegration{QualityGate: `{"max_new": {"critical": 0}, "max_total": {"high": 5}, "ignore_accepted": true}`}
+	policy, err := valid.ParseQualityGate()
+	if err != nil || policy == nil {
+		t.Fatalf("ParseQualityGate 返回 %v, %v", policy, err)
+	}
+	if policy.MaxNew[models.SeverityCritical] != 0 || policy.MaxTotal[models.SeverityHigh] != 5 || !policy.IgnoreAccepted {
+		t.Errorf("解析结果错误: %+v", policy)
+	}
+
+	if policy, err := (&models.CIIntegration{QualityGate: "  "}).ParseQualityGate(); policy != nil || err != nil {
+		t.Errorf("未配置策略时应返回 nil, nil，实际为 %v, %v", policy, err)
+	}
+
+	for _, raw := range []string{
+		`{"max_new": {"urgent": 0}}`,
+		`{"max_total": {"high": -1}}`,
+		`not json`,
+	} {
+		if _, err := (&models.CIIntegration{QualityGate: raw}).ParseQualityGate(); err == nil {
+			t.Errorf("ParseQualityGate(%s) 应返回错误", raw)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vulnark/vulnark/models"
+)
+
+// GitLab安全报告的类别
+const (
+	gitlabSAST                 = "sast"
+	gitlabDependencyScanning   = "dependency_scanning"
+	gitlabContainerScanning    = "container_scanning"
+	gitlabSecretDetection      = "secret_detection"
+	gitlabDAST                 = "dast"
+	gitlabClusterImageScanning = "cluster_image_scanning"
+)
+
+// gitlabReport GitLab安全报告（gl-*-report.json），兼容 14.x/15.x 的报告格式
+type gitlabReport struct {
+	Version         string                `json:"version"`
+	Vulnerabilities []gitlabVulnerability `json:"vulnerabilities"`
+	Remediations    []struct {
+		Fixes []struct {
+			ID  string `json:"id"`
+			CVE string `json:"cve"`
+		} `json:"fixes"`
+		Summary string `json:"summary"`
+	} `json:"remediations"`
+	Scan struct {
+		Type    string        `json:"type"`
+		Scanner gitlabScanner `json:"scanner"`
+	} `json:"scan"`
+}
+
+type gitlabScanner struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Vendor  struct {
+		Name string `json:"name"`
+	} `json:"vendor"`
+}
+
+type gitlabIdentifier struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	URL   string `json:"url"`
+}
+
+type gitlabVulnerability struct {
+	ID          string             `json:"id"`
+	Category    string             `json:"category"` // 14.x 及更早的报告在每条漏洞上标注类别
+	Name        string             `json:"name"`
+	Message     string             `json:"message"`
+	Description string             `json:"description"`
+	CVE         string             `json:"cve"`
+	Severity    string             `json:"severity"`
+	Solution    string             `json:"solution"`
+	Scanner     gitlabScanner      `json:"scanner"`
+	Identifiers []gitlabIdentifier `json:"identifiers"`
+	Links       []struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"links"`
+	Location struct {
+		// SAST、密钥检测
+		File      string `json:"file"`
+		StartLine int    `json:"start_line"`
+		EndLine   int    `json:"end_line"`
+		Class     string `json:"class"`
+		Method    string `json:"method"` // SAST 中为函数名，DAST 中为HTTP方法
+		Commit    struct {
+			Sha string `json:"sha"`
+		} `json:"commit"`
+
+		// 依赖扫描、容器扫描
+		Dependency struct {
+			Package struct {
+				Name string `json:"name"`
+			} `json:"package"`
+			Version string `json:"version"`
+		} `json:"dependency"`
+		Image           string `json:"image"`
+		OperatingSystem string `json:"operating_system"`
+
+		// DAST
+		Hostname string `json:"hostname"`
+		Path     string `json:"path"`
+		Param    string `json:"param"`
+	} `json:"location"`
+}
+
+// 处理来自GitLab CI的扫描结果
+//
+// 支持GitLab官方的 SAST、依赖扫描、容器扫描、密钥检测和 DAST 报告格式，
+// 报告类别取 scan.type，旧版报告取每条漏洞的 category，都没有时按位置信息推断。
+func processGitlabResult(data []byte, integration models.CIIntegration) ([]models.Vulnerability, error) {
+	var report gitlabReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+
+	// 修复建议按漏洞ID关联
+	remediations := make(map[string][]string)
+	for _, r := range report.Remediations {
+		if strings.TrimSpace(r.Summary) == "" {
+			continue
+		}
+		for _, fix := range r.Fixes {
+			key := fix.ID
+			if key == "" {
+				key = fix.CVE
+			}
+			if key == "" {
+				continue
+			}
+			remediations[key] = append(remediations[key], strings.TrimSpace(r.Summary))
+		}
+	}
+
+	now := time.Now()
+	vulnerabilities := make([]models.Vulnerability, 0, len(report.Vulnerabilities))
+	for i := range report.Vulnerabilities {
+		gv := &report.Vulnerabilities[i]
+		loc := &gv.Location
+
+		category := gitlabCategory(report.Scan.Type, gv)
+		scanner := report.Scan.Scanner
+		if scanner.Name == "" {
+			scanner = gv.Scanner
+		}
+
+		// 标识符：第一个为主标识符，CVE和CWE分别提取
+		var identifiers, references []string
+		cve, cwe := "", ""
+		for _, id := range gv.Identifiers {
+			value := gitlabIdentifierValue(id)
+			if value == "" {
+				continue
+			}
+			identifiers = append(identifiers, value)
+			if id.URL != "" {
+				references = append(references, id.URL)
+			}
+			switch strings.ToLower(id.Type) {
+			case "cve":
+				if cve == "" {
+					cve = strings.ToUpper(value)
+				}
+			case "cwe":
+				if cwe == "" {
+					cwe = value
+				}
+			}
+		}
+		if cve == "" && cvePattern.MatchString(gv.CVE) {
+			cve = strings.ToUpper(cvePattern.FindString(gv.CVE))
+		}
+		for _, link := range gv.Links {
+			if link.URL != "" {
+				references = append(references, link.URL)
+			}
+		}
+
+		title := gv.Name
+		if title == "" {
+			title = gv.Message
+		}
+		if title == "" && len(gv.Identifiers) > 0 {
+			title = gv.Identifiers[0].Name
+		}
+
+		solution := gv.Solution
+		for _, key := range uniqueStrings([]string{gv.ID, gv.CVE}) {
+			for _, summary := range remediations[key] {
+				solution = strings.TrimSpace(solution + "\n" + summary)
+			}
+		}
+
+		vuln := models.Vulnerability{
+			Title:            truncateString(title, 255),
+			CVE:              truncateString(cve, 50),
+			Description:      gv.Description,
+			Type:             gitlabVulnType(category, cwe, title),
+			Severity:         gitlabSeverity(gv.Severity),
+			Status:           models.StatusNew,
+			Identifiers:      strings.Join(identifiers, ", "),
+			Component:        truncateString(loc.Dependency.Package.Name, 255),
+			ComponentVersion: truncateString(loc.Dependency.Version, 100),
+			Image:            truncateString(loc.Image, 255),
+			OperatingSystem:  truncateString(loc.OperatingSystem, 100),
+			Scanner:          truncateString(gitlabScannerName(scanner), 100),
+			Solution:         solution,
+			References:       strings.Join(uniqueStrings(references), "\n"),
+			Source:           "gitlab-ci",
+			DiscoveredAt:     now,
+		}
+
+		// 位置和指纹：与GitLab相同，按类别、主标识符和位置区分同一漏洞
+		var position string
+		switch category {
+		case gitlabDependencyScanning:
+			vuln.Location = loc.File
+			position = loc.File + ":" + loc.Dependency.Package.Name
+		case gitlabContainerScanning, gitlabClusterImageScanning:
+			vuln.Location = loc.Image
+			position = imageName(loc.Image) + ":" + loc.Dependency.Package.Name
+		case gitlabDAST:
+			vuln.Location = strings.TrimSpace(strings.ToUpper(loc.Method) + " " + loc.Hostname + loc.Path)
+			if loc.Param != "" {
+				vuln.Location += " (" + loc.Param + ")"
+			}
+			position = loc.Method + ":" + loc.Hostname + loc.Path + ":" + loc.Param
+		default:
+			vuln.Location = fileLocation(loc.File, loc.StartLine, loc.EndLine)
+			position = fmt.Sprintf("%s:%d:%d", loc.File, loc.StartLine, loc.EndLine)
+			if category == gitlabSecretDetection && loc.Commit.Sha != "" {
+				vuln.StepsToReproduce = "Commit: " + loc.Commit.Sha
+			}
+			if loc.Class != "" || loc.Method != "" {
+				vuln.StepsToReproduce = strings.TrimSpace(vuln.StepsToReproduce + "\n" +
+					strings.Trim(loc.Class+"."+loc.Method, "."))
+			}
+		}
+		vuln.Location = truncateString(vuln.Location, 500)
+		if vuln.Component != "" {
+			vuln.Description = strings.TrimSpace(fmt.Sprintf("%s\n\n受影响的组件: %s %s",
+				vuln.Description, vuln.Component, vuln.ComponentVersion))
+		}
+
+		primary := ""
+		if len(identifiers) > 0 {
+			primary = identifiers[0]
+		}
+		vuln.Fingerprint = ciFingerprint(integration.ID, category, primary, position)
+
+		vulnerabilities = append(vulnerabilities, vuln)
+	}
+
+	return vulnerabilities, nil
+}
+
+// gitlabCategory 确定漏洞所属的报告类别
+func gitlabCategory(scanType string, gv *gitlabVulnerability) string {
+	if scanType != "" {
+		return strings.ToLower(scanType)
+	}
+	if gv.Category != "" {
+		return strings.ToLower(gv.Category)
+	}
+	switch {
+	case gv.Location.Image != "":
+		return gitlabContainerScanning
+	case gv.Location.Dependency.Package.Name != "":
+		return gitlabDependencyScanning
+	case gv.Location.Hostname != "":
+		return gitlabDAST
+	}
+	return gitlabSAST
+}
+
+// gitlabVulnType 按报告类别和CWE确定漏洞类型
+func gitlabVulnType(category, cwe, title string) models.VulnType {
+	switch category {
+	case gitlabDependencyScanning, gitlabContainerScanning, gitlabClusterImageScanning:
+		return models.TypeVulnerableComponent
+	case gitlabSecretDetection:
+		return models.TypeSecretLeak
+	}
+	if cwe != "" {
+		if t, ok := getVulnerabilityTypeFromCWE(cwe); ok {
+			return t
+		}
+	}
+	return getVulnerabilityTypeFromCategory(title)
+}
+
+// gitlabSeverity 转换GitLab的严重程度，Unknown 等无法识别的值按信息级别处理
+func gitlabSeverity(severity string) models.Severity {
+	switch s := models.Severity(strings.ToLower(strings.TrimSpace(severity))); s {
+	case models.SeverityCritical, models.SeverityHigh, models.SeverityMedium, models.SeverityLow:
+		return s
+	}
+	return models.SeverityInfo
+}
+
+// gitlabIdentifierValue 标识符的展示值，CWE统一为 CWE-79 的形式
+func gitlabIdentifierValue(id gitlabIdentifier) string {
+	value := strings.TrimSpace(id.Value)
+	if value == "" {
+		value = strings.TrimSpace(id.Name)
+	}
+	if strings.EqualFold(id.Type, "cwe") && !strings.HasPrefix(strings.ToUpper(value), "CWE") {
+		value = "CWE-" + value
+	}
+	return value
+}
+
+// gitlabScannerName 扫描器名称及厂商，如 "Gemnasium 4.2.0 (GitLab)"
+func gitlabScannerName(s gitlabScanner) string {
+	name := s.Name
+	if name == "" {
+		name = s.ID
+	}
+	name = strings.TrimSpace(name + " " + s.Version)
+	if s.Vendor.Name != "" {
+		name += " (" + s.Vendor.Name + ")"
+	}
+	return name
+}
+
+// fileLocation 返回形如 "path/to/file.go:12-15" 的位置描述
+func fileLocation(file string, start, end int) string {
+	if file == "" {
+		return ""
+	}
+	if start > 0 {
+		file += ":" + strconv.Itoa(start)
+		if end > start {
+			file += "-" + strconv.Itoa(end)
+		}
+	}
+	return file
+}
+
+// imageName 去掉镜像的标签和摘要，镜像升级标签后仍能匹配到同一漏洞
+func imageName(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
+
+// uniqueStrings 去除重复的字符串，保持原有顺序
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := values[:0]
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,317 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vulnark/vulnark/models"
+)
+
+const testGitlabSAST = `{
+  "version": "15.0.6",
+  "vulnerabilities": [
+    {
+      "id": "6d1ee9a7c0f9a4e3d1b0c9f5e6a2b7c8d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8",
+      "category": "sast",
+      "name": "Improper Neutralization of Special Elements used in an SQL Command ('SQL Injection')",
+      "description": "Detected a formatted string in a SQL statement.",
+      "cve": "semgrep_id:bandit.B608:4321:4323",
+      "severity": "High",
+      "scanner": {"id": "semgrep", "name": "Semgrep"},
+      "location": {"file": "app/models/report.py", "start_line": 4321, "end_line": 4323, "class": "ReportQuery", "method": "by_owner"},
+      "identifiers": [
+        {"type": "semgrep_id", "name": "bandit.B608", "value": "bandit.B608", "url": "https://semgrep.dev/r/gitlab.bandit.B608"},
+        {"type": "cwe", "name": "CWE-89", "value": "89", "url": "https://cwe.mitre.org/data/definitions/89.html"},
+        {"type": "owasp", "name": "A03:2021 - Injection", "value": "A03:2021"}
+      ]
+    }
+  ],
+  "scan": {
+    "analyzer": {"id": "semgrep", "name": "Semgrep", "version": "4.4.1", "vendor": {"name": "GitLab"}},
+    "scanner": {"id": "semgrep", "name": "Semgrep", "version": "1.41.0", "vendor": {"name": "GitLab"}},
+    "type": "sast",
+    "start_time": "2023-10-12T08:14:21",
+    "end_time": "2023-10-12T08:15:02",
+    "status": "success"
+  }
+}`
+
+const testGitlabDependencyScanning = `{
+  "version": "15.0.6",
+  "vulnerabilities": [
+    {
+      "id": "f3c7a5e2d9b1e0c4a6b8d2f1e3c5a7b9d0e2f4a6c8b0d1e3f5a7c9b2d4e6f8a0",
+      "name": "Prototype Pollution in lodash",
+      "description": "Versions of lodash prior to 4.17.19 are vulnerable to Prototype Pollution.",
+      "severity": "Critical",
+      "solution": "Upgrade to version 4.17.19 or above.",
+      "location": {"file": "package-lock.json", "dependency": {"package": {"name": "lodash"}, "version": "4.17.15"}},
+      "identifiers": [
+        {"type": "gemnasium", "name": "Gemnasium-7bca3fd6-6fa6-4d05-ba3f-3ba8a5e9b0fd", "value": "7bca3fd6-6fa6-4d05-ba3f-3ba8a5e9b0fd", "url": "https://gitlab.com/gitlab-org/security-products/gemnasium-db/-/blob/master/npm/lodash/CVE-2020-8203.yml"},
+        {"type": "cve", "name": "CVE-2020-8203", "value": "cve-2020-8203", "url": "https://nvd.nist.gov/vuln/detail/CVE-2020-8203"}
+      ],
+      "links": [{"url": "https://github.com/lodash/lodash/issues/4744"}, {"url": "https://nvd.nist.gov/vuln/detail/CVE-2020-8203"}]
+    }
+  ],
+  "remediations": [
+    {
+      "fixes": [{"id": "f3c7a5e2d9b1e0c4a6b8d2f1e3c5a7b9d0e2f4a6c8b0d1e3f5a7c9b2d4e6f8a0"}],
+      "summary": "Upgrade lodash to 4.17.21",
+      "diff": "ZGlmZiAtLWdpdCBhL3lhcm4ubG9jaw=="
+    }
+  ],
+  "dependency_files": [],
+  "scan": {
+    "scanner": {"id": "gemnasium", "name": "Gemnasium", "version": "4.2.0", "vendor": {"name": "GitLab"}},
+    "type": "dependency_scanning",
+    "status": "success"
+  }
+}`
+
+const testGitlabContainerScanning = `{
+  "version": "15.0.6",
+  "vulnerabilities": [
+    {
+      "id": "0b6c1e3f5a7d9c2e4f6a8b0d1c3e5f7a9b2d4c6e8f0a1b3d5c7e9f2a4b6d8c0e",
+      "name": "CVE-2022-37434",
+      "description": "zlib through 1.2.12 has a heap-based buffer over-read or buffer overflow in inflate.",
+      "severity": "Critical",
+      "solution": "Upgrade zlib to 1.2.12-r2",
+      "location": {
+        "dependency": {"package": {"name": "zlib"}, "version": "1.2.12-r1"},
+        "operating_system": "alpine 3.16.1",
+        "image": "registry.example.com/shop/web:1.4.2"
+      },
+      "identifiers": [{"type": "cve", "name": "CVE-2022-37434", "value": "CVE-2022-37434", "url": "https://nvd.nist.gov/vuln/detail/CVE-2022-37434"}],
+      "links": [{"url": "https://github.com/madler/zlib/issues/668"}]
+    }
+  ],
+  "remediations": [],
+  "scan": {
+    "scanner": {"id": "trivy", "name": "Trivy", "version": "0.44.1", "vendor": {"name": "GitLab"}},
+    "type": "container_scanning",
+    "status": "success"
+  }
+}`
+
+const testGitlabSecretDetection = `{
+  "version": "15.0.6",
+  "vulnerabilities": [
+    {
+      "id": "27d2322d519c94f803ffed1cf6d14e455df13b5ac3d0ee6edd3b0d8c51a8e8c4",
+      "category": "secret_detection",
+      "name": "AWS access token",
+      "description": "AWS access token",
+      "cve": "config/settings.yml:6c6b6d7e:AWS",
+      "severity": "Critical",
+      "confidence": "Unknown",
+      "scanner": {"id": "gitleaks", "name": "Gitleaks"},
+      "location": {"file": "config/settings.yml", "commit": {"author": "dev", "date": "2023-10-10T09:12:00Z", "message": "add settings", "sha": "8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d9c"}, "start_line": 12},
+      "identifiers": [{"type": "gitleaks_rule_id", "name": "Gitleaks rule ID AWS", "value": "AWS"}]
+    }
+  ],
+  "scan": {
+    "scanner": {"id": "gitleaks", "name": "Gitleaks", "version": "8.18.0", "vendor": {"name": "GitLab"}},
+    "type": "secret_detection",
+    "status": "success"
+  }
+}`
+
+const testGitlabDAST = `{
+  "version": "15.0.6",
+  "vulnerabilities": [
+    {
+      "id": "a9d0e3c7-83d4-4f3b-9e2a-5d5b9c2a4e7f",
+      "name": "Cross Site Scripting (Reflected)",
+      "description": "Cross-site Scripting (XSS) is an attack technique that involves echoing attacker-supplied code into a user's browser instance.",
+      "severity": "Medium",
+      "solution": "Validate all input and encode output.",
+      "location": {"hostname": "https://staging.example.com", "method": "GET", "path": "/search", "param": "q"},
+      "identifiers": [
+        {"type": "ZAProxy_PluginId", "name": "Cross Site Scripting (Reflected)", "value": "40012", "url": "https://www.zaproxy.org/docs/alerts/40012/"},
+        {"type": "CWE", "name": "CWE-79", "value": "79", "url": "https://cwe.mitre.org/data/definitions/79.html"}
+      ],
+      "links": [{"url": "http://projects.webappsec.org/Cross-Site-Scripting"}]
+    },
+    {
+      "id": "c1f2e3d4-5a6b-4c7d-8e9f-0a1b2c3d4e5f",
+      "name": "Server Leaks Version Information",
+      "severity": "Unknown",
+      "location": {"hostname": "https://staging.example.com", "method": "GET", "path": "/"},
+      "identifiers": [{"type": "ZAProxy_PluginId", "name": "Server Leaks Version Information", "value": "10036"}]
+    }
+  ],
+  "scan": {
+    "scanner": {"id": "zaproxy", "name": "OWASP Zed Attack Proxy (ZAP)", "version": "D-2023-08-14", "vendor": {"name": "GitLab"}},
+    "type": "dast",
+    "status": "success"
+  }
+}`
+
+// testGitlabLegacyReport 14.x 的报告没有 scan.type，每条漏洞上标注类别
+const testGitlabLegacyReport = `{
+  "version": "14.1.2",
+  "vulnerabilities": [
+    {
+      "id": "1a2b3c",
+      "category": "dependency_scanning",
+      "message": "Regular Expression Denial of Service in minimatch",
+      "cve": "yarn.lock:minimatch:gemnasium:CVE-2022-3517",
+      "severity": "High",
+      "scanner": {"id": "gemnasium", "name": "Gemnasium"},
+      "location": {"file": "yarn.lock", "dependency": {"package": {"name": "minimatch"}, "version": "3.0.4"}},
+      "identifiers": [{"type": "gemnasium", "name": "Gemnasium-minimatch", "value": "minimatch-redos"}]
+    },
+    {
+      "id": "4d5e6f",
+      "message": "Use of MD5",
+      "severity": "Low",
+      "scanner": {"id": "gosec", "name": "Gosec"},
+      "location": {"file": "internal/hash.go", "start_line": 9},
+      "identifiers": [{"type": "gosec_rule_id", "name": "Gosec Rule ID G401", "value": "G401"}]
+    }
+  ]
+}`
+
+func processGitlabTestReport(t *testing.T, report string) []models.Vulnerability {
+	t.Helper()
+	vulns, err := processGitlabResult([]byte(report), models.CIIntegration{ID: 5})
+	if err != nil {
+		t.Fatalf("processGitlabResult 返回错误: %v", err)
+	}
+	return vulns
+}
+
+func TestProcessGitlabSAST(t *testing.T) {
+	vulns := processGitlabTestReport(t, testGitlabSAST)
+	if len(vulns) != 1 {
+		t.Fatalf("漏洞数量 = %d, want 1", len(vulns))
+	}
+	v := vulns[0]
+	// 行号超过一个字符的编码范围时仍应按数字输出
+	if v.Location != "app/models/report.py:4321-4323" {
+		t.Errorf("位置 = %q", v.Location)
+	}
+	if v.Type != models.TypeSQLInjection || v.Severity != models.SeverityHigh || v.CVE != "" {
+		t.Errorf("类型 = %s, 严重程度 = %s, CVE = %q", v.Type, v.Severity, v.CVE)
+	}
+	if v.Identifiers != "bandit.B608, CWE-89, A03:2021" || v.StepsToReproduce != "ReportQuery.by_owner" {
+		t.Errorf("标识符 = %q, 复现步骤 = %q", v.Identifiers, v.StepsToReproduce)
+	}
+	if v.Scanner != "Semgrep 1.41.0 (GitLab)" || v.Source != "gitlab-ci" {
+		t.Errorf("扫描器 = %q, 来源 = %q", v.Scanner, v.Source)
+	}
+	if !strings.Contains(v.References, "https://cwe.mitre.org/data/definitions/89.html") {
+		t.Errorf("参考 = %q", v.References)
+	}
+
+	// 代码移动后按位置区分为新的发现
+	moved := processGitlabTestReport(t, strings.Replace(testGitlabSAST, `"start_line": 4321`, `"start_line": 4320`, 1))
+	if moved[0].Fingerprint == v.Fingerprint {
+		t.Error("SAST 漏洞的指纹应包含行号")
+	}
+}
+
+func TestProcessGitlabDependencyScanning(t *testing.T) {
+	vulns := processGitlabTestReport(t, testGitlabDependencyScanning)
+	if len(vulns) != 1 {
+		t.Fatalf("漏洞数量 = %d, want 1", len(vulns))
+	}
+	v := vulns[0]
+	if v.Type != models.TypeVulnerableComponent || v.Severity != models.SeverityCritical || v.CVE != "CVE-2020-8203" {
+		t.Errorf("类型 = %s, 严重程度 = %s, CVE = %q", v.Type, v.Severity, v.CVE)
+	}
+	if v.Component != "lodash" || v.ComponentVersion != "4.17.15" || v.Location != "package-lock.json" {
+		t.Errorf("组件 = %q %q, 位置 = %q", v.Component, v.ComponentVersion, v.Location)
+	}
+	if !strings.HasSuffix(v.Description, "受影响的组件: lodash 4.17.15") {
+		t.Errorf("描述 = %q", v.Description)
+	}
+	// 修复建议合并 remediations 中的摘要
+	if v.Solution != "Upgrade to version 4.17.19 or above.\nUpgrade lodash to 4.17.21" {
+		t.Errorf("修复建议 = %q", v.Solution)
+	}
+	// 标识符和链接中重复的地址只保留一次
+	if strings.Count(v.References, "https://nvd.nist.gov/vuln/detail/CVE-2020-8203") != 1 {
+		t.Errorf("参考 = %q", v.References)
+	}
+	if v.Scanner != "Gemnasium 4.2.0 (GitLab)" {
+		t.Errorf("扫描器 = %q", v.Scanner)
+	}
+}
+
+func TestProcessGitlabContainerScanning(t *testing.T) {
+	vulns := processGitlabTestReport(t, testGitlabContainerScanning)
+	if len(vulns) != 1 {
+		t.Fatalf("漏洞数量 = %d, want 1", len(vulns))
+	}
+	v := vulns[0]
+	if v.Type != models.TypeVulnerableComponent || v.CVE != "CVE-2022-37434" ||
+		v.Image != "registry.example.com/shop/web:1.4.2" || v.OperatingSystem != "alpine 3.16.1" ||
+		v.Location != "registry.example.com/shop/web:1.4.2" || v.Component != "zlib" {
+		t.Errorf("漏洞 = %+v", v)
+	}
+
+	// 镜像升级标签后仍匹配到同一漏洞
+	upgraded := processGitlabTestReport(t, strings.Replace(testGitlabContainerScanning, "web:1.4.2", "web:1.5.0", 1))
+	if upgraded[0].Fingerprint != v.Fingerprint {
+		t.Error("镜像标签变化后指纹不应变化")
+	}
+}
+
+func TestProcessGitlabSecretDetection(t *testing.T) {
+	vulns := processGitlabTestReport(t, testGitlabSecretDetection)
+	if len(vulns) != 1 {
+		t.Fatalf("漏洞数量 = %d, want 1", len(vulns))
+	}
+	v := vulns[0]
+	if v.Type != models.TypeSecretLeak || v.Severity != models.SeverityCritical || v.Location != "config/settings.yml:12" {
+		t.Errorf("类型 = %s, 严重程度 = %s, 位置 = %q", v.Type, v.Severity, v.Location)
+	}
+	if v.StepsToReproduce != "Commit: 8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d9c" {
+		t.Errorf("复现步骤 = %q", v.StepsToReproduce)
+	}
+	// cve 字段中不是CVE编号的内容不作为CVE
+	if v.CVE != "" {
+		t.Errorf("CVE = %q", v.CVE)
+	}
+}
+
+func TestProcessGitlabDAST(t *testing.T) {
+	vulns := processGitlabTestReport(t, testGitlabDAST)
+	if len(vulns) != 2 {
+		t.Fatalf("漏洞数量 = %d, want 2", len(vulns))
+	}
+	xss := vulns[0]
+	if xss.Location != "GET https://staging.example.com/search (q)" || xss.Type != models.TypeXSS ||
+		xss.Severity != models.SeverityMedium || xss.Identifiers != "40012, CWE-79" {
+		t.Errorf("漏洞 = %q %s %s %q", xss.Location, xss.Type, xss.Severity, xss.Identifiers)
+	}
+	if xss.StepsToReproduce != "" {
+		t.Errorf("DAST 的HTTP方法不应作为函数名: %q", xss.StepsToReproduce)
+	}
+	// Unknown 按信息级别处理
+	if vulns[1].Severity != models.SeverityInfo || vulns[1].Location != "GET https://staging.example.com/" {
+		t.Errorf("漏洞 = %q %s", vulns[1].Location, vulns[1].Severity)
+	}
+	if xss.Fingerprint == vulns[1].Fingerprint {
+		t.Error("不同的发现不应共用指纹")
+	}
+}
+
+func TestProcessGitlabLegacyReport(t *testing.T) {
+	vulns := processGitlabTestReport(t, testGitlabLegacyReport)
+	if len(vulns) != 2 {
+		t.Fatalf("漏洞数量 = %d, want 2", len(vulns))
+	}
+	// 类别取漏洞上的 category，标题取 message，CVE取自 cve 字段
+	dep := vulns[0]
+	if dep.Title != "Regular Expression Denial of Service in minimatch" || dep.Type != models.TypeVulnerableComponent ||
+		dep.CVE != "CVE-2022-3517" || dep.Location != "yarn.lock" || dep.Scanner != "Gemnasium" {
+		t.Errorf("漏洞 = %q %s %q %q %q", dep.Title, dep.Type, dep.CVE, dep.Location, dep.Scanner)
+	}
+	// 没有类别时按位置推断为 SAST
+	if vulns[1].Location != "internal/hash.go:9" || vulns[1].Severity != models.SeverityLow {
+		t.Errorf("漏洞 = %q %s", vulns[1].Location, vulns[1].Severity)
+	}
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/scanner"
+)
+
+// cvePattern 匹配规则ID或标签中的CVE编号
+var cvePattern = regexp.MustCompile(`(?i)\bCVE-\d{4}-\d{4,}\b`)
+
+// 处理SARIF 2.1.0格式的扫描结果，一次上传可包含多个工具的运行结果
+//
+// level 和 security-severity 映射为严重程度，CWE标签映射为漏洞类型，
+// physicalLocation 映射为代码位置（文件:行号）。
+func processSarifResult(data []byte, integration models.CIIntegration) ([]models.Vulnerability, error) {
+	findings, err := scanner.ParseSARIF(data)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	vulnerabilities := make([]models.Vulnerability, 0, len(findings))
+	for i := range findings {
+		finding := &findings[i]
+
+		vulnType := getVulnerabilityTypeFromCategory(finding.RuleID)
+		for _, cwe := range finding.CWEs {
+			if t, ok := getVulnerabilityTypeFromCWE(cwe); ok {
+				vulnType = t
+				break
+			}
+		}
+
+		description := finding.Message
+		if finding.Description != "" && finding.Description != finding.Message {
+			description += "\n\n" + finding.Description
+		}
+
+		var details []string
+		if finding.Tool != "" {
+			details = append(details, "Tool: "+strings.TrimSpace(finding.Tool+" "+finding.ToolVersion))
+		}
+		if finding.RuleID != "" {
+			details = append(details, "Rule: "+finding.RuleID)
+		}
+		if len(finding.CWEs) > 0 {
+			details = append(details, "CWE: "+strings.Join(finding.CWEs, ", "))
+		}
+		if finding.Snippet != "" {
+			details = append(details, "Snippet:\n"+finding.Snippet)
+		}
+
+		cve := cvePattern.FindString(finding.RuleID)
+		if cve == "" {
+			cve = cvePattern.FindString(strings.Join(finding.Tags, " "))
+		}
+
+		// 工具提供的部分指纹不随代码行移动而变化，没有时按位置计算
+		position := finding.Fingerprint
+		if position == "" {
+			position = fmt.Sprintf("%s:%d", finding.URI, finding.StartLine)
+		}
+
+		vulnerabilities = append(vulnerabilities, models.Vulnerability{
+			Title:            truncateString(finding.Title, 255),
+			CVE:              strings.ToUpper(cve),
+			Fingerprint:      ciFingerprint(integration.ID, finding.Tool, finding.RuleID, finding.URI, position),
+			Description:      strings.TrimSpace(description),
+			Type:             vulnType,
+			Severity:         finding.Severity,
+			Status:           models.StatusNew,
+			CVSS:             finding.SecuritySeverity,
+			Location:         truncateString(finding.Location(), 500),
+			StepsToReproduce: strings.Join(details, "\n"),
+			Solution:         finding.Help,
+			References:       finding.HelpURI,
+			Source:           "sarif",
+			DiscoveredAt:     now,
+		})
+	}
+
+	return vulnerabilities, nil
+}
+
+// ciFingerprint 计算CI上报结果的指纹，同一集成重复上报的相同发现会匹配到同一个漏洞
+func ciFingerprint(integrationID uint, parts ...string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "ci:%d", integrationID)
+	for _, part := range parts {
+		h.Write([]byte{0})
+		h.Write([]byte(strings.ToLower(strings.TrimSpace(part))))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
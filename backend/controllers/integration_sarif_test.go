@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vulnark/vulnark/models"
+)
+
+// testSarifLog CodeQL 和 Semgrep 两次运行合并上传的SARIF日志
+const testSarifLog = `{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "CodeQL",
+          "semanticVersion": "2.15.3",
+          "rules": [
+            {
+              "id": "js/sql-injection",
+              "name": "js/sql-injection",
+              "shortDescription": {"text": "Database query built from user-controlled sources"},
+              "fullDescription": {"text": "Building a database query from user-controlled sources is vulnerable to insertion of malicious code by the user."},
+              "defaultConfiguration": {"enabled": true, "level": "error"},
+              "help": {"text": "Use parameterized queries.", "markdown": "# Database query built from user-controlled sources"},
+              "helpUri": "https://codeql.github.com/codeql-query-help/javascript/js-sql-injection/",
+              "properties": {
+                "tags": ["security", "external/cwe/cwe-089", "external/cwe/cwe-090"],
+                "precision": "high",
+                "security-severity": "8.8"
+              }
+            },
+            {
+              "id": "js/log-injection",
+              "shortDescription": {"text": "Log injection"},
+              "defaultConfiguration": {"level": "error"},
+              "properties": {"tags": ["security", "external/cwe/cwe-117"], "security-severity": "7.8"}
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "js/sql-injection",
+          "ruleIndex": 0,
+          "message": {"text": "This query string depends on a [user-provided value](1)."},
+          "locations": [{
+            "physicalLocation": {
+              "artifactLocation": {"uri": "src/routes/users.js", "uriBaseId": "%SRCROOT%", "index": 0},
+              "region": {"startLine": 42, "startColumn": 18, "endLine": 44, "endColumn": 3, "snippet": {"text": "db.query(\"SELECT * FROM users WHERE id = \" + req.params.id)"}}
+            }
+          }],
+          "partialFingerprints": {"primaryLocationLineHash": "5c9ef5ea4f1a1a8b:1", "primaryLocationStartColumnFingerprint": "13"}
+        },
+        {
+          "ruleId": "js/log-injection",
+          "ruleIndex": 1,
+          "level": "note",
+          "message": {"text": "Log entry depends on a user-provided value."},
+          "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/app.js"}, "region": {"startLine": 7}}}],
+          "properties": {"security-severity": "3.1"}
+        }
+      ]
+    },
+    {
+      "tool": {
+        "driver": {
+          "name": "Semgrep OSS",
+          "version": "1.45.0",
+          "rules": [
+            {
+              "id": "python.lang.security.audit.dangerous-subprocess-use",
+              "name": "python.lang.security.audit.dangerous-subprocess-use",
+              "shortDescription": {"text": "Semgrep Finding: python.lang.security.audit.dangerous-subprocess-use"},
+              "fullDescription": {"text": "Detected subprocess function with argument tainted by user input."},
+              "defaultConfiguration": {"level": "warning"},
+              "properties": {"tags": ["CWE-78: Improper Neutralization of Special Elements used in an OS Command", "OWASP-A03:2021 - Injection"]}
+            },
+            {
+              "id": "java.log4j.log4shell",
+              "shortDescription": {"text": "Log4j JNDI lookup"},
+              "defaultConfiguration": {"level": "error"},
+              "relationships": [{"target": {"id": "917", "toolComponent": {"name": "CWE"}}, "kinds": ["superset"]}]
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "python.lang.security.audit.dangerous-subprocess-use",
+          "message": {"text": "Detected subprocess function 'run' with user controlled data."},
+          "locations": [{"physicalLocation": {"artifactLocation": {"uri": "app/tasks.py"}, "region": {"startLine": 18, "endLine": 18}}}],
+          "fingerprints": {"matchBasedId/v1": "3fa5c7c1"}
+        },
+        {
+          "ruleId": "java.log4j.log4shell",
+          "message": {"text": "Message is logged with a JNDI lookup enabled."},
+          "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/main/java/App.java"}, "region": {"startLine": 21}}}],
+          "properties": {"tags": ["CVE-2021-44228"]}
+        }
+      ]
+    }
+  ]
+}`
+
+func TestProcessSarifResult(t *testing.T) {
+	vulns, err := processSarifResult([]byte(testSarifLog), models.CIIntegration{ID: 3})
+	if err != nil {
+		t.Fatalf("processSarifResult 返回错误: %v", err)
+	}
+	if len(vulns) != 4 {
+		t.Fatalf("漏洞数量 = %d, want 4", len(vulns))
+	}
+
+	cases := []struct {
+		title    string
+		severity models.Severity
+		vulnType models.VulnType
+		cvss     float64
+		cve      string
+		location string
+	}{
+		// 规则上的 security-severity 决定严重程度，CWE标签决定类型
+		{"Database query built from user-controlled sources", models.SeverityHigh, models.TypeSQLInjection, 8.8, "", "src/routes/users.js:42-44"},
+		// 结果上的 security-severity 优先于规则
+		{"Log injection", models.SeverityLow, models.TypeOther, 3.1, "", "src/app.js:7"},
+		// 没有 security-severity 时按 level 计算
+		{"Semgrep Finding: python.lang.security.audit.dangerous-subprocess-use", models.SeverityMedium, models.TypeCmdInjection, 0, "", "app/tasks.py:18"},
+		// CVE编号取自结果的标签
+		{"Log4j JNDI lookup", models.SeverityHigh, models.TypeOther, 0, "CVE-2021-44228", "src/main/java/App.java:21"},
+	}
+	for i, c := range cases {
+		v := vulns[i]
+		if v.Title != c.title || v.Severity != c.severity || v.Type != c.vulnType ||
+			v.CVSS != c.cvss || v.CVE != c.cve || v.Location != c.location {
+			t.Errorf("漏洞 %d = %q %s %s %v %q %q, want %q %s %s %v %q %q", i,
+				v.Title, v.Severity, v.Type, v.CVSS, v.CVE, v.Location,
+				c.title, c.severity, c.vulnType, c.cvss, c.cve, c.location)
+		}
+		if v.Source != "sarif" || v.Status != models.StatusNew || v.Fingerprint == "" {
+			t.Errorf("漏洞 %d: 来源 = %q, 状态 = %q, 指纹 = %q", i, v.Source, v.Status, v.Fingerprint)
+		}
+	}
+
+	sqli := vulns[0]
+	for _, want := range []string{"Tool: CodeQL 2.15.3", "Rule: js/sql-injection", "CWE: CWE-89, CWE-90", "Snippet:\ndb.query("} {
+		if !strings.Contains(sqli.StepsToReproduce, want) {
+			t.Errorf("复现步骤中缺少 %q: %q", want, sqli.StepsToReproduce)
+		}
+	}
+	if !strings.HasPrefix(sqli.Description, "This query string depends on") ||
+		!strings.Contains(sqli.Description, "vulnerable to insertion of malicious code") {
+		t.Errorf("描述应包含结果消息和规则说明: %q", sqli.Description)
+	}
+	if sqli.Solution != "Use parameterized queries." || !strings.HasPrefix(sqli.References, "https://codeql.github.com/") {
+		t.Errorf("修复建议 = %q, 参考 = %q", sqli.Solution, sqli.References)
+	}
+	if !strings.Contains(vulns[3].StepsToReproduce, "CWE: CWE-917") {
+		t.Errorf("规则关联的CWE应保留: %q", vulns[3].StepsToReproduce)
+	}
+}
+
+func TestProcessSarifResultFingerprint(t *testing.T) {
+	fingerprints := func(log string, integrationID uint) []string {
+		t.Helper()
+		vulns, err := processSarifResult([]byte(log), models.CIIntegration{ID: integrationID})
+		if err != nil {
+			t.Fatalf("processSarifResult 返回错误: %v", err)
+		}
+		var result []string
+		for _, v := range vulns {
+			result = append(result, v.Fingerprint)
+		}
+		return result
+	}
+
+	base := fingerprints(testSarifLog, 3)
+	// 代码上方插入了几行：有部分指纹的结果仍匹配到同一漏洞，按位置计算指纹的结果视为新发现
+	moved := strings.NewReplacer(`"startLine": 42`, `"startLine": 45`, `"startLine": 18`, `"startLine": 21`).Replace(testSarifLog)
+	shifted := fingerprints(moved, 3)
+	if base[0] != shifted[0] {
+		t.Error("partialFingerprints 不变时指纹不应随行号变化")
+	}
+	if base[2] == shifted[2] {
+		t.Error("没有部分指纹时指纹应包含行号")
+	}
+
+	other := fingerprints(testSarifLog, 4)
+	for i := range base {
+		if base[i] == other[i] {
+			t.Errorf("漏洞 %d: 不同集成上报的相同发现不应共用指纹", i)
+		}
+	}
+}
+
+func TestProcessSarifResultUnsupportedVersion(t *testing.T) {
+	if _, err := processSarifResult([]byte(`{"version":"1.0.0","runs":[]}`), models.CIIntegration{ID: 1}); err == nil {
+		t.Error("SARIF 1.0 日志应返回错误")
+	}
+	if _, err := processSarifResult([]byte(`{"runs":`), models.CIIntegration{ID: 1}); err == nil {
+		t.Error("无效的JSON应返回错误")
+	}
+}
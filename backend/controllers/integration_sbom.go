@@ -0,0 +1,255 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/sbom"
+	"github.com/vulnark/vulnark/utils"
+)
+
+// sbomUpload 上传SBOM时通过查询参数指定的资产和版本
+type sbomUpload struct {
+	AssetID string // 资产ID，为空时按SBOM描述的应用查找或创建应用程序资产
+	Version string // 资产版本，为空时使用SBOM中应用的版本
+}
+
+// 处理CycloneDX或SPDX格式的SBOM
+//
+// 组件清单按资产版本保存，同一版本重复上传时替换之前的清单；随后将组件与漏洞库中
+// 受影响产品和版本匹配的条目生成组件漏洞，并关联到该资产。
+func processSBOMResult(data []byte, integration models.CIIntegration, format string, upload sbomUpload) ([]models.Vulnerability, error) {
+	doc, err := sbom.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Format != format {
+		return nil, fmt.Errorf("集成类型为 %s，上传的SBOM格式为 %s", format, doc.Format)
+	}
+
+	asset, err := sbomAsset(doc, upload.AssetID)
+	if err != nil {
+		return nil, err
+	}
+	version := upload.Version
+	if version == "" {
+		version = doc.Subject.Version
+	}
+
+	if err := saveSBOM(doc, asset, version, integration.ID); err != nil {
+		return nil, fmt.Errorf("保存组件清单失败: %v", err)
+	}
+
+	matcher, err := newVulnDBMatcher()
+	if err != nil {
+		return nil, fmt.Errorf("加载漏洞库失败: %v", err)
+	}
+
+	now := time.Now()
+	var vulnerabilities []models.Vulnerability
+	for _, component := range doc.Components {
+		for _, entry := range matcher.match(component) {
+			var cwes []string
+			if entry.CWE != "" {
+				cwes = []string{"CWE-" + strings.TrimPrefix(strings.ToUpper(entry.CWE), "CWE-")}
+			}
+			vuln := componentVulnerability(componentFinding{
+				ID:               entry.CVE,
+				Title:            fmt.Sprintf("%s: %s", entry.Title, sbom.Describe(component)),
+				Description:      entry.Description,
+				Severity:         string(entry.Severity),
+				Package:          component.Name,
+				InstalledVersion: component.Version,
+				CWEs:             cwes,
+				CVSS:             entry.CVSS,
+				Location:         component.PURL,
+				References:       strings.Split(entry.References, "\n"),
+				Scanner:          "VulnArk SBOM",
+			}, nil)
+			// 漏洞库条目没有结构化的修复版本，使用条目中的修复建议
+			vuln.Solution = entry.Solution
+			vuln.Source = format
+			vuln.DiscoveredAt = now
+			vuln.Assets = []models.Asset{*asset}
+			vuln.Fingerprint = ciFingerprint(integration.ID, "sbom", strconv.FormatUint(uint64(asset.ID), 10), component.Key(), strconv.FormatUint(uint64(entry.ID), 10))
+			vulnerabilities = append(vulnerabilities, vuln)
+		}
+	}
+
+	log.Printf("已导入SBOM: asset_id=%d, version=%s, format=%s, components=%d, vulnerabilities=%d",
+		asset.ID, version, doc.Format, len(doc.Components), len(vulnerabilities))
+	return vulnerabilities, nil
+}
+
+// sbomAsset 查找SBOM所属的资产，未指定资产ID时按应用的purl或名称查找应用程序资产，不存在时自动创建
+func sbomAsset(doc *sbom.Document, assetID string) (*models.Asset, error) {
+	var asset models.Asset
+	if assetID != "" {
+		if err := utils.DB.First(&asset, assetID).Error; err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				return nil, fmt.Errorf("资产不存在: %s", assetID)
+			}
+			return nil, err
+		}
+		return &asset, nil
+	}
+
+	identifier := doc.Subject.Key()
+	if identifier == "" {
+		return nil, fmt.Errorf("SBOM中没有描述应用本身的组件，请通过 asset_id 参数指定资产")
+	}
+	err := utils.DB.Where("identifier = ?", identifier).First(&asset).Error
+	if err == nil {
+		return &asset, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+
+	asset = models.Asset{
+		Name:        truncateString(doc.Subject.Name, 255),
+		Type:        models.AssetTypeApplication,
+		Identifier:  truncateString(identifier, 255),
+		Status:      models.AssetStatusActive,
+		Description: "由CI上传的SBOM自动创建",
+		Version:     truncateString(doc.Subject.Version, 50),
+		Importance:  models.ImportanceMedium,
+	}
+	if err := utils.DB.Create(&asset).Error; err != nil {
+		return nil, fmt.Errorf("创建资产失败: %v", err)
+	}
+	log.Printf("已创建应用程序资产: id=%d, name=%s, identifier=%s", asset.ID, asset.Name, asset.Identifier)
+	return &asset, nil
+}
+
+// saveSBOM 保存资产某个版本的组件清单，替换该版本之前上传的清单
+func saveSBOM(doc *sbom.Document, asset *models.Asset, version string, integrationID uint) error {
+	tx := utils.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	var previous []uint
+	if err := tx.Model(&models.SBOM{}).Where("asset_id = ? AND asset_version = ?", asset.ID, version).Pluck("id", &previous).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(previous) > 0 {
+		if err := tx.Where("sbom_id IN (?)", previous).Delete(&models.SBOMComponent{}).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Where("id IN (?)", previous).Delete(&models.SBOM{}).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	record := models.SBOM{
+		AssetID:        asset.ID,
+		AssetVersion:   truncateString(version, 100),
+		IntegrationID:  integrationID,
+		Format:         doc.Format,
+		SpecVersion:    truncateString(doc.SpecVersion, 20),
+		Name:           truncateString(doc.Name, 255),
+		SerialNumber:   truncateString(doc.SerialNumber, 255),
+		ComponentCount: len(doc.Components),
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, c := range doc.Components {
+		component := models.SBOMComponent{
+			SBOMID:       record.ID,
+			AssetID:      asset.ID,
+			AssetVersion: record.AssetVersion,
+			Type:         truncateString(c.Type, 50),
+			Group:        truncateString(c.Group, 255),
+			Name:         truncateString(c.Name, 255),
+			Version:      truncateString(c.Version, 100),
+			PURL:         truncateString(c.PURL, 500),
+			Licenses:     strings.Join(c.Licenses, ", "),
+		}
+		if err := tx.Create(&component).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	// 资产版本记录为最近上传SBOM的版本
+	now := time.Now()
+	if err := tx.Model(asset).UpdateColumns(map[string]interface{}{
+		"version":   truncateString(version, 50),
+		"last_scan": now,
+	}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// vulnDBMatcher 按受影响产品索引的漏洞库条目
+//
+// 漏洞库条目的 AffectedSystems 为逗号分隔的产品，可以是组件名称、group/name 形式的名称或不带版本的purl；
+// AffectedVersions 为 sbom.InRange 支持的版本范围。没有版本范围或范围无法解析的条目不参与匹配，避免误报。
+type vulnDBMatcher struct {
+	products map[string][]models.VulnDB
+}
+
+func newVulnDBMatcher() (*vulnDBMatcher, error) {
+	var entries []models.VulnDB
+	err := utils.DB.Where("affected_systems <> '' AND affected_versions <> ''").Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	m := &vulnDBMatcher{products: make(map[string][]models.VulnDB)}
+	for _, entry := range entries {
+		for _, product := range strings.Split(entry.AffectedSystems, ",") {
+			product = strings.ToLower(strings.TrimSpace(product))
+			if strings.HasPrefix(product, "pkg:") {
+				product = sbom.PURLWithoutVersion(product)
+			}
+			if product != "" {
+				m.products[product] = append(m.products[product], entry)
+			}
+		}
+	}
+	return m, nil
+}
+
+// match 返回影响该组件版本的漏洞库条目
+func (m *vulnDBMatcher) match(c sbom.Component) []models.VulnDB {
+	if c.Version == "" || len(m.products) == 0 {
+		return nil
+	}
+
+	keys := []string{strings.ToLower(c.Name)}
+	if c.Group != "" {
+		keys = append(keys, strings.ToLower(c.Group+"/"+c.Name), strings.ToLower(c.Group+":"+c.Name))
+	}
+	if c.PURL != "" {
+		keys = append(keys, sbom.PURLWithoutVersion(c.PURL))
+	}
+
+	seen := make(map[uint]bool)
+	var matched []models.VulnDB
+	for _, key := range keys {
+		for _, entry := range m.products[key] {
+			if seen[entry.ID] {
+				continue
+			}
+			seen[entry.ID] = true
+			if affected, ok := sbom.InRange(c.Version, entry.AffectedVersions); ok && affected {
+				matched = append(matched, entry)
+			}
+		}
+	}
+	return matched
+}
@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/utils"
+)
+
+// SBOMController 软件物料清单和组件清单
+type SBOMController struct{}
+
+// ComponentUsage 使用某个组件的资产版本
+type ComponentUsage struct {
+	ID           uint      `json:"id"`
+	SBOMID       uint      `json:"sbomId"`
+	AssetID      uint      `json:"assetId"`
+	AssetName    string    `json:"assetName"`
+	AssetVersion string    `json:"assetVersion"`
+	Current      bool      `json:"current"` // 是否为资产当前版本的清单
+	Type         string    `json:"type"`
+	Group        string    `json:"group" gorm:"column:component_group"`
+	Name         string    `json:"name"`
+	Version      string    `json:"version"`
+	PURL         string    `json:"purl" gorm:"column:purl"`
+	Licenses     string    `json:"licenses"`
+	UploadedAt   time.Time `json:"uploadedAt"`
+}
+
+// SearchComponents 按组件查找使用它的资产版本，如 name=log4j-core&version=2.14
+//
+// version 为前缀匹配，2.14 匹配 2.14、2.14.0、2.14.1；purl 为不带版本的purl前缀匹配；
+// current=true 时只查找资产当前版本的清单。
+func (s *SBOMController) SearchComponents(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	name := c.Query("name")
+	version := c.Query("version")
+	purl := c.Query("purl")
+	license := c.Query("license")
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 20
+	}
+	if name == "" && purl == "" && license == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请指定组件名称、purl或许可证",
+		})
+		return
+	}
+
+	query := utils.DB.Table("sbom_components").
+		Joins("JOIN sboms ON sboms.id = sbom_components.sbom_id").
+		Joins("JOIN assets ON assets.id = sbom_components.asset_id AND assets.deleted_at IS NULL")
+	if name != "" {
+		query = query.Where("sbom_components.name LIKE ?", "%"+name+"%")
+	}
+	if version != "" {
+		query = query.Where("sbom_components.version = ? OR sbom_components.version LIKE ? OR sbom_components.version LIKE ?",
+			version, version+".%", version+"-%")
+	}
+	if purl != "" {
+		query = query.Where("sbom_components.purl LIKE ?", purl+"%")
+	}
+	if license != "" {
+		query = query.Where("sbom_components.licenses LIKE ?", "%"+license+"%")
+	}
+	if assetID := c.Query("asset_id"); assetID != "" {
+		query = query.Where("sbom_components.asset_id = ?", assetID)
+	}
+	if c.Query("current") == "true" {
+		query = query.Where("sbom_components.asset_version = assets.version")
+	}
+
+	var total int
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "查询组件失败: " + err.Error(),
+		})
+		return
+	}
+
+	var items []ComponentUsage
+	err := query.Select("sbom_components.id, sbom_components.sbom_id, sbom_components.asset_id, assets.name AS asset_name, " +
+		"sbom_components.asset_version, sbom_components.asset_version = assets.version AS current, " +
+		"sbom_components.type, sbom_components.component_group, sbom_components.name, sbom_components.version, " +
+		"sbom_components.purl, sbom_components.licenses, sboms.created_at AS uploaded_at").
+		Order("assets.name ASC, sbom_components.asset_version DESC, sbom_components.name ASC").
+		Limit(pageSize).Offset((page - 1) * pageSize).
+		Scan(&items).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "查询组件失败: " + err.Error(),
+		})
+		return
+	}
+	if items == nil {
+		items = []ComponentUsage{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data": gin.H{
+			"items": items,
+			"total": total,
+		},
+	})
+}
+
+// ListAssetSBOMs 获取资产各版本上传的SBOM
+func (s *SBOMController) ListAssetSBOMs(c *gin.Context) {
+	var asset models.Asset
+	if err := utils.DB.Select("id").First(&asset, c.Param("id")).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "资产不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取资产失败: " + err.Error(),
+		})
+		return
+	}
+
+	var items []models.SBOM
+	if err := utils.DB.Where("asset_id = ?", asset.ID).Order("created_at DESC").Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取SBOM失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data":    items,
+	})
+}
+
+// ListSBOMComponents 获取SBOM中的组件
+func (s *SBOMController) ListSBOMComponents(c *gin.Context) {
+	var record models.SBOM
+	if err := utils.DB.First(&record, c.Param("id")).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "SBOM不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取SBOM失败: " + err.Error(),
+		})
+		return
+	}
+
+	var components []models.SBOMComponent
+	if err := utils.DB.Where("sbom_id = ?", record.ID).Order("name ASC, version ASC").Find(&components).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取组件失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data": gin.H{
+			"sbom":       record,
+			"components": components,
+		},
+	})
+}
@@ -0,0 +1,851 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/viper"
+
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/scanner"
+	"github.com/vulnark/vulnark/utils"
+)
+
+const (
+	agentPollCheckInterval = time.Second // 长轮询期间查询排队任务的间隔
+	agentMaxPollWait       = 60          // 长轮询最长等待时间上限（秒）
+	agentMaxEventBatch     = 500         // 单次上报的最大事件数
+	agentMaxResultBatch    = 1000        // 单次上传的最大结果数
+)
+
+// ScanAgentController 扫描代理控制器
+//
+// 管理员通过 /admin/scan-agents 创建代理并获取代理令牌，代理使用令牌访问 /agent 下的接口，
+// 领取指定了网络区域（agent_zone）的扫描任务并在本地执行，接口数据见 scanner.AgentJob 等类型。
+type ScanAgentController struct{}
+
+// ScanAgentRequest 创建/更新扫描代理的请求
+type ScanAgentRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Zone        string `json:"zone" binding:"required"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+// apply 将请求内容写入扫描代理
+func (r *ScanAgentRequest) apply(agent *models.ScanAgent) error {
+	zone := strings.TrimSpace(r.Zone)
+	if zone == "" {
+		return errors.New("网络区域不能为空")
+	}
+	agent.Name = strings.TrimSpace(r.Name)
+	agent.Description = r.Description
+	agent.Zone = zone
+	if r.Enabled != nil {
+		agent.Enabled = *r.Enabled
+	}
+	return nil
+}
+
+// ListScanAgents 获取扫描代理列表及其在线状态，可通过 zone 过滤
+func (c *ScanAgentController) ListScanAgents(ctx *gin.Context) {
+	var agents []models.ScanAgent
+	query := utils.DB.Order("zone ASC, name ASC")
+	if zone := ctx.Query("zone"); zone != "" {
+		query = query.Where("zone = ?", zone)
+	}
+	if err := query.Find(&agents).Error; err != nil {
+		log.Printf("获取扫描代理列表失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取扫描代理列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	now, timeout := time.Now(), agentOfflineTimeout()
+	for i := range agents {
+		agents[i].ComputeStatus(now, timeout)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取扫描代理列表成功",
+		"data":    agents,
+	})
+}
+
+// GetScanAgent 获取扫描代理详情，包括正在执行的扫描
+func (c *ScanAgentController) GetScanAgent(ctx *gin.Context) {
+	var agent models.ScanAgent
+	if err := utils.DB.First(&agent, ctx.Param("id")).Error; err != nil {
+		respondScanAgentNotFound(ctx, err)
+		return
+	}
+	agent.ComputeStatus(time.Now(), agentOfflineTimeout())
+
+	var runs []models.ScanRun
+	utils.DB.Where("agent_id = ? AND status = ?", agent.ID, models.ScanTaskStatusRunning).
+		Order("id ASC").Find(&runs)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取扫描代理成功",
+		"data": gin.H{
+			"agent":        agent,
+			"running_runs": runs,
+		},
+	})
+}
+
+// CreateScanAgent 创建扫描代理，代理令牌只在响应中返回一次
+func (c *ScanAgentController) CreateScanAgent(ctx *gin.Context) {
+	var req ScanAgentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	agent := models.ScanAgent{Enabled: true, CreatedBy: ctx.GetUint("user_id")}
+	if err := req.apply(&agent); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	token, err := models.GenerateAgentToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "生成扫描代理令牌失败: " + err.Error(),
+		})
+		return
+	}
+	agent.SetToken(token)
+
+	if err := utils.DB.Create(&agent).Error; err != nil {
+		log.Printf("创建扫描代理失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "创建扫描代理失败: " + err.Error(),
+		})
+		return
+	}
+
+	agent.ComputeStatus(time.Now(), agentOfflineTimeout())
+	ctx.JSON(http.StatusCreated, gin.H{
+		"code":    200,
+		"message": "扫描代理创建成功，请妥善保存代理令牌，令牌不会再次显示",
+		"data": gin.H{
+			"agent": agent,
+			"token": token,
+		},
+	})
+}
+
+// UpdateScanAgent 更新扫描代理，修改网络区域后代理只领取新区域的任务
+func (c *ScanAgentController) UpdateScanAgent(ctx *gin.Context) {
+	var agent models.ScanAgent
+	if err := utils.DB.First(&agent, ctx.Param("id")).Error; err != nil {
+		respondScanAgentNotFound(ctx, err)
+		return
+	}
+
+	var req ScanAgentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if err := req.apply(&agent); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := utils.DB.Save(&agent).Error; err != nil {
+		log.Printf("更新扫描代理失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "更新扫描代理失败: " + err.Error(),
+		})
+		return
+	}
+
+	agent.ComputeStatus(time.Now(), agentOfflineTimeout())
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "扫描代理更新成功",
+		"data":    agent,
+	})
+}
+
+// DeleteScanAgent 删除扫描代理，其正在执行的扫描由工作池标记为失败
+func (c *ScanAgentController) DeleteScanAgent(ctx *gin.Context) {
+	var agent models.ScanAgent
+	if err := utils.DB.First(&agent, ctx.Param("id")).Error; err != nil {
+		respondScanAgentNotFound(ctx, err)
+		return
+	}
+
+	if err := utils.DB.Delete(&agent).Error; err != nil {
+		log.Printf("删除扫描代理失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除扫描代理失败: " + err.Error(),
+		})
+		return
+	}
+	notifyScanPool()
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "扫描代理删除成功",
+	})
+}
+
+// RegenerateScanAgentToken 重置扫描代理令牌，原令牌立即失效
+func (c *ScanAgentController) RegenerateScanAgentToken(ctx *gin.Context) {
+	var agent models.ScanAgent
+	if err := utils.DB.First(&agent, ctx.Param("id")).Error; err != nil {
+		respondScanAgentNotFound(ctx, err)
+		return
+	}
+
+	token, err := models.GenerateAgentToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "生成扫描代理令牌失败: " + err.Error(),
+		})
+		return
+	}
+	agent.SetToken(token)
+
+	err = utils.DB.Model(&models.ScanAgent{}).Where("id = ?", agent.ID).Updates(map[string]interface{}{
+		"token_hash":   agent.TokenHash,
+		"token_prefix": agent.TokenPrefix,
+	}).Error
+	if err != nil {
+		log.Printf("重置扫描代理令牌失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "重置扫描代理令牌失败: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "扫描代理令牌已重置，请妥善保存代理令牌，令牌不会再次显示",
+		"data": gin.H{
+			"token":        token,
+			"token_prefix": agent.TokenPrefix,
+		},
+	})
+}
+
+// RegisterAgent 扫描代理启动时注册，上报主机信息并获取运行参数
+func (c *ScanAgentController) RegisterAgent(ctx *gin.Context) {
+	agent := ctx.MustGet("agent").(*models.ScanAgent)
+
+	var req scanner.AgentRegistration
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	now := time.Now()
+	types := make([]string, 0, len(req.ScannerTypes))
+	for _, t := range req.ScannerTypes {
+		types = append(types, string(t))
+	}
+	err := utils.DB.Model(&models.ScanAgent{}).Where("id = ?", agent.ID).Updates(map[string]interface{}{
+		"hostname":      req.Hostname,
+		"version":       req.Version,
+		"scanner_types": strings.Join(types, ","),
+		"running_jobs":  len(req.RunningRuns),
+		"registered_at": now,
+	}).Error
+	if err != nil {
+		log.Printf("扫描代理注册失败: agent_id=%d, err=%v", agent.ID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "扫描代理注册失败: " + err.Error(),
+		})
+		return
+	}
+
+	// 代理重启后不会继续执行之前领取的扫描
+	failOrphanedAgentRuns(agent, req.RunningRuns, now)
+
+	log.Printf("扫描代理已注册: agent_id=%d, name=%s, zone=%s, hostname=%s, ip=%s, types=%v",
+		agent.ID, agent.Name, agent.Zone, req.Hostname, agent.IPAddress, types)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "扫描代理注册成功",
+		"data": scanner.AgentConfig{
+			AgentID:           agent.ID,
+			Name:              agent.Name,
+			Zone:              agent.Zone,
+			HeartbeatInterval: int(agentHeartbeatInterval() / time.Second),
+			PollWait:          int(agentPollWait() / time.Second),
+			PollInterval:      int(scanPollInterval() / time.Second),
+			ResultBatchSize:   agentResultBatchSize(),
+		},
+	})
+}
+
+// AgentHeartbeat 扫描代理心跳，返回需要取消的扫描
+func (c *ScanAgentController) AgentHeartbeat(ctx *gin.Context) {
+	agent := ctx.MustGet("agent").(*models.ScanAgent)
+
+	var req scanner.AgentRegistration
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	utils.DB.Model(&models.ScanAgent{}).Where("id = ?", agent.ID).UpdateColumn("running_jobs", len(req.RunningRuns))
+
+	// 领取后超过一个心跳周期仍未在代理上运行的扫描视为已丢失
+	failOrphanedAgentRuns(agent, req.RunningRuns, time.Now().Add(-2*agentHeartbeatInterval()))
+
+	resp := scanner.AgentHeartbeatResponse{Cancel: []scanner.AgentCancel{}}
+	if len(req.RunningRuns) > 0 {
+		var runs []models.ScanRun
+		utils.DB.Select("id, keep_partial").
+			Where("agent_id = ? AND id IN (?) AND cancel_requested = ?", agent.ID, req.RunningRuns, true).
+			Find(&runs)
+		for _, run := range runs {
+			resp.Cancel = append(resp.Cancel, scanner.AgentCancel{RunID: run.ID, KeepPartial: run.KeepPartial})
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "ok",
+		"data":    resp,
+	})
+}
+
+// PollAgentJob 长轮询领取本网络区域中代理支持的扫描作业，等待期间没有作业时返回204
+func (c *ScanAgentController) PollAgentJob(ctx *gin.Context) {
+	agent := ctx.MustGet("agent").(*models.ScanAgent)
+
+	wait := agentPollWait()
+	if seconds, err := strconv.Atoi(ctx.Query("wait")); err == nil && seconds >= 0 {
+		if seconds > agentMaxPollWait {
+			seconds = agentMaxPollWait
+		}
+		wait = time.Duration(seconds) * time.Second
+	}
+	deadline := time.Now().Add(wait)
+
+	for {
+		if job := claimAgentJob(agent); job != nil {
+			ctx.JSON(http.StatusOK, gin.H{
+				"code":    200,
+				"message": "领取扫描作业成功",
+				"data":    job,
+			})
+			return
+		}
+		if !time.Now().Before(deadline) {
+			ctx.Status(http.StatusNoContent)
+			return
+		}
+
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-time.After(agentPollCheckInterval):
+		}
+	}
+}
+
+// ReportAgentEvents 扫描代理上报扫描日志和进度
+func (c *ScanAgentController) ReportAgentEvents(ctx *gin.Context) {
+	agent := ctx.MustGet("agent").(*models.ScanAgent)
+	run, ok := loadAgentRun(ctx, agent)
+	if !ok {
+		return
+	}
+
+	var req scanner.AgentEventBatch
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if len(req.Events) > agentMaxEventBatch {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": fmt.Sprintf("单次最多上报%d条事件", agentMaxEventBatch),
+		})
+		return
+	}
+
+	progress := -1
+	for _, event := range req.Events {
+		switch event.Type {
+		case models.ScanEventLog:
+			log.Printf("[扫描任务 %d][代理 %s] %s", run.ScanTaskID, agent.Name, event.Message)
+		case models.ScanEventProgress:
+			progress = event.Progress
+		default:
+			continue
+		}
+		recordScanEvent(run.ScanTaskID, run.ID, event.Type, event.Progress, event.Message)
+	}
+	if progress >= 0 {
+		utils.DB.Model(&models.ScanTask{}).Where("id = ?", run.ScanTaskID).UpdateColumn("progress", progress)
+	}
+
+	resp := scanner.AgentEventResponse{}
+	if run.CancelRequested {
+		resp.Cancel = &scanner.AgentCancel{RunID: run.ID, KeepPartial: run.KeepPartial}
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "ok",
+		"data":    resp,
+	})
+}
+
+// UploadAgentResults 扫描代理分批上传扫描结果，结果在扫描结束时统一统计、复测和导入
+func (c *ScanAgentController) UploadAgentResults(ctx *gin.Context) {
+	agent := ctx.MustGet("agent").(*models.ScanAgent)
+	run, ok := loadAgentRun(ctx, agent)
+	if !ok {
+		return
+	}
+
+	var req scanner.AgentResultBatch
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if len(req.Results) > agentMaxResultBatch {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": fmt.Sprintf("单次最多上传%d条结果", agentMaxResultBatch),
+		})
+		return
+	}
+
+	task := &models.ScanTask{ID: run.ScanTaskID}
+	saved := saveScanResults(task, run, req.Results)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "扫描结果上传成功",
+		"data": gin.H{
+			"saved": len(saved),
+		},
+	})
+}
+
+// CompleteAgentJob 扫描代理上报扫描结束，按已上传的结果完成本次执行
+func (c *ScanAgentController) CompleteAgentJob(ctx *gin.Context) {
+	agent := ctx.MustGet("agent").(*models.ScanAgent)
+	run, ok := loadAgentRun(ctx, agent)
+	if !ok {
+		return
+	}
+
+	var req scanner.AgentCompletion
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+	switch req.State {
+	case scanner.StateCompleted, scanner.StateFailed, scanner.StateCancelled:
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的扫描结束状态: " + string(req.State),
+		})
+		return
+	}
+
+	var task models.ScanTask
+	if err := utils.DB.First(&task, run.ScanTaskID).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取扫描任务失败: " + err.Error(),
+		})
+		return
+	}
+	if req.Progress > 0 {
+		task.Progress = req.Progress
+	}
+	task.ScanLog = req.ScanLog
+	if len(task.ScanLog) > maxScanLogSize {
+		task.ScanLog = strings.ToValidUTF8(task.ScanLog[:maxScanLogSize], "") + "\n...（日志过长，已截断）\n"
+	}
+
+	cancelled := req.State == scanner.StateCancelled || run.CancelRequested
+	switch {
+	case req.State == scanner.StateFailed && !cancelled:
+		discardRunResults(run.ID)
+		message := req.Error
+		if message == "" {
+			message = "扫描代理报告扫描失败"
+		}
+		reason := req.FailureReason
+		if reason == "" {
+			reason = models.ScanFailureUnknown
+		}
+		failScanRun(&task, run, scanner.WithReason(reason, fmt.Errorf("[代理 %s] %s", agent.Name, message)))
+	default:
+		if cancelled && !run.KeepPartial {
+			discardRunResults(run.ID)
+		}
+		var saved []models.ScanResult
+		utils.DB.Where("scan_run_id = ?", run.ID).Order("id ASC").Find(&saved)
+
+		// 复测和导入按领取作业时代理实际扫描的目标关联资产
+		targets := agentRunTargets(&task, run)
+		finishScanRun(&task, run, targets, saved, cancelled)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "扫描结束状态已记录",
+		"data": gin.H{
+			"status": task.Status,
+		},
+	})
+}
+
+// claimAgentJob 为代理认领一个本网络区域的排队任务并生成扫描作业，没有可执行的任务时返回 nil
+func claimAgentJob(agent *models.ScanAgent) *scanner.AgentJob {
+	var fresh models.ScanAgent
+	if err := utils.DB.First(&fresh, agent.ID).Error; err != nil || !fresh.Enabled {
+		return nil
+	}
+
+	order := "queued_at ASC, id ASC"
+	if scanPool != nil {
+		order = scanPool.orderClause()
+	}
+
+	now := time.Now()
+	var tasks []models.ScanTask
+	err := readyScanTasks(now).
+		Where("agent_zone = ?", fresh.Zone).
+		Select("id, type, queue_trigger").
+		Order(order).
+		Find(&tasks).Error
+	if err != nil {
+		log.Printf("查询排队扫描任务失败: %v", err)
+		return nil
+	}
+
+	for _, candidate := range tasks {
+		if !fresh.SupportsType(candidate.Type) || holdForScanWindows(candidate.ID, now) {
+			continue
+		}
+
+		trigger := candidate.QueueTrigger
+		if trigger == "" {
+			trigger = models.ScanTriggerManual
+		}
+		task, run, ok := claimScanTask(candidate.ID, trigger, fresh.ID)
+		if !ok {
+			continue
+		}
+
+		job, err := prepareAgentJob(task, run)
+		if err != nil {
+			failScanRun(task, run, err)
+			continue
+		}
+		return &scanner.AgentJob{
+			TaskID:  task.ID,
+			RunID:   run.ID,
+			Trigger: trigger,
+			Job:     job,
+		}
+	}
+	return nil
+}
+
+// prepareAgentJob 解析扫描目标并生成交给代理执行的扫描作业
+func prepareAgentJob(task *models.ScanTask, run *models.ScanRun) (*scanner.Job, error) {
+	targets, err := resolveScanTargets(task)
+	if err != nil {
+		return nil, scanner.WithReason(models.ScanFailureConfig, fmt.Errorf("解析扫描目标失败: %v", err))
+	}
+	applyScanWindows(task, targets)
+	job, err := prepareScanJob(task, targets)
+	if err != nil {
+		return nil, scanner.WithReason(models.ScanFailureConfig, err)
+	}
+
+	// 保存本次下发的目标，完成时不再重新解析，避免资产或扫描窗口变化导致复测和导入的范围与实际扫描不一致
+	data, err := json.Marshal(scanTargets{IPs: targets.IPs, URLs: targets.URLs, AssetIDs: targets.AssetIDs})
+	if err != nil {
+		return nil, err
+	}
+	run.Targets = string(data)
+	if err := utils.DB.Model(run).UpdateColumn("targets", run.Targets).Error; err != nil {
+		return nil, fmt.Errorf("保存扫描目标失败: %v", err)
+	}
+
+	recordScanEvent(task.ID, run.ID, models.ScanEventLog, 0, fmt.Sprintf("扫描目标: IP/主机 %d 个，URL %d 个，资产 %d 个，排除 %d 个",
+		len(targets.IPs), len(targets.URLs), len(targets.AssetIDs), len(targets.Excluded)))
+	for _, warning := range targets.Warnings {
+		recordScanEvent(task.ID, run.ID, models.ScanEventLog, 0, warning)
+	}
+	return job, nil
+}
+
+// agentRunTargets 返回领取作业时保存的扫描目标，升级前领取的作业没有保存目标时按当前配置重新解析
+func agentRunTargets(task *models.ScanTask, run *models.ScanRun) *scanTargets {
+	if run.Targets != "" {
+		var targets scanTargets
+		err := json.Unmarshal([]byte(run.Targets), &targets)
+		if err == nil {
+			return &targets
+		}
+		log.Printf("解析保存的扫描目标失败: run_id=%d, err=%v", run.ID, err)
+	}
+
+	targets, err := resolveScanTargets(task)
+	if err != nil {
+		log.Printf("解析扫描目标失败: task_id=%d, err=%v", task.ID, err)
+		return &scanTargets{}
+	}
+	// 暂缓的资产已在领取作业时拆分，这里只去掉它们
+	filterScanWindows(task, targets, time.Now())
+	return targets
+}
+
+// loadAgentRun 获取代理正在执行的执行记录，不存在或不属于该代理时返回错误响应
+func loadAgentRun(ctx *gin.Context, agent *models.ScanAgent) (*models.ScanRun, bool) {
+	var run models.ScanRun
+	err := utils.DB.Where("id = ? AND agent_id = ?", ctx.Param("run_id"), agent.ID).First(&run).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "扫描作业不存在",
+			})
+			return nil, false
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取扫描作业失败: " + err.Error(),
+		})
+		return nil, false
+	}
+
+	// 已结束（如被标记为失联）的执行不再接受上报，代理收到409后应放弃该扫描
+	if run.Status != models.ScanTaskStatusRunning {
+		ctx.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": "扫描作业已结束: " + string(run.Status),
+		})
+		return nil, false
+	}
+	return &run, true
+}
+
+// discardRunResults 删除执行记录已上传的扫描结果
+func discardRunResults(runID uint) {
+	deleteResultEvidence(utils.DB.Unscoped().Model(&models.ScanResult{}).Where("scan_run_id = ?", runID))
+	if err := utils.DB.Where("scan_run_id = ?", runID).Delete(&models.ScanResult{}).Error; err != nil {
+		log.Printf("删除扫描结果失败: run_id=%d, err=%v", runID, err)
+	}
+}
+
+// requestAgentCancel 为扫描代理正在执行的任务记录取消请求，任务不由代理执行时返回 false
+func requestAgentCancel(task *models.ScanTask, keepPartial bool) bool {
+	if task.AgentID == 0 {
+		return false
+	}
+	update := utils.DB.Model(&models.ScanRun{}).
+		Where("scan_task_id = ? AND agent_id = ? AND status = ?", task.ID, task.AgentID, models.ScanTaskStatusRunning).
+		Updates(map[string]interface{}{
+			"cancel_requested": true,
+			"keep_partial":     keepPartial,
+		})
+	if update.Error != nil {
+		log.Printf("记录扫描代理取消请求失败: task_id=%d, err=%v", task.ID, update.Error)
+		return false
+	}
+	return update.RowsAffected > 0
+}
+
+// failOrphanedAgentRuns 将代理未在执行且在 before 之前领取的扫描标记为失败
+func failOrphanedAgentRuns(agent *models.ScanAgent, running []uint, before time.Time) {
+	query := utils.DB.Where("agent_id = ? AND status = ? AND started_at < ?", agent.ID, models.ScanTaskStatusRunning, before)
+	if len(running) > 0 {
+		query = query.Where("id NOT IN (?)", running)
+	}
+
+	var runs []models.ScanRun
+	if err := query.Find(&runs).Error; err != nil {
+		log.Printf("查询扫描代理执行记录失败: agent_id=%d, err=%v", agent.ID, err)
+		return
+	}
+	for i := range runs {
+		failAgentRun(&runs[i], fmt.Errorf("扫描代理 %s 已不再执行该扫描", agent.Name))
+	}
+}
+
+// reapStaleAgentRuns 将失联、停用或已删除的扫描代理正在执行的扫描标记为失败
+func reapStaleAgentRuns() {
+	var runs []models.ScanRun
+	err := utils.DB.Where("agent_id <> 0 AND status = ?", models.ScanTaskStatusRunning).Find(&runs).Error
+	if err != nil {
+		log.Printf("查询扫描代理执行记录失败: %v", err)
+		return
+	}
+
+	now, timeout := time.Now(), agentOfflineTimeout()
+	agents := make(map[uint]*models.ScanAgent)
+	for i := range runs {
+		run := &runs[i]
+		agent, ok := agents[run.AgentID]
+		if !ok {
+			agent = &models.ScanAgent{}
+			if err := utils.DB.First(agent, run.AgentID).Error; err != nil {
+				agent = nil
+			}
+			agents[run.AgentID] = agent
+		}
+
+		switch {
+		case agent == nil:
+			failAgentRun(run, errors.New("扫描代理已删除"))
+		case agent.ComputeStatus(now, timeout) == models.ScanAgentStatusDisabled:
+			failAgentRun(run, fmt.Errorf("扫描代理 %s 已停用", agent.Name))
+		case agent.Status == models.ScanAgentStatusOffline:
+			failAgentRun(run, fmt.Errorf("扫描代理 %s 失联（最近心跳 %s）", agent.Name, agent.LastSeenAt.Format("2006-01-02 15:04:05")))
+		}
+	}
+}
+
+// failAgentRun 将代理执行记录及其任务标记为失败，已上传的结果不再保留
+func failAgentRun(run *models.ScanRun, err error) {
+	discardRunResults(run.ID)
+	err = scanner.WithReason(models.ScanFailureAgent, err)
+
+	var task models.ScanTask
+	if e := utils.DB.First(&task, run.ScanTaskID).Error; e != nil || task.Status != models.ScanTaskStatusRunning || task.AgentID != run.AgentID {
+		// 任务已重新执行或已删除，只结束这次执行记录
+		completed := time.Now()
+		utils.DB.Model(run).Updates(map[string]interface{}{
+			"status":         models.ScanTaskStatusFailed,
+			"completed_at":   &completed,
+			"result_summary": "扫描失败: " + err.Error(),
+			"failure_reason": models.ScanFailureAgent,
+		})
+		return
+	}
+	failScanRun(&task, run, err)
+}
+
+// checkAgentZone 检查网络区域中是否有可用的扫描代理
+func checkAgentZone(zone string) error {
+	if zone == "" {
+		return nil
+	}
+	var count int
+	if err := utils.DB.Model(&models.ScanAgent{}).Where("zone = ? AND enabled = ?", zone, true).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("网络区域 %s 中没有可用的扫描代理", zone)
+	}
+	return nil
+}
+
+// agentHeartbeatInterval 扫描代理心跳间隔
+func agentHeartbeatInterval() time.Duration {
+	interval := time.Duration(viper.GetInt("scan.agent.heartbeat_interval")) * time.Second
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return interval
+}
+
+// agentOfflineTimeout 扫描代理超过该时间未访问服务端视为离线
+func agentOfflineTimeout() time.Duration {
+	timeout := time.Duration(viper.GetInt("scan.agent.offline_timeout")) * time.Second
+	if timeout <= 0 {
+		timeout = 4 * agentHeartbeatInterval()
+	}
+	return timeout
+}
+
+// agentPollWait 长轮询领取扫描作业的默认等待时间
+func agentPollWait() time.Duration {
+	wait := viper.GetInt("scan.agent.poll_wait")
+	if wait <= 0 {
+		wait = 30
+	}
+	if wait > agentMaxPollWait {
+		wait = agentMaxPollWait
+	}
+	return time.Duration(wait) * time.Second
+}
+
+// agentResultBatchSize 扫描代理每批上传的结果数
+func agentResultBatchSize() int {
+	size := viper.GetInt("scan.agent.result_batch_size")
+	if size <= 0 || size > agentMaxResultBatch {
+		size = 200
+	}
+	return size
+}
+
+// respondScanAgentNotFound 返回查询扫描代理失败的响应
+func respondScanAgentNotFound(ctx *gin.Context, err error) {
+	if gorm.IsRecordNotFoundError(err) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "扫描代理不存在",
+		})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{
+		"code":    500,
+		"message": "获取扫描代理失败: " + err.Error(),
+	})
+}
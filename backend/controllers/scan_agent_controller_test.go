@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/utils"
+)
+
+// useTestDB 将 utils.DB 替换为内存SQLite数据库，并为指定模型建表
+func useTestDB(t *testing.T, values ...interface{}) {
+	t.Helper()
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	// 内存数据库只对单个连接可见
+	db.DB().SetMaxOpenConns(1)
+	if err := db.AutoMigrate(values...).Error; err != nil {
+		t.Fatalf("创建测试表失败: %v", err)
+	}
+
+	previous := utils.DB
+	utils.DB = db
+	t.Cleanup(func() {
+		utils.DB = previous
+		db.Close()
+	})
+}
+
+// performJSON 以JSON请求体调用处理函数，返回响应
+func performJSON(handler gin.HandlerFunc, method, path string, body interface{}, params ...gin.Param) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	raw, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(method, path, bytes.NewReader(raw))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	ctx.Params = params
+	handler(ctx)
+	return w
+}
+
+func TestCreateScanAgentDisabled(t *testing.T) {
+	useTestDB(t, &models.ScanAgent{})
+	controller := &ScanAgentController{}
+
+	for _, enabled := range []bool{false, true} {
+		w := performJSON(controller.CreateScanAgent, http.MethodPost, "/api/v1/admin/scan-agents", gin.H{
+			"name": "agent", "zone": "dmz", "enabled": enabled,
+		})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("enabled=%v: 状态码 = %d, body = %s", enabled, w.Code, w.Body.String())
+		}
+
+		var resp struct {
+			Data struct {
+				Agent models.ScanAgent `json:"agent"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+		var stored models.ScanAgent
+		if err := utils.DB.First(&stored, resp.Data.Agent.ID).Error; err != nil {
+			t.Fatalf("读取扫描代理失败: %v", err)
+		}
+		if resp.Data.Agent.Enabled != enabled || stored.Enabled != enabled {
+			t.Errorf("enabled=%v: 响应中为 %v，数据库中为 %v", enabled, resp.Data.Agent.Enabled, stored.Enabled)
+		}
+	}
+}
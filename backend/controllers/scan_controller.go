@@ -1,17 +1,23 @@
 package controllers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/viper"
 
 	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/scanner"
 	"github.com/vulnark/vulnark/utils"
 )
 
@@ -95,6 +101,11 @@ func (c *ScanController) GetScanTask(ctx *gin.Context) {
 		return
 	}
 
+	// 计算排队位置
+	if scanPool != nil {
+		task.QueuePosition = scanPool.Position(&task)
+	}
+
 	// 隐藏敏感信息
 	task.ScannerAPIKey = ""
 	task.ScannerPassword = ""
@@ -111,17 +122,31 @@ type ScanTaskRequest struct {
 	Name        string `json:"name" binding:"required"`
 	Description string `json:"description"`
 	Type        string `json:"type" binding:"required"`
+	Priority    int    `json:"priority"`
+
+	ScannerURL       string `json:"scanner_url"`
+	ScannerAPIKey    string `json:"scanner_api_key"`
+	ScannerUsername  string `json:"scanner_username"`
+	ScannerPassword  string `json:"scanner_password"`
+	CustomScannerID  uint   `json:"custom_scanner_id"`
+	ScannerProfileID uint   `json:"scanner_profile_id"`
+	AgentZone        string `json:"agent_zone"`
+	WindowPolicy     string `json:"window_policy"`
 
-	ScannerURL      string `json:"scanner_url"`
-	ScannerAPIKey   string `json:"scanner_api_key"`
-	ScannerUsername string `json:"scanner_username"`
-	ScannerPassword string `json:"scanner_password"`
+	MaxRetries   *int `json:"max_retries"`   // 最多自动重试次数，为空时使用 scan.retry.max_retries
+	RetryBackoff int  `json:"retry_backoff"` // 首次重试的等待时间（秒），为0时使用 scan.retry.backoff
 
 	TargetIPs      string `json:"target_ips"`
 	TargetURLs     string `json:"target_urls"`
 	TargetAssets   string `json:"target_assets"`
+	TargetFilter   string `json:"target_filter"`
+	ExcludeTargets string `json:"exclude_targets"`
 	ScanParameters string `json:"scan_parameters"`
 
+	AutoImport            bool   `json:"auto_import"`
+	AutoImportMinSeverity string `json:"auto_import_min_severity"`
+	AutoVerify            bool   `json:"auto_verify"`
+
 	ScheduledAt  *time.Time `json:"scheduled_at"`
 	IsRecurring  bool       `json:"is_recurring"`
 	CronSchedule string     `json:"cron_schedule"`
@@ -140,10 +165,10 @@ func (c *ScanController) CreateScanTask(ctx *gin.Context) {
 	}
 
 	// 参数验证
-	if req.TargetIPs == "" && req.TargetURLs == "" && req.TargetAssets == "" {
+	if req.TargetIPs == "" && req.TargetURLs == "" && req.TargetAssets == "" && req.TargetFilter == "" {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
-			"message": "扫描目标不能为空，请至少指定一个IP、URL或资产ID",
+			"message": "扫描目标不能为空，请至少指定一个IP、URL、资产ID或资产筛选条件",
 		})
 		return
 	}
@@ -160,6 +185,56 @@ func (c *ScanController) CreateScanTask(ctx *gin.Context) {
 		})
 		return
 	}
+	if scannerType == models.ScannerTypeCustom {
+		if err := checkCustomScanner(req.CustomScannerID); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+	if req.ScannerProfileID != 0 {
+		if err := checkScannerProfile(req.ScannerProfileID, scannerType); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+	if err := checkAgentZone(strings.TrimSpace(req.AgentZone)); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+	switch models.ScanWindowPolicy(req.WindowPolicy) {
+	case "", models.ScanWindowPolicyHold, models.ScanWindowPolicySplit, models.ScanWindowPolicyIgnore:
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "不支持的时间窗口策略: " + req.WindowPolicy,
+		})
+		return
+	}
+	if err := checkRetryPolicy(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 自动导入策略验证
+	if _, ok := severityRank[models.Severity(req.AutoImportMinSeverity)]; req.AutoImportMinSeverity != "" && !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的自动导入最低严重程度: " + req.AutoImportMinSeverity,
+		})
+		return
+	}
 
 	// 定期任务验证
 	if req.IsRecurring && req.CronSchedule == "" {
@@ -169,6 +244,15 @@ func (c *ScanController) CreateScanTask(ctx *gin.Context) {
 		})
 		return
 	}
+	if req.IsRecurring {
+		if _, err := utils.ParseCron(req.CronSchedule); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Cron表达式无效: " + err.Error(),
+			})
+			return
+		}
+	}
 
 	// 获取当前用户ID
 	userID, exists := ctx.Get("user_id")
@@ -182,22 +266,47 @@ func (c *ScanController) CreateScanTask(ctx *gin.Context) {
 
 	// 创建扫描任务
 	task := models.ScanTask{
-		Name:            req.Name,
-		Description:     req.Description,
-		Type:            scannerType,
-		Status:          models.ScanTaskStatusCreated,
-		ScannerURL:      req.ScannerURL,
-		ScannerAPIKey:   req.ScannerAPIKey,
-		ScannerUsername: req.ScannerUsername,
-		ScannerPassword: req.ScannerPassword,
-		TargetIPs:       req.TargetIPs,
-		TargetURLs:      req.TargetURLs,
-		TargetAssets:    req.TargetAssets,
-		ScanParameters:  req.ScanParameters,
-		ScheduledAt:     req.ScheduledAt,
-		IsRecurring:     req.IsRecurring,
-		CronSchedule:    req.CronSchedule,
-		CreatedBy:       userID.(uint),
+		Name:                  req.Name,
+		Description:           req.Description,
+		Type:                  scannerType,
+		Status:                models.ScanTaskStatusCreated,
+		Priority:              req.Priority,
+		ScannerURL:            req.ScannerURL,
+		ScannerAPIKey:         req.ScannerAPIKey,
+		ScannerUsername:       req.ScannerUsername,
+		ScannerPassword:       req.ScannerPassword,
+		CustomScannerID:       req.CustomScannerID,
+		ScannerProfileID:      req.ScannerProfileID,
+		AgentZone:             strings.TrimSpace(req.AgentZone),
+		WindowPolicy:          models.ScanWindowPolicy(req.WindowPolicy),
+		MaxRetries:            scanMaxRetries(),
+		RetryBackoff:          req.RetryBackoff,
+		TargetIPs:             req.TargetIPs,
+		TargetURLs:            req.TargetURLs,
+		TargetAssets:          req.TargetAssets,
+		TargetFilter:          req.TargetFilter,
+		ExcludeTargets:        req.ExcludeTargets,
+		ScanParameters:        req.ScanParameters,
+		AutoImport:            req.AutoImport,
+		AutoImportMinSeverity: models.Severity(req.AutoImportMinSeverity),
+		AutoVerify:            req.AutoVerify,
+		ScheduledAt:           req.ScheduledAt,
+		IsRecurring:           req.IsRecurring,
+		CronSchedule:          req.CronSchedule,
+		CreatedBy:             userID.(uint),
+	}
+	if req.MaxRetries != nil {
+		task.MaxRetries = *req.MaxRetries
+	}
+	scheduleNextRun(&task, time.Now())
+
+	// 校验扫描目标能够解析
+	if _, err := resolveScanTargets(&task); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "扫描目标无效: " + err.Error(),
+		})
+		return
 	}
 
 	result := utils.DB.Create(&task)
@@ -210,8 +319,9 @@ func (c *ScanController) CreateScanTask(ctx *gin.Context) {
 		return
 	}
 
-	// 立即执行或排队
-	go c.queueScanTask(task.ID)
+	// 加入扫描队列
+	c.queueScanTask(task.ID, models.ScanTriggerManual)
+	utils.DB.First(&task, task.ID)
 
 	// 隐藏敏感信息
 	task.ScannerAPIKey = ""
@@ -270,10 +380,10 @@ func (c *ScanController) UpdateScanTask(ctx *gin.Context) {
 	}
 
 	// 参数验证
-	if req.TargetIPs == "" && req.TargetURLs == "" && req.TargetAssets == "" {
+	if req.TargetIPs == "" && req.TargetURLs == "" && req.TargetAssets == "" && req.TargetFilter == "" {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
-			"message": "扫描目标不能为空，请至少指定一个IP、URL或资产ID",
+			"message": "扫描目标不能为空，请至少指定一个IP、URL、资产ID或资产筛选条件",
 		})
 		return
 	}
@@ -290,6 +400,56 @@ func (c *ScanController) UpdateScanTask(ctx *gin.Context) {
 		})
 		return
 	}
+	if scannerType == models.ScannerTypeCustom {
+		if err := checkCustomScanner(req.CustomScannerID); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+	if req.ScannerProfileID != 0 {
+		if err := checkScannerProfile(req.ScannerProfileID, scannerType); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+	if err := checkAgentZone(strings.TrimSpace(req.AgentZone)); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+	switch models.ScanWindowPolicy(req.WindowPolicy) {
+	case "", models.ScanWindowPolicyHold, models.ScanWindowPolicySplit, models.ScanWindowPolicyIgnore:
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "不支持的时间窗口策略: " + req.WindowPolicy,
+		})
+		return
+	}
+	if err := checkRetryPolicy(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 自动导入策略验证
+	if _, ok := severityRank[models.Severity(req.AutoImportMinSeverity)]; req.AutoImportMinSeverity != "" && !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的自动导入最低严重程度: " + req.AutoImportMinSeverity,
+		})
+		return
+	}
 
 	// 定期任务验证
 	if req.IsRecurring && req.CronSchedule == "" {
@@ -299,22 +459,55 @@ func (c *ScanController) UpdateScanTask(ctx *gin.Context) {
 		})
 		return
 	}
+	if req.IsRecurring {
+		if _, err := utils.ParseCron(req.CronSchedule); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Cron表达式无效: " + err.Error(),
+			})
+			return
+		}
+	}
 
 	// 更新任务
 	task.Name = req.Name
 	task.Description = req.Description
 	task.Type = scannerType
+	task.Priority = req.Priority
 	task.ScannerURL = req.ScannerURL
 	task.ScannerAPIKey = req.ScannerAPIKey
 	task.ScannerUsername = req.ScannerUsername
 	task.ScannerPassword = req.ScannerPassword
+	task.CustomScannerID = req.CustomScannerID
+	task.ScannerProfileID = req.ScannerProfileID
+	task.AgentZone = strings.TrimSpace(req.AgentZone)
+	task.WindowPolicy = models.ScanWindowPolicy(req.WindowPolicy)
+	if req.MaxRetries != nil {
+		task.MaxRetries = *req.MaxRetries
+	}
+	task.RetryBackoff = req.RetryBackoff
 	task.TargetIPs = req.TargetIPs
 	task.TargetURLs = req.TargetURLs
 	task.TargetAssets = req.TargetAssets
+	task.TargetFilter = req.TargetFilter
+	task.ExcludeTargets = req.ExcludeTargets
 	task.ScanParameters = req.ScanParameters
+	task.AutoImport = req.AutoImport
+	task.AutoImportMinSeverity = models.Severity(req.AutoImportMinSeverity)
+	task.AutoVerify = req.AutoVerify
 	task.ScheduledAt = req.ScheduledAt
 	task.IsRecurring = req.IsRecurring
 	task.CronSchedule = req.CronSchedule
+	scheduleNextRun(&task, time.Now())
+
+	// 校验扫描目标能够解析
+	if _, err := resolveScanTargets(&task); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "扫描目标无效: " + err.Error(),
+		})
+		return
+	}
 
 	result = utils.DB.Save(&task)
 	if result.Error != nil {
@@ -369,6 +562,9 @@ func (c *ScanController) DeleteScanTask(ctx *gin.Context) {
 		return
 	}
 
+	// 删除扫描结果的证据，包括已被软删除的结果
+	deleteResultEvidence(utils.DB.Unscoped().Model(&models.ScanResult{}).Where("scan_task_id = ?", task.ID))
+
 	// 删除相关的扫描结果
 	err := utils.DB.Where("scan_task_id = ?", task.ID).Delete(&models.ScanResult{}).Error
 	if err != nil {
@@ -380,6 +576,16 @@ func (c *ScanController) DeleteScanTask(ctx *gin.Context) {
 		return
 	}
 
+	// 删除扫描事件
+	if err := utils.DB.Where("scan_task_id = ?", task.ID).Delete(&models.ScanEvent{}).Error; err != nil {
+		log.Printf("删除扫描事件失败: %v", err)
+	}
+
+	// 删除执行记录
+	if err := utils.DB.Where("scan_task_id = ?", task.ID).Delete(&models.ScanRun{}).Error; err != nil {
+		log.Printf("删除扫描执行记录失败: %v", err)
+	}
+
 	// 删除扫描任务
 	result = utils.DB.Delete(&task)
 	if result.Error != nil {
@@ -432,12 +638,12 @@ func (c *ScanController) StartScanTask(ctx *gin.Context) {
 		return
 	}
 
-	// 更新任务状态并排队
-	task.Status = models.ScanTaskStatusQueued
-	utils.DB.Save(&task)
-
-	// 异步执行扫描任务
-	go c.queueScanTask(task.ID)
+	// 加入扫描队列
+	c.queueScanTask(task.ID, models.ScanTriggerManual)
+	utils.DB.First(&task, task.ID)
+	if scanPool != nil {
+		task.QueuePosition = scanPool.Position(&task)
+	}
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"code":    200,
@@ -478,11 +684,65 @@ func (c *ScanController) CancelScanTask(ctx *gin.Context) {
 		return
 	}
 
-	// 更新任务状态
-	task.Status = models.ScanTaskStatusCancelled
-	utils.DB.Save(&task)
+	// 是否保留已产生的部分结果，默认丢弃
+	var req struct {
+		KeepPartial bool `json:"keep_partial"`
+	}
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "参数错误: " + err.Error(),
+			})
+			return
+		}
+	}
+	if keep, err := strconv.ParseBool(ctx.Query("keep_partial")); err == nil {
+		req.KeepPartial = keep
+	}
+
+	// 排队中的任务直接取消，工作池不会再执行它
+	if task.Status == models.ScanTaskStatusQueued {
+		update := utils.DB.Model(&models.ScanTask{}).
+			Where("id = ? AND status = ?", task.ID, models.ScanTaskStatusQueued).
+			UpdateColumn("status", models.ScanTaskStatusCancelled)
+		if update.Error == nil && update.RowsAffected > 0 {
+			task.Status = models.ScanTaskStatusCancelled
+			ctx.JSON(http.StatusOK, gin.H{
+				"code":    200,
+				"message": "扫描任务已取消",
+				"data":    task,
+			})
+			return
+		}
+		// 任务刚被工作池取出，按运行中的任务处理
+	}
+
+	// 运行中的任务通过执行上下文取消，由执行流程停止扫描器上的扫描并记录部分结果
+	// 由扫描代理执行的任务在代理下次上报时下发取消请求
+	if cancelActiveScan(task.ID, req.KeepPartial) || requestAgentCancel(&task, req.KeepPartial) {
+		ctx.JSON(http.StatusAccepted, gin.H{
+			"code":    200,
+			"message": "正在取消扫描任务",
+			"data":    task,
+		})
+		return
+	}
 
-	// TODO: 实际取消扫描器上的任务
+	// 任务不在本实例执行（如服务重启前遗留的运行中状态），直接标记为已取消
+	completed := time.Now()
+	task.Status = models.ScanTaskStatusCancelled
+	task.CompletedAt = &completed
+	utils.DB.Model(&models.ScanTask{}).Where("id = ?", task.ID).Updates(map[string]interface{}{
+		"status":       task.Status,
+		"completed_at": task.CompletedAt,
+	})
+	utils.DB.Model(&models.ScanRun{}).
+		Where("scan_task_id = ? AND status = ?", task.ID, models.ScanTaskStatusRunning).
+		Updates(map[string]interface{}{
+			"status":       models.ScanTaskStatusCancelled,
+			"completed_at": task.CompletedAt,
+		})
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"code":    200,
@@ -525,7 +785,18 @@ func (c *ScanController) GetScanResults(ctx *gin.Context) {
 	}
 
 	var results []models.ScanResult
-	query := utils.DB.Where("scan_task_id = ?", task.ID).Order("severity DESC, created_at DESC")
+	query := utils.DB.Model(&models.ScanResult{}).Where("scan_task_id = ?", task.ID)
+
+	// 按执行记录过滤：默认只返回最近一次已结束执行的结果，all=true 时返回全部执行的结果
+	var runID uint
+	if id, err := strconv.ParseUint(ctx.Query("run_id"), 10, 64); err == nil {
+		runID = uint(id)
+	} else if ctx.Query("all") != "true" {
+		runID = latestFinishedRunID(task.ID)
+	}
+	if runID != 0 {
+		query = query.Where("scan_run_id = ?", runID)
+	}
 
 	// 过滤条件
 	if severity := ctx.Query("severity"); severity != "" {
@@ -547,10 +818,10 @@ func (c *ScanController) GetScanResults(ctx *gin.Context) {
 	}
 
 	var total int64
-	utils.DB.Model(&models.ScanResult{}).Where("scan_task_id = ?", task.ID).Count(&total)
+	query.Count(&total)
 
 	offset := (page - 1) * pageSize
-	result = query.Offset(offset).Limit(pageSize).Find(&results)
+	result = query.Order("severity DESC, created_at DESC").Offset(offset).Limit(pageSize).Find(&results)
 	if result.Error != nil {
 		log.Printf("获取扫描结果失败: %v", result.Error)
 		ctx.JSON(http.StatusInternalServerError, gin.H{
@@ -569,11 +840,74 @@ func (c *ScanController) GetScanResults(ctx *gin.Context) {
 			"page":       page,
 			"page_size":  pageSize,
 			"total_page": (int(total) + pageSize - 1) / pageSize,
+			"run_id":     runID,
 			"task":       task,
 		},
 	})
 }
 
+// ListScanRuns 获取扫描任务的执行历史
+func (c *ScanController) ListScanRuns(ctx *gin.Context) {
+	taskID := ctx.Param("id")
+	var task models.ScanTask
+
+	// 检查任务是否存在
+	result := utils.DB.First(&task, taskID)
+	if result.Error != nil {
+		log.Printf("扫描任务不存在: %v", result.Error)
+		if gorm.IsRecordNotFoundError(result.Error) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "扫描任务不存在",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取扫描任务失败: " + result.Error.Error(),
+		})
+		return
+	}
+
+	// 查询参数解析
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	var total int64
+	utils.DB.Model(&models.ScanRun{}).Where("scan_task_id = ?", task.ID).Count(&total)
+
+	var runs []models.ScanRun
+	offset := (page - 1) * pageSize
+	result = utils.DB.Where("scan_task_id = ?", task.ID).Order("id DESC").Offset(offset).Limit(pageSize).Find(&runs)
+	if result.Error != nil {
+		log.Printf("获取扫描执行历史失败: %v", result.Error)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取扫描执行历史失败: " + result.Error.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data": gin.H{
+			"runs":        runs,
+			"total":       total,
+			"page":        page,
+			"page_size":   pageSize,
+			"total_page":  (int(total) + pageSize - 1) / pageSize,
+			"next_run_at": task.NextRunAt,
+		},
+	})
+}
+
 // ImportScanResults 将扫描结果导入到漏洞库
 func (c *ScanController) ImportScanResults(ctx *gin.Context) {
 	taskID := ctx.Param("id")
@@ -621,75 +955,9 @@ func (c *ScanController) ImportScanResults(ctx *gin.Context) {
 			return
 		}
 
-		// 批量导入结果
-		imported := 0
-		for _, scanResult := range results {
-			now := time.Now()
-
-			// 创建漏洞记录
-			vulnerability := models.Vulnerability{
-				Title:            scanResult.VulnerabilityName,
-				Description:      scanResult.Description,
-				Severity:         scanResult.Severity,
-				Type:             getVulnerabilityTypeFromCategory(scanResult.Category),
-				Status:           models.StatusNew,
-				CVSS:             scanResult.CVSS,
-				CVE:              scanResult.CVE,
-				StepsToReproduce: scanResult.Detail,
-				Solution:         scanResult.Solution,
-				References:       scanResult.References,
-				DiscoveredAt:     now,
-				ReportedBy:       task.CreatedBy,
-				CreatedAt:        now,
-				UpdatedAt:        now,
-			}
-
-			// 保存漏洞
-			err := utils.DB.Create(&vulnerability).Error
-			if err != nil {
-				log.Printf("导入扫描结果到漏洞库失败: %v", err)
-				continue
-			}
-
-			// 如果有受影响的资产，尝试关联
-			if scanResult.AffectedURL != "" || scanResult.AffectedIP != "" {
-				// 查找匹配的资产
-				var assets []models.Asset
-				query := utils.DB
-				if scanResult.AffectedIP != "" {
-					query = query.Or("ip_address LIKE ?", "%"+scanResult.AffectedIP+"%")
-				}
-				if scanResult.AffectedURL != "" {
-					domain := extractDomainFromURL(scanResult.AffectedURL)
-					if domain != "" {
-						query = query.Or("domain LIKE ?", "%"+domain+"%")
-					}
-				}
-
-				err := query.Find(&assets).Error
-				if err != nil {
-					log.Printf("查找匹配资产失败: %v", err)
-				} else if len(assets) > 0 {
-					// 建立资产关联
-					for _, asset := range assets {
-						err := utils.DB.Model(&vulnerability).Association("Assets").Append(&asset)
-						if err != nil {
-							log.Printf("关联资产失败: %v", err)
-						}
-					}
-				}
-			}
-
-			// 更新扫描结果为已导入
-			scanResult.IsImported = true
-			scanResult.ImportedAt = &now
-			scanResult.ImportedID = vulnerability.ID
-			utils.DB.Save(&scanResult)
-
-			imported++
-		}
-
-		log.Printf("扫描结果导入完成: task_id=%d, imported_count=%d", task.ID, imported)
+		// 批量导入结果，按指纹合并到已有漏洞
+		stats := importScanResults(&task, results, "")
+		log.Printf("扫描结果导入完成: task_id=%d, created=%d, merged=%d", task.ID, stats.Created, stats.Merged)
 	}()
 
 	ctx.JSON(http.StatusOK, gin.H{
@@ -698,8 +966,55 @@ func (c *ScanController) ImportScanResults(ctx *gin.Context) {
 	})
 }
 
+// 常见CWE编号对应的漏洞类型
+var cweVulnTypes = map[string]models.VulnType{
+	"89":   models.TypeSQLInjection,
+	"564":  models.TypeSQLInjection,
+	"79":   models.TypeXSS,
+	"80":   models.TypeXSS,
+	"77":   models.TypeCmdInjection,
+	"78":   models.TypeCmdInjection,
+	"94":   models.TypeCmdInjection,
+	"918":  models.TypeSSRF,
+	"434":  models.TypeFileUpload,
+	"22":   models.TypeFileInclusion,
+	"98":   models.TypeFileInclusion,
+	"200":  models.TypeInfoDisclosure,
+	"209":  models.TypeInfoDisclosure,
+	"532":  models.TypeInfoDisclosure,
+	"284":  models.TypeUnauthorizedAccess,
+	"285":  models.TypeUnauthorizedAccess,
+	"287":  models.TypeUnauthorizedAccess,
+	"306":  models.TypeUnauthorizedAccess,
+	"639":  models.TypeUnauthorizedAccess,
+	"862":  models.TypeUnauthorizedAccess,
+	"863":  models.TypeUnauthorizedAccess,
+	"259":  models.TypeWeakPassword,
+	"521":  models.TypeWeakPassword,
+	"798":  models.TypeWeakPassword,
+	"16":   models.TypeMisconfig,
+	"693":  models.TypeMisconfig,
+	"1021": models.TypeMisconfig,
+}
+
+// 根据CWE编号推断漏洞类型，参数形如 "CWE-79" 或 "79"
+func getVulnerabilityTypeFromCWE(cwe string) (models.VulnType, bool) {
+	id := strings.TrimSpace(strings.ToUpper(cwe))
+	id = strings.TrimPrefix(id, "CWE-")
+	id = strings.TrimPrefix(id, "CWE")
+	vulnType, ok := cweVulnTypes[strings.TrimSpace(id)]
+	return vulnType, ok
+}
+
 // 根据扫描结果分类推断漏洞类型
 func getVulnerabilityTypeFromCategory(category string) models.VulnType {
+	// 分类为CWE编号时优先按CWE匹配
+	if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(category)), "CWE") {
+		if vulnType, ok := getVulnerabilityTypeFromCWE(category); ok {
+			return vulnType
+		}
+	}
+
 	// 基于类别名称匹配漏洞类型
 	lowerCategory := strings.ToLower(category)
 
@@ -750,87 +1065,211 @@ func getVulnerabilityTypeFromCategory(category string) models.VulnType {
 	return models.TypeOther
 }
 
-// 从URL中提取域名
-func extractDomainFromURL(url string) string {
-	// 简单实现：提取 http(s)://domain.com/ 中的 domain.com
-	// 实际应用中可能需要更复杂的解析
-	url = strings.ToLower(url)
+// 内部方法：将扫描任务排队，由扫描工作池按并发限制和出队顺序执行
+func (c *ScanController) queueScanTask(taskID uint, trigger models.ScanTrigger) {
+	c.enqueueScanTask(taskID, trigger, 0)
+}
 
-	// 移除协议
-	url = strings.TrimPrefix(url, "http://")
-	url = strings.TrimPrefix(url, "https://")
+// enqueueScanTask 将扫描任务排队，retryRunID 为手动重试的失败执行记录ID
+func (c *ScanController) enqueueScanTask(taskID uint, trigger models.ScanTrigger, retryRunID uint) {
+	var task models.ScanTask
+	if err := utils.DB.First(&task, taskID).Error; err != nil {
+		log.Printf("获取扫描任务失败: %v", err)
+		return
+	}
 
-	// 提取域名部分（到第一个斜杠或问号之前）
-	domainEnd := len(url)
-	slashPos := strings.Index(url, "/")
-	if slashPos > 0 {
-		domainEnd = slashPos
+	// 如果设置了计划时间且该时间在未来，任务到点后才会出队（定期调度触发的执行不受计划时间限制）
+	if trigger != models.ScanTriggerCron && task.ScheduledAt != nil && task.ScheduledAt.After(time.Now()) {
+		trigger = models.ScanTriggerScheduled
+		log.Printf("任务已排队，等待计划时间执行: task_id=%d, scheduled_at=%v", taskID, task.ScheduledAt)
 	}
 
-	queryPos := strings.Index(url, "?")
-	if queryPos > 0 && queryPos < domainEnd {
-		domainEnd = queryPos
+	err := utils.DB.Model(&models.ScanTask{}).Where("id = ?", taskID).Updates(map[string]interface{}{
+		"status":        models.ScanTaskStatusQueued,
+		"queued_at":     time.Now(),
+		"queue_trigger": trigger,
+		"wait_reason":   "",
+		"wait_until":    nil,
+		"retry_count":   0,
+		"retry_run_id":  retryRunID,
+	}).Error
+	if err != nil {
+		log.Printf("扫描任务排队失败: task_id=%d, err=%v", taskID, err)
+		return
 	}
 
-	return url[:domainEnd]
+	notifyScanPool()
 }
 
-// 内部方法：将扫描任务排队
-func (c *ScanController) queueScanTask(taskID uint) {
-	var task models.ScanTask
-	if err := utils.DB.First(&task, taskID).Error; err != nil {
-		log.Printf("获取扫描任务失败: %v", err)
+// 内部方法：执行扫描任务
+func (c *ScanController) executeScanTask(taskID uint, trigger models.ScanTrigger) {
+	// 先注册可取消的执行上下文再认领任务，取消请求在认领后任何时刻到达都能停止扫描
+	scanCtx, unregister := registerActiveScan(taskID)
+	defer unregister()
+
+	task, run, ok := claimScanTask(taskID, trigger, 0)
+	if !ok {
 		return
 	}
 
-	// 如果设置了计划时间且该时间在未来
-	if task.ScheduledAt != nil && task.ScheduledAt.After(time.Now()) {
-		// TODO: 实现任务调度系统
-		log.Printf("任务已排队，等待计划时间执行: task_id=%d, scheduled_at=%v", taskID, task.ScheduledAt)
+	// 通过扫描器驱动执行扫描
+	var scanResults []models.ScanResult
+	targets, err := resolveScanTargets(task)
+	if err == nil {
+		applyScanWindows(task, targets)
+		scanResults, err = c.runScanner(scanCtx, task, run.ID, targets)
+	} else {
+		err = scanner.WithReason(models.ScanFailureConfig, fmt.Errorf("解析扫描目标失败: %v", err))
+	}
+	cancelled := scanCtx.Err() != nil || errors.Is(err, scanner.ErrScanCancelled)
+	if cancelled && !keepPartialResults(task.ID) {
+		scanResults = nil
+	}
+	if err != nil && !cancelled {
+		failScanRun(task, run, err)
 		return
 	}
 
-	// 否则立即执行
-	task.Status = models.ScanTaskStatusQueued
-	utils.DB.Save(&task)
-
-	c.executeScanTask(taskID)
+	saved := saveScanResults(task, run, scanResults)
+	finishScanRun(task, run, targets, saved, cancelled)
 }
 
-// 内部方法：执行扫描任务
-func (c *ScanController) executeScanTask(taskID uint) {
+// claimScanTask 认领排队中的任务并创建执行记录，agentID 为执行该任务的扫描代理，服务端执行时为0
+//
+// 仅当任务仍在排队时才会成功，避免重复执行或执行已取消的任务。
+func claimScanTask(taskID uint, trigger models.ScanTrigger, agentID uint) (*models.ScanTask, *models.ScanRun, bool) {
+	now := time.Now()
+	claim := utils.DB.Model(&models.ScanTask{}).
+		Where("id = ? AND status = ?", taskID, models.ScanTaskStatusQueued).
+		Updates(map[string]interface{}{
+			"status":         models.ScanTaskStatusRunning,
+			"progress":       0,
+			"started_at":     now,
+			"completed_at":   nil,
+			"agent_id":       agentID,
+			"wait_reason":    "",
+			"wait_until":     nil,
+			"failure_reason": "",
+		})
+	if claim.Error != nil {
+		log.Printf("更新扫描任务状态失败: task_id=%d, err=%v", taskID, claim.Error)
+		return nil, nil, false
+	}
+	if claim.RowsAffected == 0 {
+		log.Printf("扫描任务已不在排队状态，跳过执行: task_id=%d", taskID)
+		return nil, nil, false
+	}
+
 	var task models.ScanTask
 	if err := utils.DB.First(&task, taskID).Error; err != nil {
 		log.Printf("获取扫描任务失败: %v", err)
-		return
+		return nil, nil, false
 	}
 
-	// 更新任务状态为运行中
-	now := time.Now()
-	task.Status = models.ScanTaskStatusRunning
-	task.StartedAt = &now
-	utils.DB.Save(&task)
+	// 记录本次执行
+	run := models.ScanRun{
+		ScanTaskID:   task.ID,
+		Trigger:      trigger,
+		Status:       models.ScanTaskStatusRunning,
+		AgentID:      agentID,
+		Attempt:      task.RetryCount + 1,
+		RetryOfRunID: task.RetryRunID,
+		StartedAt:    &now,
+	}
+	if err := utils.DB.Create(&run).Error; err != nil {
+		log.Printf("创建扫描执行记录失败: %v", err)
+	}
 
-	log.Printf("开始执行扫描任务: task_id=%d", taskID)
+	log.Printf("开始执行扫描任务: task_id=%d, run_id=%d, trigger=%s, agent_id=%d, attempt=%d", taskID, run.ID, trigger, agentID, run.Attempt)
+	message := fmt.Sprintf("扫描开始（%s）", trigger)
+	if run.RetryOfRunID != 0 {
+		message = fmt.Sprintf("扫描开始（%s，重试执行记录 %d，第%d次尝试）", trigger, run.RetryOfRunID, run.Attempt)
+	}
+	recordScanEvent(task.ID, run.ID, models.ScanEventStatus, 0, message)
+	return &task, &run, true
+}
 
-	// TODO: 实现与不同扫描器的集成
-	// 这里只是模拟扫描过程
-	mockScanResults := c.mockScanExecution(&task)
+// failScanRun 将执行记录标记为失败并记录失败原因
+//
+// 失败原因可重试且未超过任务的重试次数时，任务按指数退避重新排队，否则标记为失败。
+func failScanRun(task *models.ScanTask, run *models.ScanRun, err error) {
+	reason := scanner.ClassifyError(err)
+	log.Printf("扫描任务执行失败: task_id=%d, reason=%s, err=%v", task.ID, reason, err)
+
+	failed := time.Now()
+	task.CompletedAt = &failed
+	task.FailureReason = reason
+	task.ResultSummary = "扫描失败: " + err.Error()
+	if run.ID != 0 {
+		utils.DB.Model(run).Updates(map[string]interface{}{
+			"status":         models.ScanTaskStatusFailed,
+			"completed_at":   task.CompletedAt,
+			"result_summary": task.ResultSummary,
+			"failure_reason": reason,
+		})
+	}
 
-	// 保存扫描结果
-	for _, result := range mockScanResults {
+	if reason.Retryable() && task.RetryCount < task.MaxRetries {
+		delay := retryDelay(task)
+		retryAt := failed.Add(delay)
+		task.Status = models.ScanTaskStatusQueued
+		task.QueuedAt = &failed
+		task.RetryCount++
+		task.RetryRunID = run.ID
+		task.WaitUntil = &retryAt
+		task.WaitReason = fmt.Sprintf("扫描失败（%s），%s后进行第%d次重试", reason, delay, task.RetryCount)
+		recordScanEvent(task.ID, run.ID, models.ScanEventStatus, task.Progress, task.ResultSummary+"；"+task.WaitReason)
+		utils.DB.Save(task)
+		notifyScanPool()
+		return
+	}
+
+	task.Status = models.ScanTaskStatusFailed
+	recordScanEvent(task.ID, run.ID, models.ScanEventStatus, task.Progress, task.ResultSummary)
+	utils.DB.Save(task)
+}
+
+// saveScanResults 保存扫描结果，每条结果关联本次执行并计算指纹，返回保存成功的结果
+func saveScanResults(task *models.ScanTask, run *models.ScanRun, results []models.ScanResult) []models.ScanResult {
+	saved := make([]models.ScanResult, 0, len(results))
+	for _, result := range results {
+		result.ID = 0
 		result.ScanTaskID = task.ID
-		utils.DB.Create(&result)
+		result.ScanRunID = run.ID
+		result.Fingerprint = result.ComputeFingerprint()
+		result.IsImported = false
+		result.ImportedAt = nil
+		result.ImportedID = 0
+		if err := utils.DB.Create(&result).Error; err != nil {
+			log.Printf("保存扫描结果失败: task_id=%d, err=%v", task.ID, err)
+			continue
+		}
+		saveResultEvidence(&result)
+		saved = append(saved, result)
 	}
+	return saved
+}
 
-	// 更新任务状态为已完成
+// finishScanRun 按本次执行保存的结果将任务和执行记录标记为已完成或已取消，并执行复测和自动导入
+func finishScanRun(task *models.ScanTask, run *models.ScanRun, targets *scanTargets, saved []models.ScanResult, cancelled bool) {
 	completed := time.Now()
+	progress := task.Progress
 	task.Status = models.ScanTaskStatusCompleted
+	task.Progress = 100
+	if cancelled {
+		// 取消时保留取消前的扫描进度
+		task.Status = models.ScanTaskStatusCancelled
+		task.Progress = progress
+	}
 	task.CompletedAt = &completed
-	task.TotalVulnerabilities = len(mockScanResults)
+	task.TotalVulnerabilities = len(saved)
+	task.CriticalVulnerabilities = 0
+	task.HighVulnerabilities = 0
+	task.MediumVulnerabilities = 0
+	task.LowVulnerabilities = 0
 
 	// 统计各严重程度的漏洞数量
-	for _, result := range mockScanResults {
+	for _, result := range saved {
 		switch result.Severity {
 		case models.SeverityCritical:
 			task.CriticalVulnerabilities++
@@ -849,98 +1288,160 @@ func (c *ScanController) executeScanTask(taskID uint) {
 		task.HighVulnerabilities,
 		task.MediumVulnerabilities,
 		task.LowVulnerabilities)
+	if cancelled {
+		task.ResultSummary = "扫描已取消，" + task.ResultSummary
+	}
 
-	utils.DB.Save(&task)
+	// 先记录结束事件再更新状态，保证实时推送在任务结束前能读到该事件
+	recordScanEvent(task.ID, run.ID, models.ScanEventStatus, task.Progress, task.ResultSummary)
+	utils.DB.Save(task)
+
+	// 更新执行记录
+	if run.ID != 0 {
+		utils.DB.Model(run).Updates(map[string]interface{}{
+			"status":                   task.Status,
+			"completed_at":             task.CompletedAt,
+			"total_vulnerabilities":    task.TotalVulnerabilities,
+			"critical_vulnerabilities": task.CriticalVulnerabilities,
+			"high_vulnerabilities":     task.HighVulnerabilities,
+			"medium_vulnerabilities":   task.MediumVulnerabilities,
+			"low_vulnerabilities":      task.LowVulnerabilities,
+			"result_summary":           task.ResultSummary,
+		})
+	}
+
+	// 更新扫描目标资产的最近扫描时间，按策略自动复测和导入结果
+	// 复测先于导入，再次出现的已修复漏洞重新打开后，本次结果会合并到该漏洞
+	assetIDs := scannedAssetIDs(targets)
+	touchScannedAssets(assetIDs, completed)
+	autoVerifyScanResults(task, run, saved, targets, assetIDs)
+	autoImportScanResults(task, saved)
+
+	log.Printf("扫描任务执行结束: task_id=%d, status=%s, total_vulns=%d", task.ID, task.Status, task.TotalVulnerabilities)
+}
 
-	log.Printf("扫描任务执行完成: task_id=%d, total_vulns=%d", taskID, task.TotalVulnerabilities)
+// maxScanLogSize 扫描日志保存上限，ScanLog 为 text 类型，需小于64KB
+const maxScanLogSize = 60 * 1024
 
-	// 如果是定期任务，则设置下一次执行时间
-	if task.IsRecurring && task.CronSchedule != "" {
-		// TODO: 实现Cron调度
+// 内部方法：通过扫描器类型对应的驱动执行扫描
+func (c *ScanController) runScanner(ctx context.Context, task *models.ScanTask, runID uint, targets *scanTargets) ([]models.ScanResult, error) {
+	driver, err := scanner.Lookup(task.Type)
+	if err != nil {
+		return nil, err
 	}
+
+	job, err := prepareScanJob(task, targets)
+	if err != nil {
+		return nil, scanner.WithReason(models.ScanFailureConfig, err)
+	}
+
+	// 扫描过程日志同时输出到服务日志、扫描事件和任务的扫描日志
+	var (
+		logMu        sync.Mutex
+		logBuf       strings.Builder
+		logTruncated bool
+		progress     int32
+	)
+	job.Logf = func(format string, args ...interface{}) {
+		message := fmt.Sprintf(format, args...)
+		log.Printf("[扫描任务 %d] %s", task.ID, message)
+		recordScanEvent(task.ID, runID, models.ScanEventLog, int(atomic.LoadInt32(&progress)), message)
+
+		logMu.Lock()
+		defer logMu.Unlock()
+		line := time.Now().Format("2006-01-02 15:04:05") + " " + message + "\n"
+		switch {
+		case logBuf.Len()+len(line) <= maxScanLogSize:
+			logBuf.WriteString(line)
+		case !logTruncated:
+			logBuf.WriteString("...（日志过长，已截断）\n")
+			logTruncated = true
+		}
+	}
+	defer func() {
+		logMu.Lock()
+		defer logMu.Unlock()
+		task.ScanLog = logBuf.String()
+	}()
+
+	job.Log("扫描目标: IP/主机 %d 个，URL %d 个，资产 %d 个，排除 %d 个",
+		len(targets.IPs), len(targets.URLs), len(targets.AssetIDs), len(targets.Excluded))
+	for _, warning := range targets.Warnings {
+		job.Log("%s", warning)
+	}
+
+	var lastMessage string
+	return scanner.Run(ctx, driver, job, scanner.RunOptions{
+		PollInterval: scanPollInterval(),
+		KeepPartial: func() bool {
+			return keepPartialResults(task.ID)
+		},
+		OnStatus: func(status *scanner.Status) {
+			// 记录扫描进度供前端展示，进度或状态说明变化时追加进度事件
+			previous := int(atomic.SwapInt32(&progress, int32(status.Progress)))
+			if status.Progress != previous || status.Message != lastMessage {
+				recordScanEvent(task.ID, runID, models.ScanEventProgress, status.Progress, status.Message)
+				utils.DB.Model(&models.ScanTask{}).Where("id = ?", task.ID).UpdateColumn("progress", status.Progress)
+			}
+			lastMessage = status.Message
+			task.Progress = status.Progress
+		},
+	})
 }
 
-// 模拟扫描执行，生成示例结果
-func (c *ScanController) mockScanExecution(task *models.ScanTask) []models.ScanResult {
-	// 这只是一个演示方法，真实实现需要集成各类扫描器
-	time.Sleep(5 * time.Second) // 模拟扫描耗时
-
-	results := []models.ScanResult{}
-
-	// 根据扫描器类型生成不同的模拟结果
-	switch task.Type {
-	case models.ScannerTypeNessus:
-		// 模拟Nessus扫描结果
-		results = append(results, models.ScanResult{
-			VulnerabilityName: "SSL Certificate Expired",
-			Description:       "The SSL certificate of the remote service has expired.",
-			Severity:          models.SeverityMedium,
-			AffectedIP:        strings.Split(task.TargetIPs, ",")[0],
-			AffectedPort:      "443",
-			Detail:            "The SSL certificate expired on 2023-01-01.",
-			Category:          "SSL/TLS",
-			CVE:               "CVE-2023-1234",
-			CVSS:              5.5,
-			Solution:          "Renew the SSL certificate.",
-			References:        "https://example.com/ssl-vulnerabilities",
-			CreatedAt:         time.Now(),
-		})
-		results = append(results, models.ScanResult{
-			VulnerabilityName: "Outdated OpenSSH Version",
-			Description:       "The remote service is running an outdated version of OpenSSH.",
-			Severity:          models.SeverityHigh,
-			AffectedIP:        strings.Split(task.TargetIPs, ",")[0],
-			AffectedPort:      "22",
-			Detail:            "OpenSSH version 7.4 is vulnerable to multiple issues.",
-			Category:          "Services",
-			CVE:               "CVE-2023-5678",
-			CVSS:              7.8,
-			Solution:          "Update OpenSSH to the latest version.",
-			References:        "https://example.com/openssh-vulnerabilities",
-			CreatedAt:         time.Now(),
-		})
-	case models.ScannerTypeXray:
-		// 模拟Xray扫描结果
-		results = append(results, models.ScanResult{
-			VulnerabilityName: "SQL Injection Vulnerability",
-			Description:       "A SQL injection vulnerability was detected in the login form.",
-			Severity:          models.SeverityCritical,
-			AffectedURL:       strings.Split(task.TargetURLs, ",")[0] + "/login.php",
-			Detail:            "SQL injection in the 'username' parameter allows authentication bypass.",
-			Category:          "SQLInjection",
-			CVSS:              9.5,
-			Solution:          "Implement proper input validation and parameterized queries.",
-			References:        "https://example.com/sql-injection",
-			CreatedAt:         time.Now(),
-		})
-		results = append(results, models.ScanResult{
-			VulnerabilityName: "Cross-Site Scripting (XSS)",
-			Description:       "A reflected XSS vulnerability was detected.",
-			Severity:          models.SeverityHigh,
-			AffectedURL:       strings.Split(task.TargetURLs, ",")[0] + "/search.php",
-			Detail:            "Reflected XSS in the 'q' parameter.",
-			Category:          "XSS",
-			CVSS:              7.2,
-			Solution:          "Implement proper output encoding.",
-			References:        "https://example.com/xss",
-			CreatedAt:         time.Now(),
-		})
-	default:
-		// 默认生成一些通用结果
-		results = append(results, models.ScanResult{
-			VulnerabilityName: "Information Disclosure",
-			Description:       "Server information is exposed in HTTP headers.",
-			Severity:          models.SeverityLow,
-			AffectedURL:       task.TargetURLs,
-			AffectedIP:        task.TargetIPs,
-			Detail:            "Server version information is disclosed in HTTP headers.",
-			Category:          "InfoDisclosure",
-			CVSS:              3.5,
-			Solution:          "Configure web server to hide version information.",
-			References:        "https://example.com/info-disclosure",
-			CreatedAt:         time.Now(),
-		})
-	}
-
-	return results
+// prepareScanJob 根据任务和解析后的目标创建扫描作业，服务端和扫描代理执行时共用
+func prepareScanJob(task *models.ScanTask, targets *scanTargets) (*scanner.Job, error) {
+	job, err := scanner.NewJob(task)
+	if err != nil {
+		return nil, err
+	}
+	job.TargetIPs = targets.IPs
+	job.TargetURLs = targets.URLs
+	if len(job.TargetIPs) == 0 && len(job.TargetURLs) == 0 {
+		return nil, errors.New("没有可扫描的目标，请检查目标资产和排除列表")
+	}
+
+	// 引用了连接配置时使用配置中的地址和凭据，否则使用任务中的连接信息
+	if task.ScannerProfileID != 0 {
+		var profile models.ScannerProfile
+		if err := utils.DB.First(&profile, task.ScannerProfileID).Error; err != nil {
+			return nil, fmt.Errorf("扫描器连接配置不存在: %d", task.ScannerProfileID)
+		}
+		if err := job.ApplyProfile(&profile); err != nil {
+			return nil, err
+		}
+	}
+
+	if task.Type == models.ScannerTypeCustom {
+		var def models.CustomScanner
+		if err := utils.DB.First(&def, task.CustomScannerID).Error; err != nil {
+			return nil, fmt.Errorf("自定义扫描器定义不存在: %d", task.CustomScannerID)
+		}
+		job.Custom = &def
+	}
+	return job, nil
+}
+
+// checkCustomScanner 检查自定义扫描器定义是否存在且已启用
+func checkCustomScanner(id uint) error {
+	if id == 0 {
+		return errors.New("自定义扫描任务必须指定扫描器定义")
+	}
+	var def models.CustomScanner
+	if err := utils.DB.First(&def, id).Error; err != nil {
+		return fmt.Errorf("自定义扫描器定义不存在: %d", id)
+	}
+	if !def.Enabled {
+		return fmt.Errorf("自定义扫描器 %s 已停用", def.Name)
+	}
+	return nil
+}
+
+// scanPollInterval 扫描状态轮询间隔
+func scanPollInterval() time.Duration {
+	interval := time.Duration(viper.GetInt("scan.poll_interval")) * time.Second
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return interval
 }
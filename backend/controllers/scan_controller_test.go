@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+
+	"github.com/vulnark/vulnark/evidence"
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/utils"
+)
+
+func TestDeleteScanTaskCleansUp(t *testing.T) {
+	useTestDB(t, &models.ScanTask{}, &models.ScanRun{}, &models.ScanResult{}, &models.ScanEvent{}, &models.Evidence{})
+	viper.Set("upload.location", t.TempDir())
+	defer viper.Set("upload.location", nil)
+
+	task := models.ScanTask{Name: "delete", Type: models.ScannerTypeNessus, Status: models.ScanTaskStatusCompleted}
+	utils.DB.Create(&task)
+	run := models.ScanRun{ScanTaskID: task.ID, Status: models.ScanTaskStatusCompleted}
+	utils.DB.Create(&run)
+	utils.DB.Create(&models.ScanEvent{ScanTaskID: task.ID, ScanRunID: run.ID, Type: models.ScanEventLog, Message: "done"})
+
+	// 未导入结果的证据随任务删除，已导入漏洞的证据保留
+	var digests []string
+	for i, vulnID := range []uint{0, 9} {
+		result := models.ScanResult{ScanTaskID: task.ID, ScanRunID: run.ID, VulnerabilityName: "XSS", Severity: models.SeverityHigh}
+		utils.DB.Create(&result)
+		digest, err := evidence.Save([]byte{byte(i)})
+		if err != nil {
+			t.Fatalf("保存证据失败: %v", err)
+		}
+		digests = append(digests, digest)
+		utils.DB.Create(&models.Evidence{ScanResultID: result.ID, VulnerabilityID: vulnID, Kind: models.EvidenceHTTPRequest, SHA256: digest})
+	}
+
+	w := performJSON((&ScanController{}).DeleteScanTask, http.MethodDelete, "/api/v1/scans/1", nil,
+		gin.Param{Key: "id", Value: "1"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("状态码 = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	for _, table := range []interface{}{&models.ScanRun{}, &models.ScanEvent{}, &models.ScanResult{}} {
+		var count int
+		utils.DB.Model(table).Where("scan_task_id = ?", task.ID).Count(&count)
+		if count != 0 {
+			t.Errorf("%T 剩余 %d 条", table, count)
+		}
+	}
+
+	var remaining []models.Evidence
+	utils.DB.Find(&remaining)
+	if len(remaining) != 1 || remaining[0].VulnerabilityID != 9 {
+		t.Errorf("剩余证据 = %+v", remaining)
+	}
+	for i, digest := range digests {
+		path, _ := evidence.Path(digest)
+		_, err := os.Stat(path)
+		if exists := err == nil; exists != (i == 1) {
+			t.Errorf("证据文件 %d 存在 = %v", i, exists)
+		}
+	}
+}
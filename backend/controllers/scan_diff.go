@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/utils"
+)
+
+// finishedRunStatuses 已结束且可能产生结果的执行状态
+var finishedRunStatuses = []models.ScanTaskStatus{
+	models.ScanTaskStatusCompleted,
+	models.ScanTaskStatusCancelled,
+}
+
+// latestFinishedRunID 返回任务最近一次已结束执行的ID，没有时返回0
+func latestFinishedRunID(taskID uint) uint {
+	var run models.ScanRun
+	err := utils.DB.Where("scan_task_id = ? AND status IN (?)", taskID, finishedRunStatuses).
+		Order("id DESC").
+		First(&run).Error
+	if err != nil {
+		return 0
+	}
+	return run.ID
+}
+
+// DiffScanRun 对比某次执行与上一次执行的结果
+//
+// 结果按指纹（漏洞名称+主机+端口+URL+CVE）匹配，分为新增、仍存在和已消失三类。
+// 默认与同一任务中该执行之前最近一次已结束的执行对比，可通过 base_run_id 指定对比的执行。
+func (c *ScanController) DiffScanRun(ctx *gin.Context) {
+	var run models.ScanRun
+	err := utils.DB.Where("id = ? AND scan_task_id = ?", ctx.Param("run_id"), ctx.Param("id")).First(&run).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "扫描执行记录不存在",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取扫描执行记录失败: " + err.Error(),
+		})
+		return
+	}
+
+	// 确定对比的基准执行
+	var base models.ScanRun
+	baseQuery := utils.DB.Where("scan_task_id = ?", run.ScanTaskID)
+	if baseID := ctx.Query("base_run_id"); baseID != "" {
+		if _, err := strconv.ParseUint(baseID, 10, 64); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "无效的 base_run_id",
+			})
+			return
+		}
+		err = baseQuery.Where("id = ?", baseID).First(&base).Error
+	} else {
+		err = baseQuery.Where("id < ? AND status IN (?)", run.ID, finishedRunStatuses).Order("id DESC").First(&base).Error
+	}
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取对比执行记录失败: " + err.Error(),
+		})
+		return
+	}
+
+	current, err := runResultsByFingerprint(run.ID)
+	if err != nil {
+		log.Printf("获取扫描结果失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取扫描结果失败: " + err.Error(),
+		})
+		return
+	}
+
+	previous := map[string]models.ScanResult{}
+	var baseRun *models.ScanRun
+	if base.ID != 0 {
+		baseRun = &base
+		if previous, err = runResultsByFingerprint(base.ID); err != nil {
+			log.Printf("获取对比扫描结果失败: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "获取对比扫描结果失败: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	added := make([]models.ScanResult, 0)
+	persisting := make([]models.ScanResult, 0)
+	resolved := make([]models.ScanResult, 0)
+	for fingerprint, result := range current {
+		if _, ok := previous[fingerprint]; ok {
+			persisting = append(persisting, result)
+		} else {
+			added = append(added, result)
+		}
+	}
+	for fingerprint, result := range previous {
+		if _, ok := current[fingerprint]; !ok {
+			resolved = append(resolved, result)
+		}
+	}
+	sortScanResults(added)
+	sortScanResults(persisting)
+	sortScanResults(resolved)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data": gin.H{
+			"run":        run,
+			"base_run":   baseRun,
+			"new":        added,
+			"persisting": persisting,
+			"resolved":   resolved,
+			"summary": gin.H{
+				"new":        len(added),
+				"persisting": len(persisting),
+				"resolved":   len(resolved),
+			},
+		},
+	})
+}
+
+// runResultsByFingerprint 获取某次执行的结果并按指纹去重
+func runResultsByFingerprint(runID uint) (map[string]models.ScanResult, error) {
+	var results []models.ScanResult
+	if err := utils.DB.Where("scan_run_id = ?", runID).Order("id ASC").Find(&results).Error; err != nil {
+		return nil, err
+	}
+
+	byFingerprint := make(map[string]models.ScanResult, len(results))
+	for _, result := range results {
+		fingerprint := result.Fingerprint
+		if fingerprint == "" {
+			fingerprint = result.ComputeFingerprint()
+		}
+		if _, ok := byFingerprint[fingerprint]; !ok {
+			byFingerprint[fingerprint] = result
+		}
+	}
+	return byFingerprint, nil
+}
+
+// severityRank 严重程度排序权重
+var severityRank = map[models.Severity]int{
+	models.SeverityCritical: 0,
+	models.SeverityHigh:     1,
+	models.SeverityMedium:   2,
+	models.SeverityLow:      3,
+	models.SeverityInfo:     4,
+}
+
+// sortScanResults 按严重程度和名称排序
+func sortScanResults(results []models.ScanResult) {
+	sort.Slice(results, func(i, j int) bool {
+		ri, rj := severityRank[results[i].Severity], severityRank[results[j].Severity]
+		if ri != rj {
+			return ri < rj
+		}
+		if results[i].VulnerabilityName != results[j].VulnerabilityName {
+			return results[i].VulnerabilityName < results[j].VulnerabilityName
+		}
+		return results[i].ID < results[j].ID
+	})
+}
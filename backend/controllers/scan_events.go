@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/utils"
+)
+
+const (
+	scanStreamPollInterval = time.Second      // SSE 推送时查询新事件的间隔
+	scanStreamHeartbeat    = 15 * time.Second // SSE 心跳间隔，防止代理断开空闲连接
+	scanStreamBatchSize    = 200              // 每次查询的最大事件数
+)
+
+// recordScanEvent 追加一条扫描事件
+func recordScanEvent(taskID, runID uint, eventType models.ScanEventType, progress int, message string) {
+	event := models.ScanEvent{
+		ScanTaskID: taskID,
+		ScanRunID:  runID,
+		Type:       eventType,
+		Message:    message,
+		Progress:   progress,
+	}
+	if err := utils.DB.Create(&event).Error; err != nil {
+		log.Printf("记录扫描事件失败: task_id=%d, err=%v", taskID, err)
+	}
+}
+
+// ListScanEvents 分页获取扫描任务的事件历史，可通过 run_id 过滤某次执行
+func (c *ScanController) ListScanEvents(ctx *gin.Context) {
+	var task models.ScanTask
+	if err := utils.DB.First(&task, ctx.Param("id")).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "扫描任务不存在",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取扫描任务失败: " + err.Error(),
+		})
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "100"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 500 {
+		pageSize = 100
+	}
+
+	query := utils.DB.Model(&models.ScanEvent{}).Where("scan_task_id = ?", task.ID)
+	if runID := ctx.Query("run_id"); runID != "" {
+		query = query.Where("scan_run_id = ?", runID)
+	}
+	if eventType := ctx.Query("type"); eventType != "" {
+		query = query.Where("type = ?", eventType)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var events []models.ScanEvent
+	offset := (page - 1) * pageSize
+	if err := query.Order("id ASC").Offset(offset).Limit(pageSize).Find(&events).Error; err != nil {
+		log.Printf("获取扫描事件失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取扫描事件失败: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data": gin.H{
+			"events":     events,
+			"total":      total,
+			"page":       page,
+			"page_size":  pageSize,
+			"total_page": (int(total) + pageSize - 1) / pageSize,
+		},
+	})
+}
+
+// StreamScanEvents 以 Server-Sent Events 推送扫描日志和进度
+//
+// 客户端断线重连时通过 Last-Event-ID 请求头（或 last_event_id 参数）从断点继续；
+// 未指定时从最近一次执行的第一条事件开始推送。任务结束且事件推送完毕后发送 end 事件并关闭连接。
+func (c *ScanController) StreamScanEvents(ctx *gin.Context) {
+	var task models.ScanTask
+	if err := utils.DB.First(&task, ctx.Param("id")).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "扫描任务不存在",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取扫描任务失败: " + err.Error(),
+		})
+		return
+	}
+
+	lastID := ctx.GetHeader("Last-Event-ID")
+	if lastID == "" {
+		lastID = ctx.Query("last_event_id")
+	}
+	cursor, err := strconv.ParseUint(lastID, 10, 64)
+	if err != nil {
+		cursor = latestRunEventCursor(task.ID)
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	poll := time.NewTicker(scanStreamPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(scanStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var events []models.ScanEvent
+		err := utils.DB.Where("scan_task_id = ? AND id > ?", task.ID, cursor).
+			Order("id ASC").
+			Limit(scanStreamBatchSize).
+			Find(&events).Error
+		if err != nil {
+			log.Printf("查询扫描事件失败: task_id=%d, err=%v", task.ID, err)
+			return
+		}
+
+		for _, event := range events {
+			data, _ := json.Marshal(event)
+			fmt.Fprintf(ctx.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			cursor = uint64(event.ID)
+		}
+		if len(events) > 0 {
+			ctx.Writer.Flush()
+		}
+
+		// 还有未推送的事件时立即继续
+		if len(events) == scanStreamBatchSize {
+			continue
+		}
+
+		// 任务已结束且事件已全部推送，通知客户端并关闭连接
+		var current models.ScanTask
+		if err := utils.DB.Select("id, status, progress").First(&current, task.ID).Error; err != nil || !current.IsInProgress() {
+			data, _ := json.Marshal(gin.H{"status": current.Status, "progress": current.Progress})
+			fmt.Fprintf(ctx.Writer, "event: end\ndata: %s\n\n", data)
+			ctx.Writer.Flush()
+			return
+		}
+
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(ctx.Writer, ": ping\n\n")
+			ctx.Writer.Flush()
+		case <-poll.C:
+		}
+	}
+}
+
+// latestRunEventCursor 返回最近一次执行的第一条事件之前的游标
+func latestRunEventCursor(taskID uint) uint64 {
+	var run models.ScanRun
+	if err := utils.DB.Where("scan_task_id = ?", taskID).Order("id DESC").First(&run).Error; err != nil {
+		return 0
+	}
+
+	var first models.ScanEvent
+	if err := utils.DB.Where("scan_task_id = ? AND scan_run_id = ?", taskID, run.ID).Order("id ASC").First(&first).Error; err != nil {
+		// 最近一次执行还没有事件，从当前最新事件之后开始
+		var last models.ScanEvent
+		if err := utils.DB.Where("scan_task_id = ?", taskID).Order("id DESC").First(&last).Error; err != nil {
+			return 0
+		}
+		return uint64(last.ID)
+	}
+	return uint64(first.ID) - 1
+}
@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/utils"
+)
+
+// scanAssetMatchBatch 按扫描目标查找资产时每批的目标数
+const scanAssetMatchBatch = 500
+
+// closedVulnStatuses 不再参与去重匹配的漏洞状态，匹配到这些状态的发现会新建漏洞
+var closedVulnStatuses = []models.VulnStatus{
+	models.StatusFixed,
+	models.StatusClosed,
+	models.StatusFalsePositive,
+}
+
+// scanImportStats 扫描结果导入统计
+type scanImportStats struct {
+	Created int // 新建的漏洞数
+	Merged  int // 合并到已有漏洞的结果数
+	Skipped int // 未达到最低严重程度而跳过的结果数
+}
+
+// meetsMinSeverity 判断严重程度是否不低于最低要求，最低要求为空时总是满足
+func meetsMinSeverity(severity, min models.Severity) bool {
+	if min == "" {
+		return true
+	}
+	rank, ok := severityRank[severity]
+	if !ok {
+		rank = severityRank[models.SeverityInfo]
+	}
+	return rank <= severityRank[min]
+}
+
+// importScanResults 将扫描结果导入漏洞库
+//
+// 按指纹匹配未关闭的已有漏洞，匹配到时只关联资产，不再重复创建；
+// 受影响的资产按 AffectedIP/AffectedURL 与资产的 IPAddress、Identifier、URL 精确匹配。
+func importScanResults(task *models.ScanTask, results []models.ScanResult, minSeverity models.Severity) scanImportStats {
+	var stats scanImportStats
+	scannedAt := time.Now()
+	if task.CompletedAt != nil {
+		scannedAt = *task.CompletedAt
+	}
+
+	for i := range results {
+		scanResult := &results[i]
+		if scanResult.IsImported {
+			continue
+		}
+		if !meetsMinSeverity(scanResult.Severity, minSeverity) {
+			stats.Skipped++
+			continue
+		}
+
+		fingerprint := scanResult.Fingerprint
+		if fingerprint == "" {
+			fingerprint = scanResult.ComputeFingerprint()
+		}
+
+		var vulnerability models.Vulnerability
+		err := utils.DB.Where("fingerprint = ? AND status NOT IN (?)", fingerprint, closedVulnStatuses).
+			Order("id DESC").
+			First(&vulnerability).Error
+		switch {
+		case err == nil:
+			stats.Merged++
+		case gorm.IsRecordNotFoundError(err):
+			now := time.Now()
+			vulnerability = models.Vulnerability{
+				Title:            scanResult.VulnerabilityName,
+				Fingerprint:      fingerprint,
+				Description:      scanResult.Description,
+				Severity:         scanResult.Severity,
+				Type:             getVulnerabilityTypeFromCategory(scanResult.Category),
+				Status:           models.StatusNew,
+				CVSS:             scanResult.CVSS,
+				CVE:              scanResult.CVE,
+				StepsToReproduce: scanResult.Detail,
+				Solution:         scanResult.Solution,
+				References:       scanResult.References,
+				Source:           "scan",
+				DiscoveredAt:     now,
+				ReportedBy:       task.CreatedBy,
+				CreatedAt:        now,
+				UpdatedAt:        now,
+			}
+			if err := utils.DB.Create(&vulnerability).Error; err != nil {
+				log.Printf("导入扫描结果到漏洞库失败: result_id=%d, err=%v", scanResult.ID, err)
+				continue
+			}
+			stats.Created++
+		default:
+			log.Printf("查找已有漏洞失败: result_id=%d, err=%v", scanResult.ID, err)
+			continue
+		}
+
+		// 关联受影响的资产并更新最近扫描时间
+		for _, asset := range findAssetsForTarget(scanResult.AffectedIP, scanResult.AffectedURL) {
+			asset := asset
+			if err := utils.DB.Model(&vulnerability).Association("Assets").Append(&asset).Error; err != nil {
+				log.Printf("关联资产失败: vuln_id=%d, asset_id=%d, err=%v", vulnerability.ID, asset.ID, err)
+			}
+			touchAssetLastScan(asset.ID, scannedAt)
+		}
+
+		// 扫描结果的证据随结果转到漏洞
+		linkResultEvidence(scanResult.ID, vulnerability.ID)
+
+		// 更新扫描结果为已导入
+		now := time.Now()
+		utils.DB.Model(scanResult).Updates(map[string]interface{}{
+			"is_imported": true,
+			"imported_at": now,
+			"imported_id": vulnerability.ID,
+		})
+		scanResult.IsImported = true
+		scanResult.ImportedAt = &now
+		scanResult.ImportedID = vulnerability.ID
+	}
+
+	return stats
+}
+
+// findAssetsForTarget 查找与IP或URL匹配的资产
+// IP 与资产的 IPAddress、Identifier 匹配；URL 与资产的 URL（完整地址或站点根地址）匹配，
+// URL中的主机名再与 Identifier、IPAddress 匹配
+func findAssetsForTarget(ip, rawURL string) []models.Asset {
+	var conditions []string
+	var args []interface{}
+
+	if ip = strings.TrimSpace(ip); ip != "" {
+		conditions = append(conditions, "ip_address = ?", "identifier = ?")
+		args = append(args, ip, ip)
+	}
+
+	if rawURL = strings.TrimSpace(rawURL); rawURL != "" {
+		candidates := []string{rawURL, strings.TrimRight(rawURL, "/")}
+		if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+			origin := u.Scheme + "://" + u.Host
+			candidates = append(candidates, origin, origin+"/")
+
+			host := u.Hostname()
+			conditions = append(conditions, "identifier = ?", "identifier = ?")
+			args = append(args, host, u.Host)
+			if net.ParseIP(host) != nil {
+				conditions = append(conditions, "ip_address = ?")
+				args = append(args, host)
+			}
+		}
+		conditions = append(conditions, "url IN (?)")
+		args = append(args, candidates)
+	}
+
+	if len(conditions) == 0 {
+		return nil
+	}
+
+	var assets []models.Asset
+	if err := utils.DB.Where(strings.Join(conditions, " OR "), args...).Find(&assets).Error; err != nil {
+		log.Printf("查找匹配资产失败: %v", err)
+		return nil
+	}
+	return assets
+}
+
+// touchAssetLastScan 更新资产的最近扫描时间，不会把时间改早
+func touchAssetLastScan(assetID uint, scannedAt time.Time) {
+	err := utils.DB.Model(&models.Asset{}).
+		Where("id = ? AND (last_scan IS NULL OR last_scan < ?)", assetID, scannedAt).
+		UpdateColumn("last_scan", scannedAt).Error
+	if err != nil {
+		log.Printf("更新资产最近扫描时间失败: asset_id=%d, err=%v", assetID, err)
+	}
+}
+
+// scannedAssetIDs 扫描目标对应的资产ID：作为目标的资产以及IP、主机名或URL与目标匹配的资产
+//
+// AssetIDs 中只有排除列表和时间窗口过滤后仍有目标的资产（见 resolveScanTargets、excludeBlockedTargets），
+// 其余资产只按实际扫描的目标匹配，未被扫描的资产不会更新最近扫描时间，也不会参与自动复测。
+func scannedAssetIDs(targets *scanTargets) map[uint]bool {
+	ids := make(map[uint]bool)
+	for _, id := range targets.AssetIDs {
+		ids[id] = true
+	}
+
+	var urls []string
+	for _, target := range targets.URLs {
+		urls = append(urls, target, strings.TrimRight(target, "/"))
+		if u, err := url.Parse(target); err == nil && u.Host != "" {
+			origin := u.Scheme + "://" + u.Host
+			urls = append(urls, origin, origin+"/")
+		}
+	}
+
+	// 分批查询，避免展开大网段后单条语句参数过多
+	match := func(condition string, values []string) {
+		for start := 0; start < len(values); start += scanAssetMatchBatch {
+			end := start + scanAssetMatchBatch
+			if end > len(values) {
+				end = len(values)
+			}
+			batch := values[start:end]
+			args := make([]interface{}, strings.Count(condition, "?"))
+			for i := range args {
+				args[i] = batch
+			}
+
+			var assets []models.Asset
+			if err := utils.DB.Select("id").Where(condition, args...).Find(&assets).Error; err != nil {
+				log.Printf("查找匹配资产失败: %v", err)
+				return
+			}
+			for _, asset := range assets {
+				ids[asset.ID] = true
+			}
+		}
+	}
+	match("ip_address IN (?) OR identifier IN (?)", targets.hosts())
+	match("url IN (?)", urls)
+	return ids
+}
+
+// touchScannedAssets 更新扫描目标对应资产的最近扫描时间
+func touchScannedAssets(assetIDs map[uint]bool, scannedAt time.Time) {
+	for id := range assetIDs {
+		touchAssetLastScan(id, scannedAt)
+	}
+}
+
+// autoImportScanResults 按任务的自动导入策略导入本次执行的结果
+func autoImportScanResults(task *models.ScanTask, results []models.ScanResult) {
+	if !task.AutoImport || len(results) == 0 {
+		return
+	}
+
+	stats := importScanResults(task, results, task.AutoImportMinSeverity)
+	log.Printf("自动导入扫描结果完成: task_id=%d, created=%d, merged=%d, skipped=%d",
+		task.ID, stats.Created, stats.Merged, stats.Skipped)
+}
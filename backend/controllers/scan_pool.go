@@ -0,0 +1,321 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/viper"
+
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/utils"
+)
+
+// 排队任务的出队顺序
+const (
+	QueueOrderFIFO     = "fifo"     // 按入队时间先后
+	QueueOrderPriority = "priority" // 按优先级从高到低，同优先级按入队时间先后
+)
+
+// scanPool 全局扫描工作池，由 main 在启动时创建
+var scanPool *ScanWorkerPool
+
+// ScanWorkerPool 扫描任务工作池
+//
+// 排队的任务保存在数据库中（status=queued），工作池按配置的顺序从数据库取出可执行的任务，
+// 同时限制全局并发数和每种扫描器类型的并发数。服务重启后，仍处于排队状态的任务会被重新调度。
+type ScanWorkerPool struct {
+	controller  *ScanController
+	maxWorkers  int
+	typeLimits  map[models.ScannerType]int
+	order       string
+	interval    time.Duration
+	wake        chan struct{}
+	stop        chan struct{}
+	wg          sync.WaitGroup
+	mu          sync.Mutex
+	running     map[uint]models.ScannerType
+	runningType map[models.ScannerType]int
+}
+
+// NewScanWorkerPool 根据配置创建扫描工作池并设置为全局工作池
+func NewScanWorkerPool() *ScanWorkerPool {
+	maxWorkers := viper.GetInt("scan.max_concurrent")
+	if maxWorkers <= 0 {
+		maxWorkers = 5
+	}
+
+	typeLimits := make(map[models.ScannerType]int)
+	for key := range viper.GetStringMap("scan.type_concurrency") {
+		if limit := viper.GetInt("scan.type_concurrency." + key); limit > 0 {
+			typeLimits[models.ScannerType(strings.ToLower(key))] = limit
+		}
+	}
+
+	order := strings.ToLower(viper.GetString("scan.queue_order"))
+	if order != QueueOrderPriority {
+		order = QueueOrderFIFO
+	}
+
+	interval := time.Duration(viper.GetInt("scan.queue_interval")) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	scanPool = &ScanWorkerPool{
+		controller:  new(ScanController),
+		maxWorkers:  maxWorkers,
+		typeLimits:  typeLimits,
+		order:       order,
+		interval:    interval,
+		wake:        make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		running:     make(map[uint]models.ScannerType),
+		runningType: make(map[models.ScannerType]int),
+	}
+	return scanPool
+}
+
+// Start 启动工作池的调度循环
+func (p *ScanWorkerPool) Start() {
+	if utils.DB == nil {
+		log.Printf("数据库未初始化，扫描工作池未启动")
+		return
+	}
+
+	var queued int
+	utils.DB.Model(&models.ScanTask{}).Where("status = ?", models.ScanTaskStatusQueued).Count(&queued)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		p.dispatch()
+		for {
+			select {
+			case <-ticker.C:
+			case <-p.wake:
+			case <-p.stop:
+				return
+			}
+			p.dispatch()
+		}
+	}()
+
+	log.Printf("扫描工作池已启动: 全局并发=%d, 类型并发=%v, 出队顺序=%s, 待恢复排队任务=%d",
+		p.maxWorkers, p.typeLimits, p.order, queued)
+}
+
+// Stop 停止调度新的任务，已在执行的扫描不受影响
+func (p *ScanWorkerPool) Stop() {
+	select {
+	case <-p.stop:
+		return
+	default:
+		close(p.stop)
+	}
+	p.wg.Wait()
+	log.Printf("扫描工作池已停止")
+}
+
+// Notify 通知工作池有新的任务入队或有空闲的执行槽位
+func (p *ScanWorkerPool) Notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch 按出队顺序取出可执行的排队任务，直到达到并发上限
+func (p *ScanWorkerPool) dispatch() {
+	// 扫描代理失联时，其正在执行的任务不会再有结果
+	reapStaleAgentRuns()
+
+	p.mu.Lock()
+	free := p.maxWorkers - len(p.running)
+	p.mu.Unlock()
+	if free <= 0 {
+		return
+	}
+
+	now := time.Now()
+	var tasks []models.ScanTask
+	err := p.readyQuery(now).
+		Select("id, type, queue_trigger").
+		Order(p.orderClause()).
+		Find(&tasks).Error
+	if err != nil {
+		log.Printf("查询排队扫描任务失败: %v", err)
+		return
+	}
+
+	for _, task := range tasks {
+		if !p.acquire(task.ID, task.Type) {
+			continue
+		}
+		if holdForScanWindows(task.ID, now) {
+			p.release(task.ID, task.Type)
+			continue
+		}
+
+		trigger := task.QueueTrigger
+		if trigger == "" {
+			trigger = models.ScanTriggerManual
+		}
+
+		go func(taskID uint, scannerType models.ScannerType, trigger models.ScanTrigger) {
+			defer func() {
+				p.release(taskID, scannerType)
+				p.Notify()
+			}()
+			p.controller.executeScanTask(taskID, trigger)
+		}(task.ID, task.Type, trigger)
+	}
+}
+
+// acquire 为任务占用一个执行槽位，超出全局或类型并发上限时返回 false
+func (p *ScanWorkerPool) acquire(taskID uint, scannerType models.ScannerType) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.running[taskID]; ok {
+		return false
+	}
+	if len(p.running) >= p.maxWorkers {
+		return false
+	}
+	if limit, ok := p.typeLimits[scannerType]; ok && p.runningType[scannerType] >= limit {
+		return false
+	}
+
+	p.running[taskID] = scannerType
+	p.runningType[scannerType]++
+	return true
+}
+
+// release 释放任务占用的执行槽位
+func (p *ScanWorkerPool) release(taskID uint, scannerType models.ScannerType) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.running[taskID]; !ok {
+		return
+	}
+	delete(p.running, taskID)
+	p.runningType[scannerType]--
+}
+
+// readyScanTasks 已到执行时间的排队任务，计划时间在未来的任务暂不出队（定期触发不受计划时间限制），
+// 等待时间窗口的任务在下次检查时间之前也不出队
+func readyScanTasks(now time.Time) *gorm.DB {
+	return utils.DB.Model(&models.ScanTask{}).
+		Where("status = ?", models.ScanTaskStatusQueued).
+		Where("queue_trigger = ? OR scheduled_at IS NULL OR scheduled_at <= ?", models.ScanTriggerCron, now).
+		Where("wait_until IS NULL OR wait_until <= ?", now)
+}
+
+// readyQuery 由服务端执行的已到执行时间的排队任务，指定了网络区域的任务由扫描代理领取
+func (p *ScanWorkerPool) readyQuery(now time.Time) *gorm.DB {
+	return readyScanTasks(now).Where("agent_zone IS NULL OR agent_zone = ''")
+}
+
+// orderClause 出队顺序对应的排序条件
+func (p *ScanWorkerPool) orderClause() string {
+	if p.order == QueueOrderPriority {
+		return "priority DESC, queued_at ASC, id ASC"
+	}
+	return "queued_at ASC, id ASC"
+}
+
+// Position 计算排队任务在队列中的位置，从1开始；任务未在排队或尚未到执行时间时返回0
+func (p *ScanWorkerPool) Position(task *models.ScanTask) int {
+	if task.Status != models.ScanTaskStatusQueued || task.QueuedAt == nil {
+		return 0
+	}
+	now := time.Now()
+	if task.QueueTrigger != models.ScanTriggerCron && task.ScheduledAt != nil && task.ScheduledAt.After(now) {
+		return 0
+	}
+
+	ahead := "queued_at < ? OR (queued_at = ? AND id < ?)"
+	args := []interface{}{task.QueuedAt, task.QueuedAt, task.ID}
+	if p.order == QueueOrderPriority {
+		ahead = "priority > ? OR (priority = ? AND (" + ahead + "))"
+		args = append([]interface{}{task.Priority, task.Priority}, args...)
+	}
+
+	query := p.readyQuery(now)
+	if task.AgentZone != "" {
+		query = readyScanTasks(now).Where("agent_zone = ?", task.AgentZone)
+	}
+
+	var count int
+	if err := query.Where(ahead, args...).Count(&count).Error; err != nil {
+		log.Printf("计算排队位置失败: task_id=%d, err=%v", task.ID, err)
+		return 0
+	}
+	return count + 1
+}
+
+// notifyScanPool 通知全局工作池调度排队任务
+func notifyScanPool() {
+	if scanPool != nil {
+		scanPool.Notify()
+	}
+}
+
+// activeScan 本实例上正在执行的扫描
+type activeScan struct {
+	cancel      context.CancelFunc
+	keepPartial bool
+}
+
+var (
+	activeScansMu sync.Mutex
+	activeScans   = make(map[uint]*activeScan)
+)
+
+// registerActiveScan 为扫描任务创建可取消的执行上下文，返回的函数在扫描结束后调用
+func registerActiveScan(taskID uint) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	activeScansMu.Lock()
+	activeScans[taskID] = &activeScan{cancel: cancel}
+	activeScansMu.Unlock()
+
+	return ctx, func() {
+		activeScansMu.Lock()
+		delete(activeScans, taskID)
+		activeScansMu.Unlock()
+		cancel()
+	}
+}
+
+// cancelActiveScan 取消本实例上正在执行的扫描，任务不在本实例执行时返回 false
+func cancelActiveScan(taskID uint, keepPartial bool) bool {
+	activeScansMu.Lock()
+	defer activeScansMu.Unlock()
+
+	scan, ok := activeScans[taskID]
+	if !ok {
+		return false
+	}
+	scan.keepPartial = keepPartial
+	scan.cancel()
+	return true
+}
+
+// keepPartialResults 取消扫描时是否保留已获取的部分结果
+func keepPartialResults(taskID uint) bool {
+	activeScansMu.Lock()
+	defer activeScansMu.Unlock()
+
+	scan, ok := activeScans[taskID]
+	return ok && scan.keepPartial
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/viper"
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/utils"
+)
+
+// RetryScanRun 手动重试失败的执行
+//
+// 任务重新排队，下一次执行记录关联被重试的执行，自动重试次数从0重新计算。
+func (c *ScanController) RetryScanRun(ctx *gin.Context) {
+	var run models.ScanRun
+	err := utils.DB.Where("id = ? AND scan_task_id = ?", ctx.Param("run_id"), ctx.Param("id")).First(&run).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "扫描执行记录不存在",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取扫描执行记录失败: " + err.Error(),
+		})
+		return
+	}
+	if run.Status != models.ScanTaskStatusFailed {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "只能重试失败的执行",
+		})
+		return
+	}
+
+	var task models.ScanTask
+	if err := utils.DB.First(&task, run.ScanTaskID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "扫描任务不存在",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取扫描任务失败: " + err.Error(),
+		})
+		return
+	}
+	if task.Status == models.ScanTaskStatusQueued || task.Status == models.ScanTaskStatusRunning {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "扫描任务正在排队或执行中",
+		})
+		return
+	}
+
+	c.enqueueScanTask(task.ID, models.ScanTriggerManual, run.ID)
+	log.Printf("手动重试扫描执行: task_id=%d, run_id=%d", task.ID, run.ID)
+
+	utils.DB.First(&task, task.ID)
+	if scanPool != nil {
+		task.QueuePosition = scanPool.Position(&task)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "扫描任务已重新排队",
+		"data":    task,
+	})
+}
+
+// retryDelay 任务下一次自动重试前的等待时间，每次重试翻倍，不超过 scan.retry.max_backoff
+func retryDelay(task *models.ScanTask) time.Duration {
+	backoff := time.Duration(task.RetryBackoff) * time.Second
+	if backoff <= 0 {
+		backoff = time.Duration(viper.GetInt("scan.retry.backoff")) * time.Second
+	}
+	if backoff <= 0 {
+		backoff = time.Minute
+	}
+	limit := time.Duration(viper.GetInt("scan.retry.max_backoff")) * time.Second
+	if limit <= 0 {
+		limit = time.Hour
+	}
+
+	delay := backoff
+	for i := 0; i < task.RetryCount && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+	return delay
+}
+
+// scanMaxRetries 新建任务默认的自动重试次数
+func scanMaxRetries() int {
+	if !viper.IsSet("scan.retry.max_retries") {
+		return 2
+	}
+	return viper.GetInt("scan.retry.max_retries")
+}
+
+// maxScanRetries 任务允许设置的最多自动重试次数
+const maxScanRetries = 10
+
+// checkRetryPolicy 校验任务的重试设置
+func checkRetryPolicy(req *ScanTaskRequest) error {
+	if req.MaxRetries != nil && (*req.MaxRetries < 0 || *req.MaxRetries > maxScanRetries) {
+		return fmt.Errorf("自动重试次数必须在0到%d之间", maxScanRetries)
+	}
+	if req.RetryBackoff < 0 {
+		return errors.New("重试等待时间不能为负数")
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/utils"
+)
+
+// ScanScheduler 扫描任务调度器，在后台定期检查并将到期的定期任务加入扫描队列
+type ScanScheduler struct {
+	controller *ScanController
+	interval   time.Duration
+	stop       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// NewScanScheduler 创建扫描任务调度器
+func NewScanScheduler() *ScanScheduler {
+	interval := time.Duration(viper.GetInt("scan.scheduler_interval")) * time.Second
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	return &ScanScheduler{
+		controller: new(ScanController),
+		interval:   interval,
+		stop:       make(chan struct{}),
+	}
+}
+
+// Start 启动调度器，先从数据库重新加载定期任务，再开始周期检查
+func (s *ScanScheduler) Start() {
+	if utils.DB == nil {
+		log.Printf("数据库未初始化，扫描调度器未启动")
+		return
+	}
+
+	s.reload()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		s.tick(time.Now())
+		for {
+			select {
+			case now := <-ticker.C:
+				s.tick(now)
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+
+	log.Printf("扫描调度器已启动，检查间隔: %v", s.interval)
+}
+
+// Stop 停止调度器
+func (s *ScanScheduler) Stop() {
+	select {
+	case <-s.stop:
+		return
+	default:
+		close(s.stop)
+	}
+	s.wg.Wait()
+	log.Printf("扫描调度器已停止")
+}
+
+// reload 服务启动时为缺少下一次执行时间的定期任务补全 next_run_at
+// 已经过期的 next_run_at 保持不变，由首次检查立即补跑一次
+func (s *ScanScheduler) reload() {
+	var tasks []models.ScanTask
+	if err := utils.DB.Where("is_recurring = ? AND next_run_at IS NULL", true).Find(&tasks).Error; err != nil {
+		log.Printf("加载定期扫描任务失败: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range tasks {
+		task := &tasks[i]
+		scheduleNextRun(task, now)
+		if task.NextRunAt == nil {
+			continue
+		}
+		if err := utils.DB.Model(task).UpdateColumn("next_run_at", task.NextRunAt).Error; err != nil {
+			log.Printf("更新定期任务下一次执行时间失败: task_id=%d, err=%v", task.ID, err)
+		}
+	}
+
+	log.Printf("已加载定期扫描任务: %d 个需要补全下一次执行时间", len(tasks))
+}
+
+// tick 触发所有到期的定期任务
+// 计划时间未到的排队任务由扫描工作池在到点后出队，这里不再处理
+func (s *ScanScheduler) tick(now time.Time) {
+	s.dispatchRecurring(now)
+}
+
+// dispatchRecurring 触发所有到期的定期扫描任务
+func (s *ScanScheduler) dispatchRecurring(now time.Time) {
+	var tasks []models.ScanTask
+	err := utils.DB.Where("is_recurring = ? AND next_run_at IS NOT NULL AND next_run_at <= ?", true, now).
+		Order("next_run_at ASC").
+		Find(&tasks).Error
+	if err != nil {
+		log.Printf("查询到期的定期扫描任务失败: %v", err)
+		return
+	}
+
+	for i := range tasks {
+		task := &tasks[i]
+		previous := *task.NextRunAt
+
+		// 先推进下一次执行时间，避免重复触发
+		scheduleNextRun(task, now)
+		update := utils.DB.Model(&models.ScanTask{}).
+			Where("id = ? AND next_run_at = ?", task.ID, previous).
+			UpdateColumn("next_run_at", task.NextRunAt)
+		if update.Error != nil {
+			log.Printf("更新定期任务下一次执行时间失败: task_id=%d, err=%v", task.ID, update.Error)
+			continue
+		}
+		if update.RowsAffected == 0 {
+			// 已被其他实例或请求处理
+			continue
+		}
+
+		// 上一次执行尚未结束时跳过本次触发
+		if task.IsInProgress() {
+			log.Printf("定期扫描任务仍在执行，跳过本次触发: task_id=%d, next_run_at=%v", task.ID, task.NextRunAt)
+			continue
+		}
+
+		log.Printf("触发定期扫描任务: task_id=%d, planned_at=%v, next_run_at=%v", task.ID, previous, task.NextRunAt)
+		s.controller.queueScanTask(task.ID, models.ScanTriggerCron)
+	}
+}
+
+// scheduleNextRun 根据Cron表达式计算并设置任务的下一次执行时间
+func scheduleNextRun(task *models.ScanTask, from time.Time) {
+	if !task.IsRecurring || task.CronSchedule == "" {
+		task.NextRunAt = nil
+		return
+	}
+
+	next, err := utils.NextCronTime(task.CronSchedule, from)
+	if err != nil {
+		log.Printf("计算定期任务下一次执行时间失败: task_id=%d, cron=%q, err=%v", task.ID, task.CronSchedule, err)
+		task.NextRunAt = nil
+		return
+	}
+	task.NextRunAt = &next
+}
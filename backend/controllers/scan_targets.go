@@ -0,0 +1,363 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/viper"
+
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/scanner"
+	"github.com/vulnark/vulnark/utils"
+)
+
+// scanTargetPreviewLimit 预览接口每类目标最多返回的条数
+const scanTargetPreviewLimit = 1000
+
+// nonNetworkAssetTypes 标识不是网络地址的资产类型，这类资产没有IP和URL时不作为扫描目标
+var nonNetworkAssetTypes = map[models.AssetType]bool{
+	models.AssetTypeContainerImage: true, // 标识为镜像摘要
+	models.AssetTypeApplication:    true, // 由SBOM创建的应用，标识为purl或组件名
+}
+
+// scanTargets 展开资产、网段和排除列表后的扫描目标
+type scanTargets struct {
+	IPs      []string `json:"ips"`       // IP和主机名
+	URLs     []string `json:"urls"`      // URL
+	AssetIDs []uint   `json:"asset_ids"` // 作为目标的资产，不含目标全部被排除的资产
+	Excluded []string `json:"excluded"`  // 被排除的目标
+	Warnings []string `json:"warnings"`  // 无法解析为目标的资产等提示
+}
+
+// hosts 目标中的IP、主机名和URL主机名
+func (t *scanTargets) hosts() []string {
+	seen := make(map[string]bool)
+	var hosts []string
+	for _, host := range t.IPs {
+		if !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	for _, target := range t.URLs {
+		if host := urlHost(target); host != "" && !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// resolveScanTargets 解析扫描任务的目标
+//
+// 依次合并 TargetIPs（支持CIDR和IP范围）、TargetURLs、TargetAssets 中的资产和 TargetFilter 选中的资产，
+// 再去掉全局配置 scan.exclude_targets 和任务 ExcludeTargets 中排除的目标。
+func resolveScanTargets(task *models.ScanTask) (*scanTargets, error) {
+	targets := &scanTargets{
+		IPs:      []string{},
+		URLs:     []string{},
+		AssetIDs: []uint{},
+		Excluded: []string{},
+		Warnings: []string{},
+	}
+
+	ipEntries := scanner.SplitList(task.TargetIPs)
+	urlEntries := scanner.SplitList(task.TargetURLs)
+
+	assets, err := loadTargetAssets(task, targets)
+	if err != nil {
+		return nil, err
+	}
+	// 记录每个资产贡献的目标，排除后没有剩余目标的资产不算作扫描目标
+	type assetEntry struct {
+		id   uint
+		ips  []string
+		urls []string
+	}
+	var assetEntries []assetEntry
+	for _, asset := range assets {
+		entry := assetEntry{id: asset.ID}
+		if ip := strings.TrimSpace(asset.IPAddress); ip != "" {
+			entry.ips = append(entry.ips, ip)
+		}
+		if u := strings.TrimSpace(asset.URL); u != "" {
+			entry.urls = append(entry.urls, u)
+		}
+		if len(entry.ips) == 0 && len(entry.urls) == 0 {
+			switch identifier := strings.TrimSpace(asset.Identifier); {
+			case nonNetworkAssetTypes[asset.Type]:
+				targets.Warnings = append(targets.Warnings, fmt.Sprintf("资产 #%d %s 的类型为 %s，没有IP或URL，已跳过", asset.ID, asset.Name, asset.Type))
+				continue
+			case strings.Contains(identifier, "://"):
+				entry.urls = append(entry.urls, identifier)
+			case identifier != "":
+				entry.ips = append(entry.ips, identifier)
+			default:
+				targets.Warnings = append(targets.Warnings, fmt.Sprintf("资产 #%d %s 没有IP或URL，已跳过", asset.ID, asset.Name))
+				continue
+			}
+		}
+		ipEntries = append(ipEntries, entry.ips...)
+		urlEntries = append(urlEntries, entry.urls...)
+		assetEntries = append(assetEntries, entry)
+	}
+
+	limit := viper.GetInt("scan.max_targets")
+	ips, err := scanner.ExpandIPTargets(ipEntries, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	exclusions, err := scanner.ParseExclusions(append(viper.GetStringSlice("scan.exclude_targets"),
+		scanner.SplitList(task.ExcludeTargets)...))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ip := range ips {
+		if exclusions.ExcludesHost(ip) {
+			targets.Excluded = append(targets.Excluded, ip)
+			continue
+		}
+		targets.IPs = append(targets.IPs, ip)
+	}
+
+	seen := make(map[string]bool)
+	for _, target := range urlEntries {
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+			return nil, fmt.Errorf("无效的目标URL: %s", target)
+		}
+		if seen[target] {
+			continue
+		}
+		seen[target] = true
+		if exclusions.ExcludesURL(target) {
+			targets.Excluded = append(targets.Excluded, target)
+			continue
+		}
+		targets.URLs = append(targets.URLs, target)
+	}
+
+	if limit <= 0 {
+		limit = scanner.DefaultMaxTargets
+	}
+	if len(targets.IPs)+len(targets.URLs) > limit {
+		return nil, fmt.Errorf("%w（%d）", scanner.ErrTooManyTargets, limit)
+	}
+
+	keptIPs := make(map[string]bool, len(targets.IPs))
+	for _, ip := range targets.IPs {
+		keptIPs[ip] = true
+	}
+	keptURLs := make(map[string]bool, len(targets.URLs))
+	for _, target := range targets.URLs {
+		keptURLs[target] = true
+	}
+	for _, entry := range assetEntries {
+		// 资产的IP已随全部目标一起展开过，这里不会再出错
+		ips, _ := scanner.ExpandIPTargets(entry.ips, limit)
+		kept := false
+		for _, ip := range ips {
+			kept = kept || keptIPs[ip]
+		}
+		for _, target := range entry.urls {
+			kept = kept || keptURLs[target]
+		}
+		if kept {
+			targets.AssetIDs = append(targets.AssetIDs, entry.id)
+		}
+	}
+	return targets, nil
+}
+
+// loadTargetAssets 加载 TargetAssets 指定的资产和 TargetFilter 选中的资产，按ID去重
+func loadTargetAssets(task *models.ScanTask, targets *scanTargets) ([]models.Asset, error) {
+	ids, err := parseAssetIDs(task.TargetAssets)
+	if err != nil {
+		return nil, err
+	}
+
+	var assets []models.Asset
+	if len(ids) > 0 {
+		if err := utils.DB.Where("id IN (?)", ids).Order("id ASC").Find(&assets).Error; err != nil {
+			return nil, fmt.Errorf("查询目标资产失败: %v", err)
+		}
+		found := make(map[uint]bool, len(assets))
+		for _, asset := range assets {
+			found[asset.ID] = true
+		}
+		for _, id := range ids {
+			if !found[id] {
+				targets.Warnings = append(targets.Warnings, fmt.Sprintf("资产 #%d 不存在，已跳过", id))
+				found[id] = true
+			}
+		}
+	}
+
+	filter, err := parseScanTargetFilter(task.TargetFilter)
+	if err != nil {
+		return nil, err
+	}
+	if filter.IsEmpty() {
+		return assets, nil
+	}
+
+	// 按条件只选择活跃的资产，标签为逗号分隔的字符串，在查询结果中匹配
+	query := utils.DB.Where("status = ?", models.AssetStatusActive)
+	if len(filter.Types) > 0 {
+		query = query.Where("type IN (?)", filter.Types)
+	}
+	if len(filter.Departments) > 0 {
+		query = query.Where("department IN (?)", filter.Departments)
+	}
+	if len(filter.Tags) > 0 {
+		var conditions []string
+		var args []interface{}
+		for _, tag := range filter.Tags {
+			conditions = append(conditions, "tags LIKE ?")
+			args = append(args, "%"+tag+"%")
+		}
+		query = query.Where(strings.Join(conditions, " OR "), args...)
+	}
+
+	var matched []models.Asset
+	if err := query.Order("id ASC").Find(&matched).Error; err != nil {
+		return nil, fmt.Errorf("按条件查询目标资产失败: %v", err)
+	}
+
+	selected := make(map[uint]bool, len(assets))
+	for _, asset := range assets {
+		selected[asset.ID] = true
+	}
+	for _, asset := range matched {
+		if selected[asset.ID] || !assetHasAnyTag(&asset, filter.Tags) {
+			continue
+		}
+		selected[asset.ID] = true
+		assets = append(assets, asset)
+	}
+	return assets, nil
+}
+
+// parseScanTargetFilter 解析资产筛选条件，为空时返回空条件
+func parseScanTargetFilter(value string) (*models.ScanTargetFilter, error) {
+	filter := &models.ScanTargetFilter{}
+	if strings.TrimSpace(value) == "" {
+		return filter, nil
+	}
+	if err := json.Unmarshal([]byte(value), filter); err != nil {
+		return nil, fmt.Errorf("资产筛选条件不是有效的JSON: %v", err)
+	}
+	return filter, nil
+}
+
+// assetHasAnyTag 判断资产是否包含任一标签，未指定标签时总是满足
+func assetHasAnyTag(asset *models.Asset, tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	assetTags := make(map[string]bool)
+	for _, tag := range scanner.SplitList(asset.Tags) {
+		assetTags[strings.ToLower(tag)] = true
+	}
+	for _, tag := range tags {
+		if assetTags[strings.ToLower(strings.TrimSpace(tag))] {
+			return true
+		}
+	}
+	return false
+}
+
+// ScanTargetPreviewRequest 预览扫描目标的请求
+type ScanTargetPreviewRequest struct {
+	TargetIPs      string `json:"target_ips"`
+	TargetURLs     string `json:"target_urls"`
+	TargetAssets   string `json:"target_assets"`
+	TargetFilter   string `json:"target_filter"`
+	ExcludeTargets string `json:"exclude_targets"`
+}
+
+// PreviewScanTargets 预览未保存的目标配置解析后的扫描目标
+func (c *ScanController) PreviewScanTargets(ctx *gin.Context) {
+	var req ScanTargetPreviewRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	respondScanTargets(ctx, &models.ScanTask{
+		TargetIPs:      req.TargetIPs,
+		TargetURLs:     req.TargetURLs,
+		TargetAssets:   req.TargetAssets,
+		TargetFilter:   req.TargetFilter,
+		ExcludeTargets: req.ExcludeTargets,
+	})
+}
+
+// GetScanTargets 预览扫描任务在当前资产数据下解析出的扫描目标
+func (c *ScanController) GetScanTargets(ctx *gin.Context) {
+	var task models.ScanTask
+	if err := utils.DB.First(&task, ctx.Param("id")).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "扫描任务不存在",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取扫描任务失败: " + err.Error(),
+		})
+		return
+	}
+	respondScanTargets(ctx, &task)
+}
+
+// respondScanTargets 返回解析后的扫描目标，每类目标最多返回 scanTargetPreviewLimit 条
+func respondScanTargets(ctx *gin.Context, task *models.ScanTask) {
+	targets, err := resolveScanTargets(task)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "解析扫描目标失败: " + err.Error(),
+		})
+		return
+	}
+
+	summary := gin.H{
+		"ips":      len(targets.IPs),
+		"urls":     len(targets.URLs),
+		"assets":   len(targets.AssetIDs),
+		"excluded": len(targets.Excluded),
+		"total":    len(targets.IPs) + len(targets.URLs),
+	}
+	truncate := func(list []string) []string {
+		if len(list) > scanTargetPreviewLimit {
+			return list[:scanTargetPreviewLimit]
+		}
+		return list
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "success",
+		"data": gin.H{
+			"ips":       truncate(targets.IPs),
+			"urls":      truncate(targets.URLs),
+			"asset_ids": targets.AssetIDs,
+			"excluded":  truncate(targets.Excluded),
+			"warnings":  targets.Warnings,
+			"summary":   summary,
+		},
+	})
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/utils"
+)
+
+func TestResolveScanTargetsSkipsNonNetworkAssets(t *testing.T) {
+	useTestDB(t, &models.Asset{})
+	assets := []models.Asset{
+		{Name: "web", Type: models.AssetTypeHost, Identifier: "web01.example.com"},
+		{Name: "portal", Type: models.AssetTypeWebsite, Identifier: "https://portal.example.com"},
+		{Name: "nginx:1.25", Type: models.AssetTypeContainerImage, Identifier: "sha256:0123456789abcdef"},
+		{Name: "shop", Type: models.AssetTypeApplication, Identifier: "pkg:npm/shop"},
+		{Name: "api", Type: models.AssetTypeApplication, Identifier: "pkg:npm/api", URL: "https://api.example.com"},
+	}
+	for i := range assets {
+		assets[i].Status = models.AssetStatusActive
+		if err := utils.DB.Create(&assets[i]).Error; err != nil {
+			t.Fatalf("创建资产失败: %v", err)
+		}
+	}
+
+	targets, err := resolveScanTargets(&models.ScanTask{TargetFilter: `{"types": ["host", "website", "container_image", "application"]}`})
+	if err != nil {
+		t.Fatalf("解析扫描目标失败: %v", err)
+	}
+	if strings.Join(targets.IPs, ",") != "web01.example.com" {
+		t.Errorf("IP/主机 = %v", targets.IPs)
+	}
+	if strings.Join(targets.URLs, ",") != "https://portal.example.com,https://api.example.com" {
+		t.Errorf("URL = %v", targets.URLs)
+	}
+	if len(targets.AssetIDs) != 3 {
+		t.Errorf("资产 = %v", targets.AssetIDs)
+	}
+	if len(targets.Warnings) != 2 || !strings.Contains(targets.Warnings[0], "container_image") || !strings.Contains(targets.Warnings[1], "application") {
+		t.Errorf("提示 = %v", targets.Warnings)
+	}
+}
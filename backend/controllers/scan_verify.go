@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/utils"
+)
+
+// scanVerifyStats 自动复测统计
+type scanVerifyStats struct {
+	Fixed     int // 未再检测到而确认修复的漏洞数
+	Regressed int // 修复后再次出现而重新打开的漏洞数
+}
+
+// verifyScanFixes 根据本次执行的结果自动复测此前导入的漏洞
+//
+// 修复后再次被检测到的漏洞会重新打开并标记为回归；只有完整结束的执行才会确认修复：
+// 扫描覆盖了漏洞所在的资产或主机、但本次没有再检测到的已导入漏洞会标记为已修复。
+// 处于待复测状态的分发记录随复测结果关闭或退回。
+func verifyScanFixes(task *models.ScanTask, run *models.ScanRun, results []models.ScanResult, targets *scanTargets, assetIDs map[uint]bool) scanVerifyStats {
+	var stats scanVerifyStats
+	now := time.Now()
+
+	detected := make(map[string]bool, len(results))
+	for _, result := range results {
+		detected[result.Fingerprint] = true
+	}
+
+	// 回归：已修复的漏洞再次被检测到
+	if len(detected) > 0 {
+		fingerprints := make([]string, 0, len(detected))
+		for fp := range detected {
+			fingerprints = append(fingerprints, fp)
+		}
+
+		var regressed []models.Vulnerability
+		err := utils.DB.Where("fingerprint IN (?) AND status = ?", fingerprints, models.StatusFixed).Find(&regressed).Error
+		if err != nil {
+			log.Printf("查询已修复漏洞失败: task_id=%d, err=%v", task.ID, err)
+		}
+		for i := range regressed {
+			vuln := &regressed[i]
+			note := fmt.Sprintf("[%s] 扫描任务 #%d 执行 #%d 再次检测到该漏洞，已重新打开并标记为回归",
+				now.Format("2006-01-02 15:04:05"), task.ID, run.ID)
+			err := utils.DB.Model(vuln).Updates(map[string]interface{}{
+				"status":        models.StatusNew,
+				"is_regression": true,
+				"fixed_at":      nil,
+				"notes":         appendNote(vuln.Notes, note),
+			}).Error
+			if err != nil {
+				log.Printf("重新打开回归漏洞失败: vuln_id=%d, err=%v", vuln.ID, err)
+				continue
+			}
+			updateRetestAssignments(vuln.ID, models.AssignmentStatusAccepted, task.CreatedBy,
+				fmt.Sprintf("复测未通过：扫描任务 #%d 执行 #%d 再次检测到该漏洞", task.ID, run.ID))
+			stats.Regressed++
+		}
+	}
+
+	// 部分结果不能证明漏洞已修复
+	if task.Status != models.ScanTaskStatusCompleted {
+		return stats
+	}
+
+	// 修复：此前从本任务导入、仍未关闭且本次未再检测到的漏洞
+	var previous []models.ScanResult
+	err := utils.DB.Where("scan_task_id = ? AND scan_run_id <> ? AND is_imported = ? AND imported_id > 0", task.ID, run.ID, true).
+		Order("id DESC").
+		Find(&previous).Error
+	if err != nil {
+		log.Printf("查询已导入的扫描结果失败: task_id=%d, err=%v", task.ID, err)
+		return stats
+	}
+
+	hosts := scannedHosts(targets)
+	checked := make(map[uint]bool)
+	for _, result := range previous {
+		if checked[result.ImportedID] {
+			continue
+		}
+		checked[result.ImportedID] = true
+
+		var vuln models.Vulnerability
+		if err := utils.DB.Preload("Assets").First(&vuln, result.ImportedID).Error; err != nil {
+			continue
+		}
+		if vuln.IsFixed() || vuln.Status == models.StatusFalsePositive {
+			continue
+		}
+
+		fingerprint := vuln.Fingerprint
+		if fingerprint == "" {
+			fingerprint = result.Fingerprint
+		}
+		if fingerprint == "" || detected[fingerprint] {
+			continue
+		}
+		if !resultCovered(&result, &vuln, hosts, assetIDs) {
+			continue
+		}
+
+		note := fmt.Sprintf("[%s] 扫描任务 #%d 执行 #%d 未再检测到该漏洞，自动确认为已修复",
+			now.Format("2006-01-02 15:04:05"), task.ID, run.ID)
+		err := utils.DB.Model(&vuln).Updates(map[string]interface{}{
+			"status":   models.StatusFixed,
+			"fixed_at": now,
+			"notes":    appendNote(vuln.Notes, note),
+		}).Error
+		if err != nil {
+			log.Printf("自动确认漏洞修复失败: vuln_id=%d, err=%v", vuln.ID, err)
+			continue
+		}
+		updateRetestAssignments(vuln.ID, models.AssignmentStatusClosed, task.CreatedBy,
+			fmt.Sprintf("复测通过：扫描任务 #%d 执行 #%d 未再检测到该漏洞", task.ID, run.ID))
+		stats.Fixed++
+	}
+
+	return stats
+}
+
+// scannedHosts 本次扫描覆盖的主机：实际扫描的目标IP和主机名以及目标URL的主机名
+//
+// 不包括目标资产上被排除或因时间窗口跳过的IP和URL，这些主机上的漏洞不能按未检测到确认修复。
+func scannedHosts(targets *scanTargets) map[string]bool {
+	hosts := make(map[string]bool)
+	for _, host := range targets.hosts() {
+		hosts[strings.ToLower(host)] = true
+	}
+	return hosts
+}
+
+// resultCovered 判断漏洞是否在本次扫描范围内
+//
+// 原始结果带有IP或URL时以其主机是否被实际扫描为准，资产的部分目标被排除时不会误判；
+// 没有主机信息时按关联的资产是否被扫描判断。
+func resultCovered(result *models.ScanResult, vuln *models.Vulnerability, hosts map[string]bool, assetIDs map[uint]bool) bool {
+	ip := strings.ToLower(strings.TrimSpace(result.AffectedIP))
+	host := urlHost(result.AffectedURL)
+	if ip != "" || host != "" {
+		return (ip != "" && hosts[ip]) || (host != "" && hosts[host])
+	}
+	for _, asset := range vuln.Assets {
+		if assetIDs[asset.ID] {
+			return true
+		}
+	}
+	return false
+}
+
+// urlHost 返回URL中的小写主机名，无法解析时返回空字符串
+func urlHost(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// appendNote 在漏洞备注末尾追加一行
+func appendNote(notes, line string) string {
+	if strings.TrimSpace(notes) == "" {
+		return line
+	}
+	return strings.TrimRight(notes, "\n") + "\n" + line
+}
+
+// updateRetestAssignments 更新漏洞处于待复测状态的分发记录并记录历史
+func updateRetestAssignments(vulnID uint, status string, changedBy uint, comment string) {
+	var assignments []models.VulnerabilityAssignment
+	err := utils.DB.Where("vulnerability_id = ? AND status = ?", vulnID, models.AssignmentStatusPendingRetest).
+		Find(&assignments).Error
+	if err != nil {
+		log.Printf("查询待复测的分发记录失败: vuln_id=%d, err=%v", vulnID, err)
+		return
+	}
+
+	for _, assignment := range assignments {
+		now := time.Now()
+		err := utils.DB.Model(&assignment).Updates(map[string]interface{}{
+			"status":     status,
+			"updated_at": now,
+		}).Error
+		if err != nil {
+			log.Printf("更新分发记录状态失败: assignment_id=%d, err=%v", assignment.ID, err)
+			continue
+		}
+
+		history := models.VulnerabilityAssignmentHistory{
+			AssignmentID: assignment.ID,
+			Status:       status,
+			Comment:      comment,
+			ChangedByID:  changedBy,
+			CreatedAt:    now,
+		}
+		if err := utils.DB.Create(&history).Error; err != nil {
+			log.Printf("创建漏洞分配历史记录失败: %v", err)
+		}
+	}
+}
+
+// autoVerifyScanResults 按任务的自动复测策略处理本次执行的结果
+func autoVerifyScanResults(task *models.ScanTask, run *models.ScanRun, results []models.ScanResult, targets *scanTargets, assetIDs map[uint]bool) {
+	if !task.AutoVerify || run.ID == 0 {
+		return
+	}
+
+	stats := verifyScanFixes(task, run, results, targets, assetIDs)
+	log.Printf("自动复测完成: task_id=%d, run_id=%d, fixed=%d, regressed=%d",
+		task.ID, run.ID, stats.Fixed, stats.Regressed)
+}
@@ -0,0 +1,684 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/scanner"
+	"github.com/vulnark/vulnark/utils"
+)
+
+const (
+	scanWindowSearchHorizon = 8 * 24 * time.Hour // 查找下一个允许扫描时刻的范围，覆盖一周的重复时段
+	scanWindowRecheck       = time.Hour          // 范围内找不到允许扫描的时刻时重新检查的间隔
+)
+
+// ScanWindowController 扫描时间窗口控制器
+type ScanWindowController struct{}
+
+// ScanWindowRequest 创建/更新扫描时间窗口的请求
+type ScanWindowRequest struct {
+	Name        string                  `json:"name" binding:"required"`
+	Description string                  `json:"description"`
+	Kind        string                  `json:"kind" binding:"required"`
+	Enabled     *bool                   `json:"enabled"`
+	Timezone    string                  `json:"timezone"`
+	Slots       []models.ScanWindowSlot `json:"slots"`
+	StartAt     *time.Time              `json:"start_at"`
+	EndAt       *time.Time              `json:"end_at"`
+	AssetIDs    string                  `json:"asset_ids"`
+	AssetFilter string                  `json:"asset_filter"`
+}
+
+// apply 将请求内容写入时间窗口并校验
+func (r *ScanWindowRequest) apply(window *models.ScanWindow) error {
+	window.Name = r.Name
+	window.Description = r.Description
+	window.Kind = models.ScanWindowKind(r.Kind)
+	window.Timezone = strings.TrimSpace(r.Timezone)
+	window.StartAt = r.StartAt
+	window.EndAt = r.EndAt
+	window.AssetIDs = r.AssetIDs
+	window.AssetFilter = r.AssetFilter
+	if r.Enabled != nil {
+		window.Enabled = *r.Enabled
+	}
+
+	window.Slots = ""
+	if len(r.Slots) > 0 {
+		data, err := json.Marshal(r.Slots)
+		if err != nil {
+			return err
+		}
+		window.Slots = string(data)
+	}
+
+	if _, err := parseAssetIDs(window.AssetIDs); err != nil {
+		return err
+	}
+	filter, err := parseScanTargetFilter(window.AssetFilter)
+	if err != nil {
+		return err
+	}
+	if len(scanner.SplitList(window.AssetIDs)) == 0 && filter.IsEmpty() {
+		return errors.New("请指定关联的资产ID或资产筛选条件")
+	}
+	return window.Validate()
+}
+
+// ListScanWindows 获取扫描时间窗口列表，可通过 kind 过滤，返回各窗口当前是否生效
+func (c *ScanWindowController) ListScanWindows(ctx *gin.Context) {
+	var windows []models.ScanWindow
+	query := utils.DB.Order("name ASC")
+	if kind := ctx.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if err := query.Find(&windows).Error; err != nil {
+		log.Printf("获取扫描时间窗口列表失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取扫描时间窗口列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	now := time.Now()
+	data := make([]gin.H, 0, len(windows))
+	for i := range windows {
+		data = append(data, gin.H{
+			"window": windows[i],
+			"active": windows[i].Enabled && windows[i].Active(now),
+		})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取扫描时间窗口列表成功",
+		"data":    data,
+	})
+}
+
+// GetScanWindow 获取扫描时间窗口详情
+func (c *ScanWindowController) GetScanWindow(ctx *gin.Context) {
+	var window models.ScanWindow
+	if err := utils.DB.First(&window, ctx.Param("id")).Error; err != nil {
+		respondScanWindowNotFound(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取扫描时间窗口成功",
+		"data": gin.H{
+			"window": window,
+			"active": window.Enabled && window.Active(time.Now()),
+		},
+	})
+}
+
+// CreateScanWindow 创建扫描时间窗口
+func (c *ScanWindowController) CreateScanWindow(ctx *gin.Context) {
+	var req ScanWindowRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	window := models.ScanWindow{Enabled: true, CreatedBy: ctx.GetUint("user_id")}
+	if err := req.apply(&window); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := utils.DB.Create(&window).Error; err != nil {
+		log.Printf("创建扫描时间窗口失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "创建扫描时间窗口失败: " + err.Error(),
+		})
+		return
+	}
+	releaseWindowHolds()
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"code":    200,
+		"message": "扫描时间窗口创建成功",
+		"data":    window,
+	})
+}
+
+// UpdateScanWindow 更新扫描时间窗口，等待中的任务会按新的窗口重新检查
+func (c *ScanWindowController) UpdateScanWindow(ctx *gin.Context) {
+	var window models.ScanWindow
+	if err := utils.DB.First(&window, ctx.Param("id")).Error; err != nil {
+		respondScanWindowNotFound(ctx, err)
+		return
+	}
+
+	var req ScanWindowRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if err := req.apply(&window); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := utils.DB.Save(&window).Error; err != nil {
+		log.Printf("更新扫描时间窗口失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "更新扫描时间窗口失败: " + err.Error(),
+		})
+		return
+	}
+	releaseWindowHolds()
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "扫描时间窗口更新成功",
+		"data":    window,
+	})
+}
+
+// DeleteScanWindow 删除扫描时间窗口
+func (c *ScanWindowController) DeleteScanWindow(ctx *gin.Context) {
+	var window models.ScanWindow
+	if err := utils.DB.First(&window, ctx.Param("id")).Error; err != nil {
+		respondScanWindowNotFound(ctx, err)
+		return
+	}
+
+	if err := utils.DB.Delete(&window).Error; err != nil {
+		log.Printf("删除扫描时间窗口失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除扫描时间窗口失败: " + err.Error(),
+		})
+		return
+	}
+	releaseWindowHolds()
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "扫描时间窗口删除成功",
+	})
+}
+
+// windowBlock 因时间窗口暂不能扫描的资产
+type windowBlock struct {
+	Asset  models.Asset
+	Reason string
+}
+
+// scanWindowCheck 扫描目标对应资产的时间窗口检查结果
+type scanWindowCheck struct {
+	Blocked []windowBlock
+	windows map[uint][]*models.ScanWindow // 每个关联资产适用的窗口
+}
+
+// checkScanWindows 检查扫描目标对应的资产在 now 时刻是否允许扫描
+func checkScanWindows(targets *scanTargets, now time.Time) (*scanWindowCheck, error) {
+	check := &scanWindowCheck{windows: make(map[uint][]*models.ScanWindow)}
+
+	var windows []models.ScanWindow
+	if err := utils.DB.Where("enabled = ?", true).Find(&windows).Error; err != nil {
+		return nil, fmt.Errorf("查询扫描时间窗口失败: %v", err)
+	}
+	if len(windows) == 0 {
+		return check, nil
+	}
+
+	assetIDs := scannedAssetIDs(targets)
+	if len(assetIDs) == 0 {
+		return check, nil
+	}
+	ids := make([]uint, 0, len(assetIDs))
+	for id := range assetIDs {
+		ids = append(ids, id)
+	}
+	var assets []models.Asset
+	if err := utils.DB.Where("id IN (?)", ids).Order("id ASC").Find(&assets).Error; err != nil {
+		return nil, fmt.Errorf("查询目标资产失败: %v", err)
+	}
+
+	for _, asset := range assets {
+		var applied []*models.ScanWindow
+		for i := range windows {
+			if windowAppliesTo(&windows[i], &asset) {
+				applied = append(applied, &windows[i])
+			}
+		}
+		if len(applied) == 0 {
+			continue
+		}
+		check.windows[asset.ID] = applied
+
+		if reason := windowBlockReason(applied, now); reason != "" {
+			check.Blocked = append(check.Blocked, windowBlock{Asset: asset, Reason: reason})
+		}
+	}
+	return check, nil
+}
+
+// windowAppliesTo 判断窗口是否关联该资产
+func windowAppliesTo(window *models.ScanWindow, asset *models.Asset) bool {
+	ids, _ := parseAssetIDs(window.AssetIDs)
+	for _, id := range ids {
+		if id == asset.ID {
+			return true
+		}
+	}
+
+	filter, err := parseScanTargetFilter(window.AssetFilter)
+	if err != nil || filter.IsEmpty() {
+		return false
+	}
+	if len(filter.Types) > 0 && !containsAssetType(filter.Types, asset.Type) {
+		return false
+	}
+	if len(filter.Departments) > 0 && !containsString(filter.Departments, asset.Department) {
+		return false
+	}
+	return assetHasAnyTag(asset, filter.Tags)
+}
+
+// windowBlockReason 资产在 now 时刻不允许扫描的原因，允许扫描时返回空字符串
+//
+// 处于任一禁止扫描时段时不允许扫描；关联了维护窗口时只能在其中任一窗口内扫描。
+func windowBlockReason(windows []*models.ScanWindow, now time.Time) string {
+	var maintenance []string
+	inMaintenance := false
+	for _, window := range windows {
+		switch window.Kind {
+		case models.ScanWindowBlackout:
+			if window.Active(now) {
+				return fmt.Sprintf("处于禁止扫描时段「%s」", window.Name)
+			}
+		case models.ScanWindowMaintenance:
+			maintenance = append(maintenance, "「"+window.Name+"」")
+			if window.Active(now) {
+				inMaintenance = true
+			}
+		}
+	}
+	if len(maintenance) > 0 && !inMaintenance {
+		return "不在维护窗口" + strings.Join(maintenance, "、") + "内"
+	}
+	return ""
+}
+
+// nextAllowedAt 查找 now 之后指定资产全部允许扫描的最早时刻，范围内找不到时返回 nil
+func (c *scanWindowCheck) nextAllowedAt(assetIDs []uint, now time.Time) *time.Time {
+	seen := make(map[uint]bool)
+	var windows []*models.ScanWindow
+	for _, id := range assetIDs {
+		for _, window := range c.windows[id] {
+			if !seen[window.ID] {
+				seen[window.ID] = true
+				windows = append(windows, window)
+			}
+		}
+	}
+
+	// 一次性时间范围可能超出查找范围（如长期封网），其边界总是作为候选时刻
+	horizon := now.Add(scanWindowSearchHorizon)
+	var candidates []time.Time
+	for _, window := range windows {
+		candidates = append(candidates, window.Boundaries(now, horizon)...)
+		for _, t := range []*time.Time{window.StartAt, window.EndAt} {
+			if t != nil && t.After(horizon) {
+				candidates = append(candidates, window.Boundaries(t.Add(-time.Second), t.Add(scanWindowSearchHorizon))...)
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+
+	for _, candidate := range candidates {
+		allowed := true
+		for _, id := range assetIDs {
+			if windowBlockReason(c.windows[id], candidate) != "" {
+				allowed = false
+				break
+			}
+		}
+		if allowed {
+			next := candidate
+			return &next
+		}
+	}
+	return nil
+}
+
+// holdForScanWindows 出队前按时间窗口检查排队任务，返回 true 表示任务需要继续等待
+//
+// hold 策略下只要有目标资产不允许扫描就整体等待；split 策略下只要还有允许扫描的资产就继续执行，
+// 不允许扫描的资产在执行时由 applyScanWindows 拆分为新的排队任务。等待原因和下次检查时间记录在任务上。
+func holdForScanWindows(taskID uint, now time.Time) bool {
+	var task models.ScanTask
+	if err := utils.DB.First(&task, taskID).Error; err != nil {
+		return false
+	}
+	if task.WindowPolicy == models.ScanWindowPolicyIgnore {
+		return false
+	}
+
+	// 目标无法解析时交给执行流程记录失败
+	targets, err := resolveScanTargets(&task)
+	if err != nil {
+		return false
+	}
+	check, err := checkScanWindows(targets, now)
+	if err != nil {
+		log.Printf("检查扫描时间窗口失败: task_id=%d, err=%v", task.ID, err)
+		return false
+	}
+	if len(check.Blocked) == 0 {
+		return false
+	}
+
+	// 拆分：还有允许扫描的目标时本任务继续执行，执行时再按当时的窗口拆分，避免出队和执行之间窗口变化导致重复拆分或遗漏资产
+	remaining := len(targets.IPs) + len(targets.URLs) - len(excludeBlockedTargets(cloneScanTargets(targets), check))
+	if task.WindowPolicy == models.ScanWindowPolicySplit && remaining > 0 {
+		return false
+	}
+
+	// 拆分策略在任一资产允许扫描时即可执行，等待策略需要所有资产同时允许扫描
+	split := task.WindowPolicy == models.ScanWindowPolicySplit
+	reason, until := windowWaitReason(check, now, split)
+	if !split && until == nil && len(check.windows) > 1 {
+		reason += "；目标资产的时间窗口没有重叠，可改用拆分策略（split）"
+	}
+	holdScanTask(&task, reason, until, now)
+	return true
+}
+
+// windowWaitReason 生成等待原因和下次检查时间，earliest 为 true 时取任一暂缓资产允许扫描的最早时刻
+func windowWaitReason(check *scanWindowCheck, now time.Time, earliest bool) (string, *time.Time) {
+	var parts []string
+	for i, block := range check.Blocked {
+		if i == 5 {
+			parts = append(parts, fmt.Sprintf("等%d个资产", len(check.Blocked)))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("资产 #%d %s %s", block.Asset.ID, block.Asset.Name, block.Reason))
+	}
+	reason := "等待扫描时间窗口: " + strings.Join(parts, "；")
+
+	if !earliest {
+		ids := make([]uint, 0, len(check.windows))
+		for id := range check.windows {
+			ids = append(ids, id)
+		}
+		return reason, check.nextAllowedAt(ids, now)
+	}
+
+	var until *time.Time
+	for _, block := range check.Blocked {
+		next := check.nextAllowedAt([]uint{block.Asset.ID}, now)
+		if next != nil && (until == nil || next.Before(*until)) {
+			until = next
+		}
+	}
+	return reason, until
+}
+
+// holdScanTask 记录任务的等待原因，until 之前不再出队
+func holdScanTask(task *models.ScanTask, reason string, until *time.Time, now time.Time) {
+	recheck := until
+	if recheck == nil {
+		next := now.Add(scanWindowRecheck)
+		recheck = &next
+		reason += "，暂未找到可扫描的时间，将定期重新检查"
+	} else {
+		reason += "，预计 " + recheck.Format("2006-01-02 15:04") + " 开始"
+	}
+
+	err := utils.DB.Model(&models.ScanTask{}).
+		Where("id = ? AND status = ?", task.ID, models.ScanTaskStatusQueued).
+		Updates(map[string]interface{}{
+			"wait_reason": reason,
+			"wait_until":  recheck,
+		}).Error
+	if err != nil {
+		log.Printf("记录扫描任务等待原因失败: task_id=%d, err=%v", task.ID, err)
+		return
+	}
+	if task.WaitReason != reason {
+		log.Printf("扫描任务等待时间窗口: task_id=%d, %s", task.ID, reason)
+	}
+}
+
+// splitScanTask 为不允许扫描的资产创建排队等待的新任务，创建失败时返回 nil
+func splitScanTask(task *models.ScanTask, check *scanWindowCheck, now time.Time) *models.ScanTask {
+	parentID := task.ID
+	if task.ParentTaskID != 0 {
+		parentID = task.ParentTaskID
+	}
+
+	ids := make([]string, 0, len(check.Blocked))
+	for _, block := range check.Blocked {
+		ids = append(ids, strconv.FormatUint(uint64(block.Asset.ID), 10))
+	}
+
+	child := models.ScanTask{
+		Name:                  task.Name + "（时间窗口拆分）",
+		Description:           fmt.Sprintf("由扫描任务 #%d 按时间窗口拆分", task.ID),
+		Type:                  task.Type,
+		Status:                models.ScanTaskStatusQueued,
+		Priority:              task.Priority,
+		QueuedAt:              &now,
+		QueueTrigger:          models.ScanTriggerScheduled,
+		ScannerURL:            task.ScannerURL,
+		ScannerAPIKey:         task.ScannerAPIKey,
+		ScannerUsername:       task.ScannerUsername,
+		ScannerPassword:       task.ScannerPassword,
+		CustomScannerID:       task.CustomScannerID,
+		ScannerProfileID:      task.ScannerProfileID,
+		AgentZone:             task.AgentZone,
+		WindowPolicy:          models.ScanWindowPolicySplit,
+		ParentTaskID:          parentID,
+		MaxRetries:            task.MaxRetries,
+		RetryBackoff:          task.RetryBackoff,
+		TargetAssets:          strings.Join(ids, ","),
+		ExcludeTargets:        task.ExcludeTargets,
+		ScanParameters:        task.ScanParameters,
+		AutoImport:            task.AutoImport,
+		AutoImportMinSeverity: task.AutoImportMinSeverity,
+		AutoVerify:            task.AutoVerify,
+		CreatedBy:             task.CreatedBy,
+	}
+	if err := utils.DB.Create(&child).Error; err != nil {
+		log.Printf("按时间窗口拆分扫描任务失败: task_id=%d, err=%v", task.ID, err)
+		return nil
+	}
+
+	reason, until := windowWaitReason(check, now, true)
+	holdScanTask(&child, reason, until, now)
+	log.Printf("扫描任务按时间窗口拆分: task_id=%d, child_task_id=%d, 暂缓资产=%s", task.ID, child.ID, child.TargetAssets)
+	return &child
+}
+
+// applyScanWindows 执行前从扫描目标中去掉当前不允许扫描的资产，并把这些资产拆分为排队等待的新任务
+//
+// 出队后窗口才关闭的资产同样会拆分出去，不论任务使用哪种策略，保证每个暂缓的资产之后都会被扫描。
+func applyScanWindows(task *models.ScanTask, targets *scanTargets) {
+	now := time.Now()
+	check := filterScanWindows(task, targets, now)
+	if check == nil || len(check.Blocked) == 0 {
+		return
+	}
+	if child := splitScanTask(task, check, now); child != nil {
+		targets.Warnings = append(targets.Warnings, fmt.Sprintf("暂缓扫描的 %d 个资产已拆分为扫描任务 #%d", len(check.Blocked), child.ID))
+	}
+}
+
+// filterScanWindows 从扫描目标中去掉 now 时刻不允许扫描的资产，返回检查结果，不检查时间窗口时返回 nil
+func filterScanWindows(task *models.ScanTask, targets *scanTargets, now time.Time) *scanWindowCheck {
+	if task.WindowPolicy == models.ScanWindowPolicyIgnore {
+		return nil
+	}
+	check, err := checkScanWindows(targets, now)
+	if err != nil {
+		log.Printf("检查扫描时间窗口失败: task_id=%d, err=%v", task.ID, err)
+		return nil
+	}
+	for _, excluded := range excludeBlockedTargets(targets, check) {
+		targets.Excluded = append(targets.Excluded, excluded)
+	}
+	for _, block := range check.Blocked {
+		targets.Warnings = append(targets.Warnings, fmt.Sprintf("资产 #%d %s %s，本次不扫描", block.Asset.ID, block.Asset.Name, block.Reason))
+	}
+	return check
+}
+
+// excludeBlockedTargets 去掉不允许扫描的资产及其对应的IP、主机名和URL，返回被去掉的目标
+func excludeBlockedTargets(targets *scanTargets, check *scanWindowCheck) []string {
+	if len(check.Blocked) == 0 {
+		return nil
+	}
+
+	blocked := make(map[uint]bool)
+	hosts := make(map[string]bool)
+	urls := make(map[string]bool)
+	for _, block := range check.Blocked {
+		asset := block.Asset
+		blocked[asset.ID] = true
+		for _, value := range []string{asset.IPAddress, asset.Identifier} {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			if strings.Contains(value, "://") {
+				urls[strings.TrimRight(value, "/")] = true
+			} else {
+				hosts[strings.ToLower(value)] = true
+			}
+		}
+		if u := strings.TrimSpace(asset.URL); u != "" {
+			urls[strings.TrimRight(u, "/")] = true
+		}
+	}
+
+	var removed []string
+	ips := targets.IPs[:0]
+	for _, ip := range targets.IPs {
+		if hosts[strings.ToLower(ip)] {
+			removed = append(removed, ip)
+			continue
+		}
+		ips = append(ips, ip)
+	}
+	targets.IPs = ips
+
+	kept := targets.URLs[:0]
+	for _, target := range targets.URLs {
+		if urls[strings.TrimRight(target, "/")] || hosts[urlHost(target)] {
+			removed = append(removed, target)
+			continue
+		}
+		kept = append(kept, target)
+	}
+	targets.URLs = kept
+
+	assetIDs := targets.AssetIDs[:0]
+	for _, id := range targets.AssetIDs {
+		if !blocked[id] {
+			assetIDs = append(assetIDs, id)
+		}
+	}
+	targets.AssetIDs = assetIDs
+	return removed
+}
+
+// cloneScanTargets 复制扫描目标，避免修改原列表
+func cloneScanTargets(targets *scanTargets) *scanTargets {
+	clone := *targets
+	clone.IPs = append([]string(nil), targets.IPs...)
+	clone.URLs = append([]string(nil), targets.URLs...)
+	clone.AssetIDs = append([]uint(nil), targets.AssetIDs...)
+	return &clone
+}
+
+// releaseWindowHolds 时间窗口变化后让等待中的任务在下次调度时重新检查
+func releaseWindowHolds() {
+	err := utils.DB.Model(&models.ScanTask{}).
+		Where("status = ? AND wait_until IS NOT NULL", models.ScanTaskStatusQueued).
+		UpdateColumn("wait_until", nil).Error
+	if err != nil {
+		log.Printf("重置扫描任务等待时间失败: %v", err)
+	}
+	notifyScanPool()
+}
+
+// parseAssetIDs 解析逗号分隔的资产ID列表
+func parseAssetIDs(value string) ([]uint, error) {
+	var ids []uint
+	for _, item := range scanner.SplitList(value) {
+		id, err := strconv.ParseUint(item, 10, 64)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("无效的资产ID: %s", item)
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+// containsAssetType 资产类型是否在列表中
+func containsAssetType(types []models.AssetType, assetType models.AssetType) bool {
+	for _, t := range types {
+		if t == assetType {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString 字符串是否在列表中
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// respondScanWindowNotFound 返回查询扫描时间窗口失败的响应
+func respondScanWindowNotFound(ctx *gin.Context, err error) {
+	if gorm.IsRecordNotFoundError(err) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "扫描时间窗口不存在",
+		})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{
+		"code":    500,
+		"message": "获取扫描时间窗口失败: " + err.Error(),
+	})
+}
@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/utils"
+)
+
+func TestCreateScanWindowDisabled(t *testing.T) {
+	useTestDB(t, &models.ScanWindow{}, &models.ScanTask{})
+	controller := &ScanWindowController{}
+
+	for _, enabled := range []bool{false, true} {
+		w := performJSON(controller.CreateScanWindow, http.MethodPost, "/api/v1/admin/scan-windows", gin.H{
+			"name":      fmt.Sprintf("blackout-%v", enabled),
+			"kind":      models.ScanWindowBlackout,
+			"slots":     []models.ScanWindowSlot{{Start: "00:00", End: "06:00"}},
+			"asset_ids": "1,2",
+			"enabled":   enabled,
+		})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("enabled=%v: 状态码 = %d, body = %s", enabled, w.Code, w.Body.String())
+		}
+
+		var resp struct {
+			Data models.ScanWindow `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+		var stored models.ScanWindow
+		if err := utils.DB.First(&stored, resp.Data.ID).Error; err != nil {
+			t.Fatalf("读取扫描时间窗口失败: %v", err)
+		}
+		if resp.Data.Enabled != enabled || stored.Enabled != enabled {
+			t.Errorf("enabled=%v: 响应中为 %v，数据库中为 %v", enabled, resp.Data.Enabled, stored.Enabled)
+		}
+	}
+}
+
+// setupWindowTask 创建两个主机资产和扫描它们的排队任务
+func setupWindowTask(t *testing.T, policy models.ScanWindowPolicy) *models.ScanTask {
+	t.Helper()
+	useTestDB(t, &models.Asset{}, &models.ScanWindow{}, &models.ScanTask{})
+	for i, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		asset := models.Asset{Name: fmt.Sprintf("host-%d", i+1), Type: models.AssetTypeHost, Identifier: ip, IPAddress: ip}
+		if err := utils.DB.Create(&asset).Error; err != nil {
+			t.Fatalf("创建资产失败: %v", err)
+		}
+	}
+	task := models.ScanTask{
+		Name:         "window",
+		Type:         models.ScannerTypeNessus,
+		Status:       models.ScanTaskStatusQueued,
+		TargetAssets: "1,2",
+		WindowPolicy: policy,
+	}
+	if err := utils.DB.Create(&task).Error; err != nil {
+		t.Fatalf("创建扫描任务失败: %v", err)
+	}
+	return &task
+}
+
+// blockAsset 创建当前生效的禁止扫描时段
+func blockAsset(t *testing.T, assetID uint) {
+	t.Helper()
+	start, end := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	window := models.ScanWindow{
+		Name:     "freeze",
+		Kind:     models.ScanWindowBlackout,
+		Enabled:  true,
+		StartAt:  &start,
+		EndAt:    &end,
+		AssetIDs: fmt.Sprint(assetID),
+	}
+	if err := utils.DB.Create(&window).Error; err != nil {
+		t.Fatalf("创建扫描时间窗口失败: %v", err)
+	}
+}
+
+// splitChildren 查询拆分出的子任务
+func splitChildren(t *testing.T, parentID uint) []models.ScanTask {
+	t.Helper()
+	var children []models.ScanTask
+	if err := utils.DB.Where("parent_task_id = ?", parentID).Find(&children).Error; err != nil {
+		t.Fatalf("查询子任务失败: %v", err)
+	}
+	return children
+}
+
+func TestApplyScanWindowsSplitsOnce(t *testing.T) {
+	task := setupWindowTask(t, models.ScanWindowPolicySplit)
+	blockAsset(t, 2)
+
+	if holdForScanWindows(task.ID, time.Now()) {
+		t.Fatal("还有允许扫描的资产时 split 策略不应等待")
+	}
+	if children := splitChildren(t, task.ID); len(children) != 0 {
+		t.Fatalf("出队时不应拆分任务，实际拆分出 %d 个", len(children))
+	}
+
+	targets, err := resolveScanTargets(task)
+	if err != nil {
+		t.Fatalf("解析扫描目标失败: %v", err)
+	}
+	applyScanWindows(task, targets)
+	if len(targets.IPs) != 1 || targets.IPs[0] != "10.0.0.1" || len(targets.AssetIDs) != 1 || targets.AssetIDs[0] != 1 {
+		t.Errorf("扫描目标 = %v, 资产 = %v", targets.IPs, targets.AssetIDs)
+	}
+	children := splitChildren(t, task.ID)
+	if len(children) != 1 || children[0].TargetAssets != "2" || children[0].Status != models.ScanTaskStatusQueued {
+		t.Fatalf("拆分出的子任务 = %+v", children)
+	}
+	if children[0].WaitUntil == nil {
+		t.Error("子任务应等待时间窗口")
+	}
+}
+
+func TestApplyScanWindowsClosedAfterDispatch(t *testing.T) {
+	task := setupWindowTask(t, models.ScanWindowPolicyHold)
+	if holdForScanWindows(task.ID, time.Now()) {
+		t.Fatal("没有时间窗口时不应等待")
+	}
+
+	// 出队后、执行前进入禁止扫描时段
+	blockAsset(t, 2)
+	targets, err := resolveScanTargets(task)
+	if err != nil {
+		t.Fatalf("解析扫描目标失败: %v", err)
+	}
+	applyScanWindows(task, targets)
+	if len(targets.IPs) != 1 || targets.IPs[0] != "10.0.0.1" {
+		t.Errorf("扫描目标 = %v", targets.IPs)
+	}
+	if children := splitChildren(t, task.ID); len(children) != 1 || children[0].TargetAssets != "2" {
+		t.Fatalf("执行前被暂缓的资产应拆分为新任务，实际为 %+v", children)
+	}
+}
@@ -0,0 +1,317 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/scanner"
+	"github.com/vulnark/vulnark/utils"
+)
+
+// scannerProfileTestTimeout 测试扫描器连接的超时时间
+const scannerProfileTestTimeout = 30 * time.Second
+
+// ScannerProfileController 扫描器连接配置控制器
+type ScannerProfileController struct{}
+
+// ScannerProfileRequest 创建/更新扫描器连接配置的请求
+//
+// 查询接口不返回凭据，因此更新时 api_key、password 未传表示保持原值，传空字符串表示清除。
+type ScannerProfileRequest struct {
+	Name              string  `json:"name" binding:"required"`
+	Description       string  `json:"description"`
+	Type              string  `json:"type" binding:"required"`
+	ScannerURL        string  `json:"scanner_url"`
+	APIKey            *string `json:"api_key"`
+	Username          string  `json:"username"`
+	Password          *string `json:"password"`
+	VerifyTLS         bool    `json:"verify_tls"`
+	DefaultParameters string  `json:"default_parameters"`
+}
+
+// apply 将请求内容写入连接配置并校验
+func (r *ScannerProfileRequest) apply(profile *models.ScannerProfile) error {
+	scannerType := models.ScannerType(r.Type)
+	switch scannerType {
+	case models.ScannerTypeNessus, models.ScannerTypeXray, models.ScannerTypeAwvs, models.ScannerTypeZap:
+	default:
+		return errors.New("不支持的扫描器类型: " + r.Type)
+	}
+
+	profile.Name = r.Name
+	profile.Description = r.Description
+	profile.Type = scannerType
+	profile.ScannerURL = r.ScannerURL
+	profile.Username = r.Username
+	profile.VerifyTLS = r.VerifyTLS
+	profile.DefaultParameters = r.DefaultParameters
+	if r.APIKey != nil {
+		profile.APIKey = *r.APIKey
+	}
+	if r.Password != nil {
+		profile.Password = *r.Password
+	}
+
+	// 校验默认参数
+	_, err := scanner.NewProfileJob(profile)
+	return err
+}
+
+// ListScannerProfiles 获取扫描器连接配置列表，可通过 type 过滤
+func (c *ScannerProfileController) ListScannerProfiles(ctx *gin.Context) {
+	var profiles []models.ScannerProfile
+	query := utils.DB.Order("name ASC")
+	if scannerType := ctx.Query("type"); scannerType != "" {
+		query = query.Where("type = ?", scannerType)
+	}
+	if err := query.Find(&profiles).Error; err != nil {
+		log.Printf("获取扫描器连接配置列表失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取扫描器连接配置列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	for i := range profiles {
+		profiles[i].HideCredentials()
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取扫描器连接配置列表成功",
+		"data":    profiles,
+	})
+}
+
+// GetScannerProfile 获取扫描器连接配置详情
+func (c *ScannerProfileController) GetScannerProfile(ctx *gin.Context) {
+	var profile models.ScannerProfile
+	if err := utils.DB.First(&profile, ctx.Param("id")).Error; err != nil {
+		respondScannerProfileNotFound(ctx, err)
+		return
+	}
+
+	profile.HideCredentials()
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取扫描器连接配置成功",
+		"data":    profile,
+	})
+}
+
+// CreateScannerProfile 创建扫描器连接配置
+func (c *ScannerProfileController) CreateScannerProfile(ctx *gin.Context) {
+	var req ScannerProfileRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	profile := models.ScannerProfile{CreatedBy: ctx.GetUint("user_id")}
+	if err := req.apply(&profile); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := utils.DB.Create(&profile).Error; err != nil {
+		log.Printf("创建扫描器连接配置失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "创建扫描器连接配置失败: " + err.Error(),
+		})
+		return
+	}
+
+	profile.HideCredentials()
+	ctx.JSON(http.StatusCreated, gin.H{
+		"code":    200,
+		"message": "扫描器连接配置创建成功",
+		"data":    profile,
+	})
+}
+
+// UpdateScannerProfile 更新扫描器连接配置，引用该配置的扫描任务在下次执行时使用新的配置
+func (c *ScannerProfileController) UpdateScannerProfile(ctx *gin.Context) {
+	var profile models.ScannerProfile
+	if err := utils.DB.First(&profile, ctx.Param("id")).Error; err != nil {
+		respondScannerProfileNotFound(ctx, err)
+		return
+	}
+
+	var req ScannerProfileRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 已被任务引用时不允许修改类型，否则任务会用错误的驱动连接扫描器
+	if models.ScannerType(req.Type) != profile.Type {
+		if count := scannerProfileUsage(profile.ID); count > 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": fmt.Sprintf("该连接配置仍被%d个扫描任务使用，不能修改扫描器类型", count),
+			})
+			return
+		}
+	}
+
+	if err := req.apply(&profile); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := utils.DB.Save(&profile).Error; err != nil {
+		log.Printf("更新扫描器连接配置失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "更新扫描器连接配置失败: " + err.Error(),
+		})
+		return
+	}
+
+	profile.HideCredentials()
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "扫描器连接配置更新成功",
+		"data":    profile,
+	})
+}
+
+// DeleteScannerProfile 删除扫描器连接配置，仍被扫描任务引用时不允许删除
+func (c *ScannerProfileController) DeleteScannerProfile(ctx *gin.Context) {
+	var profile models.ScannerProfile
+	if err := utils.DB.First(&profile, ctx.Param("id")).Error; err != nil {
+		respondScannerProfileNotFound(ctx, err)
+		return
+	}
+
+	if count := scannerProfileUsage(profile.ID); count > 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "该连接配置仍被扫描任务使用，请先修改相关任务",
+		})
+		return
+	}
+
+	if err := utils.DB.Delete(&profile).Error; err != nil {
+		log.Printf("删除扫描器连接配置失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除扫描器连接配置失败: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "扫描器连接配置删除成功",
+	})
+}
+
+// TestScannerProfile 使用连接配置连接扫描器并校验凭据
+func (c *ScannerProfileController) TestScannerProfile(ctx *gin.Context) {
+	var profile models.ScannerProfile
+	if err := utils.DB.First(&profile, ctx.Param("id")).Error; err != nil {
+		respondScannerProfileNotFound(ctx, err)
+		return
+	}
+
+	job, err := scanner.NewProfileJob(&profile)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	testCtx, cancel := context.WithTimeout(ctx.Request.Context(), scannerProfileTestTimeout)
+	defer cancel()
+
+	started := time.Now()
+	info, err := scanner.TestConnection(testCtx, job)
+	elapsed := time.Since(started).Milliseconds()
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, scanner.ErrConnectionTestUnsupported) {
+			status = http.StatusBadRequest
+		}
+		ctx.JSON(status, gin.H{
+			"code":    status,
+			"message": "连接测试失败: " + err.Error(),
+			"data": gin.H{
+				"success":    false,
+				"error":      err.Error(),
+				"elapsed_ms": elapsed,
+			},
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "连接测试成功",
+		"data": gin.H{
+			"success":    true,
+			"info":       info,
+			"elapsed_ms": elapsed,
+		},
+	})
+}
+
+// scannerProfileUsage 引用连接配置的扫描任务数
+func scannerProfileUsage(id uint) int {
+	var count int
+	utils.DB.Model(&models.ScanTask{}).Where("scanner_profile_id = ?", id).Count(&count)
+	return count
+}
+
+// checkScannerProfile 检查扫描器连接配置是否存在且与任务的扫描器类型一致
+func checkScannerProfile(id uint, scannerType models.ScannerType) error {
+	var profile models.ScannerProfile
+	if err := utils.DB.Select("id, type").First(&profile, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return fmt.Errorf("扫描器连接配置不存在: %d", id)
+		}
+		return err
+	}
+	if profile.Type != scannerType {
+		return fmt.Errorf("扫描器连接配置的类型(%s)与任务的扫描器类型(%s)不一致", profile.Type, scannerType)
+	}
+	return nil
+}
+
+// respondScannerProfileNotFound 返回查询扫描器连接配置失败的响应
+func respondScannerProfileNotFound(ctx *gin.Context, err error) {
+	if gorm.IsRecordNotFoundError(err) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "扫描器连接配置不存在",
+		})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{
+		"code":    500,
+		"message": "获取扫描器连接配置失败: " + err.Error(),
+	})
+}
@@ -451,8 +451,16 @@ func (sc *SettingsController) SaveSettings(c *gin.Context) {
 	requestData.UpdatedBy = userID.(uint)
 	requestData.UpdatedAt = time.Now()
 
+	// 密钥加密后保存，返回给客户端的仍是请求中的明文
+	stored := requestData
+	if err := stored.EncryptSecrets(); err != nil {
+		log.Printf("SaveSettings错误: 加密设置中的密钥失败 - %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "加密设置失败"})
+		return
+	}
+
 	// 手动序列化JSON字段
-	integrationsJSON, err := json.Marshal(requestData.Integrations)
+	integrationsJSON, err := json.Marshal(stored.Integrations)
 	if err != nil {
 		log.Printf("SaveSettings错误: 序列化集成设置失败 - %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "序列化数据失败"})
@@ -460,7 +468,7 @@ func (sc *SettingsController) SaveSettings(c *gin.Context) {
 	}
 	log.Printf("SaveSettings: 序列化集成设置成功, JSON长度=%d", len(integrationsJSON))
 
-	notificationsJSON, err := json.Marshal(requestData.Notifications)
+	notificationsJSON, err := json.Marshal(stored.Notifications)
 	if err != nil {
 		log.Printf("SaveSettings错误: 序列化通知设置失败 - %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "序列化数据失败"})
@@ -468,7 +476,7 @@ func (sc *SettingsController) SaveSettings(c *gin.Context) {
 	}
 	log.Printf("SaveSettings: 序列化通知设置成功, JSON长度=%d", len(notificationsJSON))
 
-	aiJSON, err := json.Marshal(requestData.AI)
+	aiJSON, err := json.Marshal(stored.AI)
 	if err != nil {
 		log.Printf("SaveSettings错误: 序列化AI设置失败 - %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "序列化数据失败"})
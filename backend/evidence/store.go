@@ -0,0 +1,103 @@
+// Package evidence 在本地文件系统保存扫描结果和漏洞的证据内容
+//
+// 内容按 SHA-256 寻址，保存在 {upload.location}/evidence/{前两位}/{SHA-256} 下，
+// 相同内容只保存一份，元数据（类型、大小、摘要等）保存在数据库的 evidences 表中。
+package evidence
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+)
+
+// defaultMaxSize 单个证据默认的大小上限
+const defaultMaxSize = 10 << 20
+
+var (
+	// ErrTooLarge 证据内容超过大小上限
+	ErrTooLarge = errors.New("证据内容超过大小上限")
+	// ErrInvalidDigest 无效的 SHA-256 摘要
+	ErrInvalidDigest = errors.New("无效的证据摘要")
+)
+
+// MaxSize 单个证据的大小上限，通过 upload.evidence_max_size（MB）配置
+func MaxSize() int64 {
+	if mb := viper.GetInt64("upload.evidence_max_size"); mb > 0 {
+		return mb << 20
+	}
+	return defaultMaxSize
+}
+
+// Save 保存证据内容，返回内容的 SHA-256，内容已存在时不重复写入
+func Save(data []byte) (string, error) {
+	if int64(len(data)) > MaxSize() {
+		return "", fmt.Errorf("%w: %d 字节", ErrTooLarge, len(data))
+	}
+
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	path, _ := Path(digest)
+	if _, err := os.Stat(path); err == nil {
+		return digest, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return "", fmt.Errorf("创建证据目录失败: %v", err)
+	}
+	// 先写临时文件再重命名，避免并发写入或中断时留下不完整的内容
+	tmp, err := os.CreateTemp(filepath.Dir(path), digest+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("创建证据文件失败: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("写入证据文件失败: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("写入证据文件失败: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("保存证据文件失败: %v", err)
+	}
+	return digest, nil
+}
+
+// Open 打开证据内容
+func Open(digest string) (*os.File, error) {
+	path, err := Path(digest)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Remove 删除证据内容，内容不存在时不报错
+func Remove(digest string) error {
+	path, err := Path(digest)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Path 证据内容的文件路径，摘要必须是64位小写十六进制
+func Path(digest string) (string, error) {
+	if len(digest) != sha256.Size*2 {
+		return "", ErrInvalidDigest
+	}
+	for _, c := range digest {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return "", ErrInvalidDigest
+		}
+	}
+	return filepath.Join(viper.GetString("upload.location"), "evidence", digest[:2], digest), nil
+}
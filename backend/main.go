@@ -8,8 +8,11 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
+	"github.com/vulnark/vulnark/controllers"
 	"github.com/vulnark/vulnark/models"
 	"github.com/vulnark/vulnark/routes"
+	"github.com/vulnark/vulnark/scanner"
+	"github.com/vulnark/vulnark/secret"
 	"github.com/vulnark/vulnark/utils"
 )
 
@@ -46,16 +49,37 @@ func init() {
 }
 
 func main() {
+	// 校验加密主密钥配置
+	if err := secret.Validate(); err != nil {
+		log.Fatalf("加密主密钥配置错误: %v", err)
+	}
+
 	// 初始化数据库连接
 	utils.InitDB()
 	defer utils.CloseDB()
 
-	// 自动迁移数据库模型
+	// 自动迁移数据库模型，并加密已有的明文敏感字段
 	autoMigrateModels()
+	utils.MigrateSecrets()
 
 	// 创建默认管理员账户
 	createDefaultAdmin()
 
+	// 演示环境可为尚未接入真实驱动的扫描器类型启用模拟驱动
+	if viper.GetBool("scan.mock_driver") {
+		scanner.EnableMockDriver()
+		log.Printf("已启用模拟扫描器驱动，相关类型的扫描结果为示例数据")
+	}
+
+	// 启动扫描工作池和扫描任务调度器
+	scanPool := controllers.NewScanWorkerPool()
+	scanPool.Start()
+	defer scanPool.Stop()
+
+	scanScheduler := controllers.NewScanScheduler()
+	scanScheduler.Start()
+	defer scanScheduler.Stop()
+
 	// 创建Gin路由
 	router := gin.Default()
 
@@ -89,6 +113,15 @@ func autoMigrateModels() {
 			&models.VulnerabilityAssignmentHistory{},
 			&models.ScanTask{},
 			&models.ScanResult{},
+			&models.ScanRun{},
+			&models.ScanEvent{},
+			&models.CustomScanner{},
+			&models.ScannerProfile{},
+			&models.ScanAgent{},
+			&models.ScanWindow{},
+			&models.Evidence{},
+			&models.SBOM{},
+			&models.SBOMComponent{},
 			&models.CIIntegration{},
 			&models.IntegrationHistory{},
 		)
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/utils"
+)
+
+// AgentAuthMiddleware 扫描代理认证中间件，通过 Authorization: Bearer {代理令牌} 认证
+//
+// 认证通过后将代理信息存储在上下文的 agent 中，并记录代理的最近访问时间和来源地址。
+func AgentAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+		if len(parts) != 2 || parts[0] != "Bearer" || parts[1] == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "未提供扫描代理令牌",
+			})
+			c.Abort()
+			return
+		}
+
+		var agent models.ScanAgent
+		if err := utils.DB.Where("token_hash = ?", models.HashAgentToken(parts[1])).First(&agent).Error; err != nil {
+			log.Printf("扫描代理认证失败: ip=%s, err=%v", c.ClientIP(), err)
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "无效的扫描代理令牌",
+			})
+			c.Abort()
+			return
+		}
+		if !agent.Enabled {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "扫描代理已停用",
+			})
+			c.Abort()
+			return
+		}
+
+		now := time.Now()
+		agent.LastSeenAt = &now
+		agent.IPAddress = c.ClientIP()
+		utils.DB.Model(&models.ScanAgent{}).Where("id = ?", agent.ID).UpdateColumns(map[string]interface{}{
+			"last_seen_at": agent.LastSeenAt,
+			"ip_address":   agent.IPAddress,
+		})
+
+		c.Set("agent", &agent)
+		c.Next()
+	}
+}
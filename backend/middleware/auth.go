@@ -20,11 +20,28 @@ type Claims struct {
 	jwt.StandardClaims
 }
 
+// queryTokenRoutes 允许通过 token 查询参数认证的 SSE 接口
+var queryTokenRoutes = map[string]bool{
+	"/api/v1/scans/:id/stream": true,
+}
+
+// allowQueryToken 判断请求是否为允许通过查询参数传递令牌的 SSE 请求
+func allowQueryToken(c *gin.Context) bool {
+	return c.Request.Method == http.MethodGet &&
+		queryTokenRoutes[c.FullPath()] &&
+		strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+}
+
 // JWTAuthMiddleware JWT认证中间件
 func JWTAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取Authorization头
 		authHeader := c.GetHeader("Authorization")
+		// 浏览器的 EventSource 无法设置请求头，仅扫描事件流接口允许通过 token 查询参数传递，
+		// 避免令牌出现在其他接口的URL和访问日志中
+		if authHeader == "" && allowQueryToken(c) && c.Query("token") != "" {
+			authHeader = "Bearer " + c.Query("token")
+		}
 		if authHeader == "" {
 			log.Printf("JWT认证失败: 请求中缺少Authorization头部")
 			c.JSON(http.StatusUnauthorized, gin.H{
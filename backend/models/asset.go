@@ -8,15 +8,16 @@ import (
 type AssetType string
 
 const (
-	AssetTypeHost        AssetType = "host"        // 主机
-	AssetTypeWebsite     AssetType = "website"     // 网站
-	AssetTypeDatabase    AssetType = "database"    // 数据库
-	AssetTypeApplication AssetType = "application" // 应用程序
-	AssetTypeServer      AssetType = "server"      // 服务器
-	AssetTypeNetwork     AssetType = "network"     // 网络设备
-	AssetTypeCloud       AssetType = "cloud"       // 云服务
-	AssetTypeIoT         AssetType = "iot"         // 物联网设备
-	AssetTypeOther       AssetType = "other"       // 其他
+	AssetTypeHost           AssetType = "host"            // 主机
+	AssetTypeWebsite        AssetType = "website"         // 网站
+	AssetTypeDatabase       AssetType = "database"        // 数据库
+	AssetTypeApplication    AssetType = "application"     // 应用程序
+	AssetTypeServer         AssetType = "server"          // 服务器
+	AssetTypeNetwork        AssetType = "network"         // 网络设备
+	AssetTypeCloud          AssetType = "cloud"           // 云服务
+	AssetTypeIoT            AssetType = "iot"             // 物联网设备
+	AssetTypeContainerImage AssetType = "container_image" // 容器镜像，Identifier 为镜像摘要
+	AssetTypeOther          AssetType = "other"           // 其他
 )
 
 // 资产状态
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// CustomScannerParser 自定义扫描器输出解析方式
+type CustomScannerParser string
+
+const (
+	CustomParserJSONLines CustomScannerParser = "jsonl"   // 每行一个JSON对象
+	CustomParserSARIF     CustomScannerParser = "sarif"   // SARIF 2.1.0
+	CustomParserMapping   CustomScannerParser = "mapping" // 按映射配置解析JSON或JSON Lines
+)
+
+// CustomScanner 自定义扫描器定义，通过本地命令执行扫描
+type CustomScanner struct {
+	ID          uint   `json:"id" gorm:"primary_key"`
+	Name        string `json:"name" gorm:"type:varchar(100);unique_index;not null"`
+	Description string `json:"description" gorm:"type:text"`
+
+	// 命令模板，支持占位符 {targets} {ips} {urls} {target_file} {output_file}
+	CommandTemplate string              `json:"command_template" gorm:"type:text;not null"`
+	OutputParser    CustomScannerParser `json:"output_parser" gorm:"type:varchar(20);not null;default:'jsonl'"`
+	MappingConfig   string              `json:"mapping_config" gorm:"type:text"` // 字段映射配置（JSON格式）
+	Timeout         int                 `json:"timeout" gorm:"default:3600"`     // 超时时间（秒）
+	Enabled         bool                `json:"enabled"`
+
+	CreatedBy uint       `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"-" gorm:"index"`
+}
+
+// TableName 指定表名
+func (CustomScanner) TableName() string {
+	return "custom_scanners"
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// EvidenceKind 证据类型
+type EvidenceKind string
+
+const (
+	EvidenceHTTPRequest  EvidenceKind = "http_request"  // 触发漏洞的HTTP请求
+	EvidenceHTTPResponse EvidenceKind = "http_response" // 对应的HTTP响应
+	EvidenceScreenshot   EvidenceKind = "screenshot"    // 截图
+	EvidencePluginOutput EvidenceKind = "plugin_output" // 扫描插件的原始输出
+	EvidenceOther        EvidenceKind = "other"         // 其他文件
+)
+
+// ValidEvidenceKind 判断证据类型是否有效
+func ValidEvidenceKind(kind EvidenceKind) bool {
+	switch kind {
+	case EvidenceHTTPRequest, EvidenceHTTPResponse, EvidenceScreenshot, EvidencePluginOutput, EvidenceOther:
+		return true
+	}
+	return false
+}
+
+// ScanEvidence 扫描器随扫描结果产生的证据内容，保存扫描结果时写入证据存储
+type ScanEvidence struct {
+	Kind        EvidenceKind `json:"kind"`
+	Name        string       `json:"name"`
+	ContentType string       `json:"content_type"` // 为空时根据内容推断
+	Data        []byte       `json:"data"`
+}
+
+// Evidence 证据元数据，内容按 SHA-256 保存在 upload.location 下的证据目录中，相同内容只保存一份
+//
+// 来自扫描结果的证据在结果导入漏洞库后同时关联到对应的漏洞，直接上传到漏洞的证据 ScanResultID 为0。
+type Evidence struct {
+	ID              uint         `json:"id" gorm:"primary_key"`
+	ScanResultID    uint         `json:"scan_result_id" gorm:"index"`   // 关联的扫描结果ID
+	VulnerabilityID uint         `json:"vulnerability_id" gorm:"index"` // 关联的漏洞ID
+	Kind            EvidenceKind `json:"kind" gorm:"type:varchar(20);not null"`
+	Name            string       `json:"name" gorm:"type:varchar(255)"`
+	ContentType     string       `json:"content_type" gorm:"type:varchar(100)"`
+	Size            int64        `json:"size"`
+	SHA256          string       `json:"sha256" gorm:"column:sha256;type:varchar(64);index;not null"`
+	CreatedBy       uint         `json:"created_by"` // 上传者ID，扫描产生的证据为0
+	CreatedAt       time.Time    `json:"created_at"`
+}
+
+// TableName 指定表名
+func (Evidence) TableName() string {
+	return "evidences"
+}
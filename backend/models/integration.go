@@ -1,6 +1,9 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -8,11 +11,12 @@ import (
 type CIIntegration struct {
 	ID          uint       `json:"id" gorm:"primary_key"`
 	Name        string     `json:"name" gorm:"type:varchar(100);not null"`
-	Type        string     `json:"type" gorm:"type:varchar(50);not null"` // jenkins, gitlab, github, custom
+	Type        string     `json:"type" gorm:"type:varchar(50);not null"` // jenkins, gitlab, github, custom, sarif, trivy, grype, cyclonedx, spdx
 	Description string     `json:"description" gorm:"type:text"`
 	APIKey      string     `json:"api_key" gorm:"type:varchar(64);unique_index;not null"`
 	Enabled     bool       `json:"enabled" gorm:"default:true"`
-	Config      string     `json:"config" gorm:"type:text"` // JSON格式的额外配置
+	Config      string     `json:"config" gorm:"type:text"`       // JSON格式的额外配置
+	QualityGate string     `json:"quality_gate" gorm:"type:text"` // 质量门禁策略（JSON格式，见 QualityGatePolicy），为空时不做门禁判断
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 	DeletedAt   *time.Time `json:"-" gorm:"index"`
@@ -23,6 +27,41 @@ func (CIIntegration) TableName() string {
 	return "ci_integrations"
 }
 
+// QualityGatePolicy CI集成的质量门禁策略，上报的结果违反任一规则时门禁不通过
+//
+// 例如 {"max_new": {"critical": 0}, "max_total": {"high": 5}, "ignore_accepted": true}
+// 表示出现新的严重漏洞、或本次上报的高危漏洞超过5个时不通过，已接受风险的漏洞不计入。
+type QualityGatePolicy struct {
+	MaxNew         map[Severity]int `json:"max_new,omitempty"`   // 各严重程度允许的新增漏洞数量上限，0 表示出现即不通过
+	MaxTotal       map[Severity]int `json:"max_total,omitempty"` // 各严重程度允许的本次上报漏洞数量上限，包括已有漏洞
+	IgnoreAccepted bool             `json:"ignore_accepted"`     // 不计入已关闭（接受风险）和误报的漏洞
+}
+
+// ParseQualityGate 解析质量门禁策略，未配置时返回 nil
+func (i *CIIntegration) ParseQualityGate() (*QualityGatePolicy, error) {
+	if strings.TrimSpace(i.QualityGate) == "" {
+		return nil, nil
+	}
+
+	var policy QualityGatePolicy
+	if err := json.Unmarshal([]byte(i.QualityGate), &policy); err != nil {
+		return nil, fmt.Errorf("质量门禁策略不是有效的JSON: %v", err)
+	}
+	for _, limits := range []map[Severity]int{policy.MaxNew, policy.MaxTotal} {
+		for severity, limit := range limits {
+			switch severity {
+			case SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityInfo:
+			default:
+				return nil, fmt.Errorf("质量门禁策略中无效的严重程度: %s", severity)
+			}
+			if limit < 0 {
+				return nil, fmt.Errorf("质量门禁策略中 %s 的数量上限不能为负数", severity)
+			}
+		}
+	}
+	return &policy, nil
+}
+
 // IntegrationHistory CI/CD集成历史记录
 type IntegrationHistory struct {
 	ID              uint      `json:"id" gorm:"primary_key"`
@@ -33,6 +72,8 @@ type IntegrationHistory struct {
 	TotalRecords    int       `json:"total_records" gorm:"default:0"`
 	SuccessCount    int       `json:"success_count" gorm:"default:0"`
 	ErrorCount      int       `json:"error_count" gorm:"default:0"`
+	GateStatus      string    `json:"gate_status" gorm:"type:varchar(20)"` // passed, failed，未配置质量门禁时为空
+	GateResult      string    `json:"gate_result" gorm:"type:text"`        // 质量门禁判断结果（JSON格式）
 	ExecutedAt      time.Time `json:"executed_at"`
 	CreatedAt       time.Time `json:"created_at" gorm:"autoCreateTime"`
 }
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+)
+
+// SBOM 资产某个版本的软件物料清单，同一资产版本重复上传时替换之前的清单
+type SBOM struct {
+	ID             uint      `json:"id" gorm:"primary_key"`
+	AssetID        uint      `json:"asset_id" gorm:"index;not null"`
+	AssetVersion   string    `json:"asset_version" gorm:"type:varchar(100);index"`
+	IntegrationID  uint      `json:"integration_id" gorm:"index"`
+	Format         string    `json:"format" gorm:"type:varchar(20);not null"` // cyclonedx, spdx
+	SpecVersion    string    `json:"spec_version" gorm:"type:varchar(20)"`
+	Name           string    `json:"name" gorm:"type:varchar(255)"`
+	SerialNumber   string    `json:"serial_number" gorm:"type:varchar(255)"`
+	ComponentCount int       `json:"component_count"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (SBOM) TableName() string {
+	return "sboms"
+}
+
+// SBOMComponent SBOM中的组件，冗余记录资产和版本便于按组件反查使用它的应用
+type SBOMComponent struct {
+	ID           uint   `json:"id" gorm:"primary_key"`
+	SBOMID       uint   `json:"sbom_id" gorm:"index;not null"`
+	AssetID      uint   `json:"asset_id" gorm:"index;not null"`
+	AssetVersion string `json:"asset_version" gorm:"type:varchar(100)"`
+	Type         string `json:"type" gorm:"type:varchar(50)"`                          // library, framework, operating-system 等
+	Group        string `json:"group" gorm:"column:component_group;type:varchar(255)"` // Maven groupId、npm scope 等
+	Name         string `json:"name" gorm:"type:varchar(255);index;not null"`
+	Version      string `json:"version" gorm:"type:varchar(100)"`
+	PURL         string `json:"purl" gorm:"column:purl;type:varchar(500)"`
+	Licenses     string `json:"licenses" gorm:"type:text"` // 逗号分隔的许可证，SPDX标识符或许可证表达式
+}
+
+// TableName 指定表名
+func (SBOMComponent) TableName() string {
+	return "sbom_components"
+}
@@ -1,7 +1,13 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
 	"time"
+
+	"github.com/vulnark/vulnark/secret"
 )
 
 // ScannerType 扫描器类型
@@ -27,6 +33,38 @@ const (
 	ScanTaskStatusCancelled ScanTaskStatus = "cancelled" // 已取消
 )
 
+// ScanFailureReason 扫描失败原因分类
+type ScanFailureReason string
+
+const (
+	ScanFailureUnreachable ScanFailureReason = "unreachable" // 无法连接扫描器
+	ScanFailureAuth        ScanFailureReason = "auth"        // 扫描器认证失败
+	ScanFailureTimeout     ScanFailureReason = "timeout"     // 请求或扫描超时
+	ScanFailureParse       ScanFailureReason = "parse"       // 扫描器输出无法解析
+	ScanFailureConfig      ScanFailureReason = "config"      // 任务配置错误，如参数无效、没有可扫描的目标
+	ScanFailureScanner     ScanFailureReason = "scanner"     // 扫描器报告扫描失败
+	ScanFailureAgent       ScanFailureReason = "agent"       // 扫描代理失联或停止
+	ScanFailureUnknown     ScanFailureReason = "unknown"     // 其他错误
+)
+
+// Retryable 该类失败是否可能是暂时的，可以自动重试
+func (r ScanFailureReason) Retryable() bool {
+	switch r {
+	case ScanFailureUnreachable, ScanFailureTimeout, ScanFailureScanner, ScanFailureAgent:
+		return true
+	}
+	return false
+}
+
+// ScanTrigger 扫描触发方式
+type ScanTrigger string
+
+const (
+	ScanTriggerManual    ScanTrigger = "manual"    // 手动启动
+	ScanTriggerScheduled ScanTrigger = "scheduled" // 计划时间触发
+	ScanTriggerCron      ScanTrigger = "cron"      // 定期调度触发
+)
+
 // ScanTask 扫描任务模型
 type ScanTask struct {
 	ID          uint           `json:"id" gorm:"primary_key"`
@@ -34,25 +72,59 @@ type ScanTask struct {
 	Description string         `json:"description" gorm:"type:text"`
 	Type        ScannerType    `json:"type" gorm:"type:varchar(50);not null"`
 	Status      ScanTaskStatus `json:"status" gorm:"type:varchar(20);not null;default:'created'"`
+	Progress    int            `json:"progress" gorm:"default:0"` // 扫描进度百分比
+	Priority    int            `json:"priority" gorm:"default:0"` // 排队优先级，数值越大越先执行
+
+	// 排队信息
+	QueuedAt      *time.Time  `json:"queued_at" gorm:"index"`                // 最近一次进入队列的时间
+	QueueTrigger  ScanTrigger `json:"queue_trigger" gorm:"type:varchar(20)"` // 最近一次入队的触发方式
+	QueuePosition int         `json:"queue_position,omitempty" gorm:"-"`     // 队列中的位置，从1开始，仅查询详情时计算
 
 	// 扫描配置
-	ScannerURL      string `json:"scanner_url" gorm:"type:varchar(255)"`
-	ScannerAPIKey   string `json:"scanner_api_key" gorm:"type:varchar(255)"`
-	ScannerUsername string `json:"scanner_username" gorm:"type:varchar(100)"`
-	ScannerPassword string `json:"scanner_password" gorm:"type:varchar(100)"`
+	ScannerURL       string `json:"scanner_url" gorm:"type:varchar(255)"`
+	ScannerAPIKey    string `json:"scanner_api_key" gorm:"type:text"`
+	ScannerUsername  string `json:"scanner_username" gorm:"type:varchar(100)"`
+	ScannerPassword  string `json:"scanner_password" gorm:"type:text"`
+	CustomScannerID  uint   `json:"custom_scanner_id" gorm:"index"`  // 自定义扫描器定义ID，custom 类型使用
+	ScannerProfileID uint   `json:"scanner_profile_id" gorm:"index"` // 扫描器连接配置ID，设置后优先于上面的连接信息
+
+	// 执行位置，设置网络区域时由该区域的扫描代理执行，否则由服务端执行
+	AgentZone string `json:"agent_zone" gorm:"type:varchar(100);index"`
+	AgentID   uint   `json:"agent_id"` // 最近一次执行该任务的扫描代理ID
+
+	// 扫描时间窗口，目标资产不在允许扫描的时段时任务保持排队并记录等待原因
+	WindowPolicy ScanWindowPolicy `json:"window_policy" gorm:"type:varchar(20)"` // 为空时按 hold 处理
+	WaitReason   string           `json:"wait_reason" gorm:"type:text"`          // 排队等待的原因
+	WaitUntil    *time.Time       `json:"wait_until" gorm:"index"`               // 在此之前不出队
+	ParentTaskID uint             `json:"parent_task_id" gorm:"index"`           // 按时间窗口拆分出该任务的原任务ID
+
+	// 失败诊断和自动重试，可重试的失败按 RetryBackoff 指数退避后重新排队
+	FailureReason ScanFailureReason `json:"failure_reason" gorm:"type:varchar(20)"` // 最近一次失败的原因分类
+	MaxRetries    int               `json:"max_retries"`                            // 最多自动重试次数
+	RetryBackoff  int               `json:"retry_backoff"`                          // 首次重试的等待时间（秒），之后每次翻倍
+	RetryCount    int               `json:"retry_count"`                            // 本次排队以来已自动重试的次数
+	RetryRunID    uint              `json:"retry_run_id"`                           // 正在重试的失败执行记录ID
 
 	// 扫描目标
 	TargetIPs      string `json:"target_ips" gorm:"type:text"`      // 逗号分隔的IP地址列表
 	TargetURLs     string `json:"target_urls" gorm:"type:text"`     // 逗号分隔的URL列表
 	TargetAssets   string `json:"target_assets" gorm:"type:text"`   // 逗号分隔的资产ID列表
+	TargetFilter   string `json:"target_filter" gorm:"type:text"`   // 按条件选择资产（JSON格式，见 ScanTargetFilter）
+	ExcludeTargets string `json:"exclude_targets" gorm:"type:text"` // 逗号分隔的排除列表，支持IP、CIDR、IP范围和主机名
 	ScanParameters string `json:"scan_parameters" gorm:"type:text"` // 扫描参数（JSON格式）
 
+	// 自动导入策略
+	AutoImport            bool     `json:"auto_import"`                                      // 扫描完成后自动导入结果到漏洞库
+	AutoImportMinSeverity Severity `json:"auto_import_min_severity" gorm:"type:varchar(20)"` // 自动导入的最低严重程度，为空时导入全部
+	AutoVerify            bool     `json:"auto_verify"`                                      // 根据复扫结果自动确认修复或重新打开漏洞
+
 	// 扫描时间
 	ScheduledAt  *time.Time `json:"scheduled_at"`                           // 计划扫描时间
 	StartedAt    *time.Time `json:"started_at"`                             // 开始扫描时间
 	CompletedAt  *time.Time `json:"completed_at"`                           // 完成扫描时间
 	IsRecurring  bool       `json:"is_recurring"`                           // 是否是定期扫描
 	CronSchedule string     `json:"cron_schedule" gorm:"type:varchar(100)"` // Cron表达式
+	NextRunAt    *time.Time `json:"next_run_at" gorm:"index"`               // 下一次定期执行时间
 
 	// 扫描结果
 	TotalVulnerabilities    int    `json:"total_vulnerabilities"`           // 总漏洞数
@@ -72,10 +144,24 @@ type ScanTask struct {
 	DeletedAt *time.Time `json:"-" gorm:"index"`
 }
 
+// ScanTargetFilter 按条件选择资产作为扫描目标，不同条件之间为“且”，同一条件的多个值之间为“或”
+type ScanTargetFilter struct {
+	Tags        []string    `json:"tags"`        // 资产标签
+	Departments []string    `json:"departments"` // 所属部门
+	Types       []AssetType `json:"types"`       // 资产类型
+}
+
+// IsEmpty 是否未设置任何条件
+func (f *ScanTargetFilter) IsEmpty() bool {
+	return len(f.Tags) == 0 && len(f.Departments) == 0 && len(f.Types) == 0
+}
+
 // ScanResult 扫描结果模型
 type ScanResult struct {
-	ID         uint `json:"id" gorm:"primary_key"`
-	ScanTaskID uint `json:"scan_task_id" gorm:"index;not null"` // 关联的扫描任务ID
+	ID          uint   `json:"id" gorm:"primary_key"`
+	ScanTaskID  uint   `json:"scan_task_id" gorm:"index;not null"`        // 关联的扫描任务ID
+	ScanRunID   uint   `json:"scan_run_id" gorm:"index"`                  // 关联的执行记录ID
+	Fingerprint string `json:"fingerprint" gorm:"type:varchar(64);index"` // 跨执行匹配同一发现的指纹
 
 	VulnerabilityName string   `json:"vulnerability_name" gorm:"type:varchar(255);not null"`
 	Description       string   `json:"description" gorm:"type:text"`
@@ -101,12 +187,96 @@ type ScanResult struct {
 	ImportedAt *time.Time `json:"imported_at"`                      // 导入时间
 	ImportedID uint       `json:"imported_id"`                      // 导入后的漏洞ID
 
+	// 扫描器产生的证据内容，仅在扫描过程中传递，保存结果时写入证据存储
+	Evidence []ScanEvidence `json:"evidence,omitempty" gorm:"-"`
+
 	// 时间戳
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 	DeletedAt *time.Time `json:"-" gorm:"index"`
 }
 
+// ComputeFingerprint 根据漏洞名称、主机、端口、URL和CVE计算发现的指纹
+// 用于在不同执行之间匹配同一个发现，大小写和URL末尾的"/"不影响结果
+func (r *ScanResult) ComputeFingerprint() string {
+	parts := []string{
+		strings.ToLower(strings.TrimSpace(r.VulnerabilityName)),
+		strings.ToLower(strings.TrimSpace(r.AffectedIP)),
+		strings.TrimSpace(r.AffectedPort),
+		normalizeFingerprintURL(r.AffectedURL),
+		strings.ToUpper(strings.TrimSpace(r.CVE)),
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeFingerprintURL 规范化URL：协议和主机名小写，去掉末尾的"/"和片段
+func normalizeFingerprintURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return strings.TrimRight(raw, "/")
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	return strings.TrimRight(u.String(), "/")
+}
+
+// ScanRun 扫描任务的单次执行记录
+type ScanRun struct {
+	ID         uint           `json:"id" gorm:"primary_key"`
+	ScanTaskID uint           `json:"scan_task_id" gorm:"index;not null"` // 关联的扫描任务ID
+	Trigger    ScanTrigger    `json:"trigger" gorm:"type:varchar(20);not null"`
+	Status     ScanTaskStatus `json:"status" gorm:"type:varchar(20);not null"`
+	AgentID    uint           `json:"agent_id" gorm:"index"` // 执行本次扫描的扫描代理ID，0 表示由服务端执行
+
+	// 重试信息，Attempt 从1开始
+	Attempt       int               `json:"attempt"`
+	RetryOfRunID  uint              `json:"retry_of_run_id"`                        // 本次执行重试的失败执行记录ID
+	FailureReason ScanFailureReason `json:"failure_reason" gorm:"type:varchar(20)"` // 失败原因分类
+
+	// 由扫描代理执行时的取消请求，代理下次上报时下发
+	CancelRequested bool `json:"cancel_requested"`
+	KeepPartial     bool `json:"keep_partial"`
+
+	// 由扫描代理执行时领取作业时解析出的扫描目标（JSON），完成时按同一目标复测和导入
+	Targets string `json:"-" gorm:"type:longtext"`
+
+	StartedAt   *time.Time `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+
+	TotalVulnerabilities    int    `json:"total_vulnerabilities"`
+	CriticalVulnerabilities int    `json:"critical_vulnerabilities"`
+	HighVulnerabilities     int    `json:"high_vulnerabilities"`
+	MediumVulnerabilities   int    `json:"medium_vulnerabilities"`
+	LowVulnerabilities      int    `json:"low_vulnerabilities"`
+	ResultSummary           string `json:"result_summary" gorm:"type:text"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ScanEventType 扫描事件类型
+type ScanEventType string
+
+const (
+	ScanEventLog      ScanEventType = "log"      // 扫描日志
+	ScanEventProgress ScanEventType = "progress" // 进度变化
+	ScanEventStatus   ScanEventType = "status"   // 状态变化
+)
+
+// ScanEvent 扫描事件，只追加不修改，用于实时查看扫描日志和进度
+type ScanEvent struct {
+	ID         uint          `json:"id" gorm:"primary_key"`
+	ScanTaskID uint          `json:"scan_task_id" gorm:"index;not null"` // 关联的扫描任务ID
+	ScanRunID  uint          `json:"scan_run_id" gorm:"index"`           // 关联的执行记录ID
+	Type       ScanEventType `json:"type" gorm:"type:varchar(20);not null"`
+	Message    string        `json:"message" gorm:"type:text"`
+	Progress   int           `json:"progress"` // 事件发生时的进度百分比
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
 // TableName 指定表名
 func (ScanTask) TableName() string {
 	return "scan_tasks"
@@ -117,6 +287,16 @@ func (ScanResult) TableName() string {
 	return "scan_results"
 }
 
+// TableName 指定表名
+func (ScanRun) TableName() string {
+	return "scan_runs"
+}
+
+// TableName 指定表名
+func (ScanEvent) TableName() string {
+	return "scan_events"
+}
+
 // IsCriticalTask 判断是否为包含严重漏洞的任务
 func (s *ScanTask) IsCriticalTask() bool {
 	return s.CriticalVulnerabilities > 0
@@ -131,3 +311,23 @@ func (s *ScanTask) IsCompleted() bool {
 func (s *ScanTask) IsInProgress() bool {
 	return s.Status == ScanTaskStatusQueued || s.Status == ScanTaskStatusRunning
 }
+
+// secretFields 需要加密保存的扫描器凭据
+func (s *ScanTask) secretFields() []*string {
+	return []*string{&s.ScannerAPIKey, &s.ScannerPassword}
+}
+
+// BeforeSave 保存前加密扫描器凭据
+func (s *ScanTask) BeforeSave() error {
+	return secret.EncryptFields(s.secretFields()...)
+}
+
+// AfterSave 保存后恢复内存中的明文凭据
+func (s *ScanTask) AfterSave() error {
+	return secret.DecryptFields(s.secretFields()...)
+}
+
+// AfterFind 查询后解密扫描器凭据
+func (s *ScanTask) AfterFind() error {
+	return secret.DecryptFields(s.secretFields()...)
+}
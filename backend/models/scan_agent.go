@@ -0,0 +1,105 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// ScanAgentTokenPrefix 扫描代理令牌的固定前缀，便于在日志和配置中识别
+const ScanAgentTokenPrefix = "vsa_"
+
+// ScanAgentStatus 扫描代理状态
+type ScanAgentStatus string
+
+const (
+	ScanAgentStatusPending  ScanAgentStatus = "pending"  // 已创建，尚未注册
+	ScanAgentStatusOnline   ScanAgentStatus = "online"   // 在线
+	ScanAgentStatusOffline  ScanAgentStatus = "offline"  // 心跳超时
+	ScanAgentStatusDisabled ScanAgentStatus = "disabled" // 已停用
+)
+
+// ScanAgent 扫描代理，部署在隔离网段内，通过HTTP从服务端拉取本网络区域的扫描任务并在本地执行
+type ScanAgent struct {
+	ID          uint   `json:"id" gorm:"primary_key"`
+	Name        string `json:"name" gorm:"type:varchar(100);index;not null"`
+	Description string `json:"description" gorm:"type:text"`
+	Zone        string `json:"zone" gorm:"type:varchar(100);index;not null"` // 网络区域，只领取该区域的扫描任务
+	Enabled     bool   `json:"enabled"`
+
+	// 代理令牌只保存SHA-256摘要，明文只在创建或重置时返回一次
+	TokenHash   string `json:"-" gorm:"type:varchar(64);unique_index;not null"`
+	TokenPrefix string `json:"token_prefix" gorm:"type:varchar(16)"` // 令牌前缀，便于识别
+
+	// 代理注册和心跳时上报的信息
+	Hostname     string     `json:"hostname" gorm:"type:varchar(255)"`
+	IPAddress    string     `json:"ip_address" gorm:"type:varchar(50)"`
+	Version      string     `json:"version" gorm:"type:varchar(50)"`
+	ScannerTypes string     `json:"scanner_types" gorm:"type:varchar(255)"` // 代理支持的扫描器类型，逗号分隔
+	RunningJobs  int        `json:"running_jobs"`                           // 正在执行的扫描数
+	RegisteredAt *time.Time `json:"registered_at"`
+	LastSeenAt   *time.Time `json:"last_seen_at" gorm:"index"`
+
+	Status ScanAgentStatus `json:"status" gorm:"-"` // 根据启用状态和最近心跳时间计算
+
+	CreatedBy uint       `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"-" gorm:"index"`
+}
+
+// TableName 指定表名
+func (ScanAgent) TableName() string {
+	return "scan_agents"
+}
+
+// ComputeStatus 根据心跳超时时间计算代理状态
+func (a *ScanAgent) ComputeStatus(now time.Time, timeout time.Duration) ScanAgentStatus {
+	switch {
+	case !a.Enabled:
+		a.Status = ScanAgentStatusDisabled
+	case a.LastSeenAt == nil:
+		a.Status = ScanAgentStatusPending
+	case now.Sub(*a.LastSeenAt) > timeout:
+		a.Status = ScanAgentStatusOffline
+	default:
+		a.Status = ScanAgentStatusOnline
+	}
+	return a.Status
+}
+
+// SupportsType 代理是否支持指定的扫描器类型，未上报支持类型时视为不支持任何类型
+func (a *ScanAgent) SupportsType(scannerType ScannerType) bool {
+	for _, t := range strings.Split(a.ScannerTypes, ",") {
+		if ScannerType(strings.TrimSpace(t)) == scannerType {
+			return true
+		}
+	}
+	return false
+}
+
+// SetToken 保存令牌的摘要和前缀
+func (a *ScanAgent) SetToken(token string) {
+	a.TokenHash = HashAgentToken(token)
+	a.TokenPrefix = token
+	if len(a.TokenPrefix) > 12 {
+		a.TokenPrefix = a.TokenPrefix[:12]
+	}
+}
+
+// GenerateAgentToken 生成随机的扫描代理令牌
+func GenerateAgentToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return ScanAgentTokenPrefix + hex.EncodeToString(buf), nil
+}
+
+// HashAgentToken 计算代理令牌的SHA-256摘要
+func HashAgentToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,232 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ScanWindowKind 扫描时间窗口类型
+type ScanWindowKind string
+
+const (
+	ScanWindowMaintenance ScanWindowKind = "maintenance" // 维护窗口，关联资产只能在窗口内扫描
+	ScanWindowBlackout    ScanWindowKind = "blackout"    // 禁止扫描时段，如封网期
+)
+
+// ScanWindowPolicy 扫描任务的部分目标不在允许扫描的时段时的处理方式
+type ScanWindowPolicy string
+
+const (
+	ScanWindowPolicyHold   ScanWindowPolicy = "hold"   // 等待所有目标都允许扫描时再执行
+	ScanWindowPolicySplit  ScanWindowPolicy = "split"  // 先扫描允许的目标，其余目标拆分为新任务等待各自的窗口
+	ScanWindowPolicyIgnore ScanWindowPolicy = "ignore" // 不受时间窗口限制
+)
+
+// ScanWindowSlot 每周重复的时段
+type ScanWindowSlot struct {
+	Days  []int  `json:"days"`  // 星期几，0 为周日，为空表示每天
+	Start string `json:"start"` // 开始时间 HH:MM
+	End   string `json:"end"`   // 结束时间 HH:MM，不大于开始时间时表示跨天到次日
+}
+
+// ScanWindow 扫描时间窗口，按资产ID或资产筛选条件关联资产
+//
+// 窗口由每周重复的时段（Slots）和/或一次性的时间范围（StartAt~EndAt）组成，
+// 两者都设置时只在时间范围内的重复时段生效。
+type ScanWindow struct {
+	ID          uint           `json:"id" gorm:"primary_key"`
+	Name        string         `json:"name" gorm:"type:varchar(100);unique_index;not null"`
+	Description string         `json:"description" gorm:"type:text"`
+	Kind        ScanWindowKind `json:"kind" gorm:"type:varchar(20);not null"`
+	Enabled     bool           `json:"enabled"`
+
+	// 时间定义
+	Timezone string     `json:"timezone" gorm:"type:varchar(64)"` // IANA时区，为空时使用服务端时区
+	Slots    string     `json:"slots" gorm:"type:text"`           // 每周重复的时段（JSON格式，见 ScanWindowSlot）
+	StartAt  *time.Time `json:"start_at"`                         // 一次性时间范围的开始
+	EndAt    *time.Time `json:"end_at"`                           // 一次性时间范围的结束
+
+	// 关联的资产
+	AssetIDs    string `json:"asset_ids" gorm:"type:text"`    // 逗号分隔的资产ID列表
+	AssetFilter string `json:"asset_filter" gorm:"type:text"` // 按条件关联资产（JSON格式，见 ScanTargetFilter）
+
+	CreatedBy uint       `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"-" gorm:"index"`
+}
+
+// TableName 指定表名
+func (ScanWindow) TableName() string {
+	return "scan_windows"
+}
+
+// scanWindowSlot 解析后的时段，时间为当天的分钟数
+type scanWindowSlot struct {
+	days       [7]bool
+	start, end int
+}
+
+// contains 判断星期 weekday 的第 minute 分钟是否在时段内
+func (s *scanWindowSlot) contains(weekday time.Weekday, minute int) bool {
+	if s.start < s.end {
+		return s.days[weekday] && minute >= s.start && minute < s.end
+	}
+	// 跨天的时段：当天开始时间之后，或前一天开始、当天结束时间之前
+	previous := (weekday + 6) % 7
+	return (s.days[weekday] && minute >= s.start) || (s.days[previous] && minute < s.end)
+}
+
+// Validate 校验窗口定义
+func (w *ScanWindow) Validate() error {
+	switch w.Kind {
+	case ScanWindowMaintenance, ScanWindowBlackout:
+	default:
+		return fmt.Errorf("不支持的窗口类型: %s", w.Kind)
+	}
+	if _, err := w.location(); err != nil {
+		return fmt.Errorf("无效的时区: %s", w.Timezone)
+	}
+	slots, err := w.parseSlots()
+	if err != nil {
+		return err
+	}
+	if len(slots) == 0 && w.StartAt == nil && w.EndAt == nil {
+		return errors.New("请至少设置一个每周时段或一次性时间范围")
+	}
+	if w.StartAt != nil && w.EndAt != nil && !w.EndAt.After(*w.StartAt) {
+		return errors.New("结束时间必须晚于开始时间")
+	}
+	return nil
+}
+
+// Active 判断时刻 t 是否处于窗口内
+func (w *ScanWindow) Active(t time.Time) bool {
+	if w.StartAt != nil && t.Before(*w.StartAt) {
+		return false
+	}
+	if w.EndAt != nil && !t.Before(*w.EndAt) {
+		return false
+	}
+
+	slots, err := w.parseSlots()
+	if err != nil {
+		return false
+	}
+	if len(slots) == 0 {
+		return true
+	}
+
+	loc, _ := w.location()
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	for i := range slots {
+		if slots[i].contains(local.Weekday(), minute) {
+			return true
+		}
+	}
+	return false
+}
+
+// Boundaries 返回 (from, to] 之间窗口开始或结束的时刻，用于计算状态何时可能改变
+func (w *ScanWindow) Boundaries(from, to time.Time) []time.Time {
+	var result []time.Time
+	add := func(t time.Time) {
+		if t.After(from) && !t.After(to) {
+			result = append(result, t)
+		}
+	}
+	if w.StartAt != nil {
+		add(*w.StartAt)
+	}
+	if w.EndAt != nil {
+		add(*w.EndAt)
+	}
+
+	slots, err := w.parseSlots()
+	if err != nil || len(slots) == 0 {
+		return result
+	}
+
+	loc, _ := w.location()
+	localFrom := from.In(loc)
+	day := time.Date(localFrom.Year(), localFrom.Month(), localFrom.Day()-1, 0, 0, 0, 0, loc)
+	for !day.After(to) {
+		for i := range slots {
+			slot := &slots[i]
+			if !slot.days[day.Weekday()] {
+				continue
+			}
+			start := day.Add(time.Duration(slot.start) * time.Minute)
+			end := day.Add(time.Duration(slot.end) * time.Minute)
+			if slot.end <= slot.start {
+				end = end.AddDate(0, 0, 1)
+			}
+			add(start)
+			add(end)
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return result
+}
+
+// location 窗口时区
+func (w *ScanWindow) location() (*time.Location, error) {
+	if strings.TrimSpace(w.Timezone) == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(strings.TrimSpace(w.Timezone))
+	if err != nil {
+		return time.Local, err
+	}
+	return loc, nil
+}
+
+// parseSlots 解析每周重复的时段
+func (w *ScanWindow) parseSlots() ([]scanWindowSlot, error) {
+	if strings.TrimSpace(w.Slots) == "" {
+		return nil, nil
+	}
+
+	var defs []ScanWindowSlot
+	if err := json.Unmarshal([]byte(w.Slots), &defs); err != nil {
+		return nil, fmt.Errorf("时段定义不是有效的JSON: %v", err)
+	}
+
+	slots := make([]scanWindowSlot, 0, len(defs))
+	for _, def := range defs {
+		var slot scanWindowSlot
+		var err error
+		if slot.start, err = parseClock(def.Start); err != nil {
+			return nil, err
+		}
+		if slot.end, err = parseClock(def.End); err != nil {
+			return nil, err
+		}
+		if len(def.Days) == 0 {
+			for i := range slot.days {
+				slot.days[i] = true
+			}
+		}
+		for _, day := range def.Days {
+			if day < 0 || day > 6 {
+				return nil, fmt.Errorf("无效的星期: %d（0为周日，6为周六）", day)
+			}
+			slot.days[day] = true
+		}
+		slots = append(slots, slot)
+	}
+	return slots, nil
+}
+
+// parseClock 解析 HH:MM 格式的时间，返回当天的分钟数
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("无效的时间: %s（格式为 HH:MM）", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/vulnark/vulnark/secret"
+)
+
+// ScannerProfile 扫描器连接配置，多个扫描任务可共用同一份地址和凭据
+type ScannerProfile struct {
+	ID          uint        `json:"id" gorm:"primary_key"`
+	Name        string      `json:"name" gorm:"type:varchar(100);unique_index;not null"`
+	Description string      `json:"description" gorm:"type:text"`
+	Type        ScannerType `json:"type" gorm:"type:varchar(50);not null"`
+
+	// 连接信息，API密钥和密码加密保存
+	ScannerURL string `json:"scanner_url" gorm:"type:varchar(255)"`
+	APIKey     string `json:"api_key,omitempty" gorm:"type:text"`
+	Username   string `json:"username" gorm:"type:varchar(100)"`
+	Password   string `json:"password,omitempty" gorm:"type:text"`
+	VerifyTLS  bool   `json:"verify_tls"` // 是否校验扫描器的TLS证书
+
+	DefaultParameters string `json:"default_parameters" gorm:"type:text"` // 默认扫描参数（JSON格式），任务参数优先
+
+	// 查询时返回是否已配置凭据，不返回凭据本身
+	HasAPIKey   bool `json:"has_api_key" gorm:"-"`
+	HasPassword bool `json:"has_password" gorm:"-"`
+
+	CreatedBy uint       `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"-" gorm:"index"`
+}
+
+// TableName 指定表名
+func (ScannerProfile) TableName() string {
+	return "scanner_profiles"
+}
+
+// secretFields 需要加密保存的凭据
+func (p *ScannerProfile) secretFields() []*string {
+	return []*string{&p.APIKey, &p.Password}
+}
+
+// BeforeSave 保存前加密凭据
+func (p *ScannerProfile) BeforeSave() error {
+	return secret.EncryptFields(p.secretFields()...)
+}
+
+// AfterSave 保存后恢复内存中的明文凭据
+func (p *ScannerProfile) AfterSave() error {
+	return secret.DecryptFields(p.secretFields()...)
+}
+
+// AfterFind 查询后解密凭据
+func (p *ScannerProfile) AfterFind() error {
+	return secret.DecryptFields(p.secretFields()...)
+}
+
+// HideCredentials 隐藏凭据，只保留是否已配置的标记
+func (p *ScannerProfile) HideCredentials() {
+	p.HasAPIKey = p.APIKey != ""
+	p.HasPassword = p.Password != ""
+	p.APIKey = ""
+	p.Password = ""
+}
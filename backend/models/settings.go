@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"github.com/vulnark/vulnark/secret"
 )
 
 // JIRASettings JIRA集成设置
@@ -109,6 +111,30 @@ func (Settings) TableName() string {
 	return "settings"
 }
 
+// SecretFields 需要加密保存的集成和通知密钥
+func (s *Settings) SecretFields() []*string {
+	return []*string{
+		&s.Integrations.JIRA.APIToken,
+		&s.Integrations.Wechat.AppSecret,
+		&s.Integrations.VulnDB.APIKey,
+		&s.Integrations.VulnDB.APISecret,
+		&s.Notifications.Feishu.Secret,
+		&s.Notifications.Dingtalk.Secret,
+		&s.Notifications.Email.Password,
+		&s.AI.APIKEY,
+	}
+}
+
+// EncryptSecrets 加密设置中的密钥，在序列化保存前调用
+func (s *Settings) EncryptSecrets() error {
+	return secret.EncryptFields(s.SecretFields()...)
+}
+
+// DecryptSecrets 解密设置中的密钥，在从数据库读取后调用
+func (s *Settings) DecryptSecrets() error {
+	return secret.DecryptFields(s.SecretFields()...)
+}
+
 // Setting 单个系统设置项，用于记录设置项的变更
 type Setting struct {
 	ID        uint      `json:"id" gorm:"primary_key"`
@@ -31,17 +31,19 @@ const (
 type VulnType string
 
 const (
-	TypeSQLInjection       VulnType = "sql_injection"       // SQL注入
-	TypeXSS                VulnType = "xss"                 // 跨站脚本
-	TypeCmdInjection       VulnType = "cmd_injection"       // 命令注入
-	TypeSSRF               VulnType = "ssrf"                // 服务器端请求伪造
-	TypeFileUpload         VulnType = "file_upload"         // 文件上传
-	TypeFileInclusion      VulnType = "file_inclusion"      // 文件包含
-	TypeInfoDisclosure     VulnType = "info_disclosure"     // 信息泄露
-	TypeUnauthorizedAccess VulnType = "unauthorized_access" // 未授权访问
-	TypeWeakPassword       VulnType = "weak_password"       // 弱密码
-	TypeMisconfig          VulnType = "misconfiguration"    // 错误配置
-	TypeOther              VulnType = "other"               // 其他
+	TypeSQLInjection        VulnType = "sql_injection"        // SQL注入
+	TypeXSS                 VulnType = "xss"                  // 跨站脚本
+	TypeCmdInjection        VulnType = "cmd_injection"        // 命令注入
+	TypeSSRF                VulnType = "ssrf"                 // 服务器端请求伪造
+	TypeFileUpload          VulnType = "file_upload"          // 文件上传
+	TypeFileInclusion       VulnType = "file_inclusion"       // 文件包含
+	TypeInfoDisclosure      VulnType = "info_disclosure"      // 信息泄露
+	TypeUnauthorizedAccess  VulnType = "unauthorized_access"  // 未授权访问
+	TypeWeakPassword        VulnType = "weak_password"        // 弱密码
+	TypeMisconfig           VulnType = "misconfiguration"     // 错误配置
+	TypeVulnerableComponent VulnType = "vulnerable_component" // 存在漏洞的依赖组件或系统软件包
+	TypeSecretLeak          VulnType = "secret_leak"          // 密钥、令牌等敏感凭据泄露
+	TypeOther               VulnType = "other"                // 其他
 )
 
 // Vulnerability 漏洞模型
@@ -49,13 +51,23 @@ type Vulnerability struct {
 	ID               uint       `json:"id" gorm:"primary_key"`
 	Title            string     `json:"title" gorm:"type:varchar(255);not null"`
 	CVE              string     `json:"cve" gorm:"type:varchar(50);index"`
+	Fingerprint      string     `json:"fingerprint" gorm:"type:varchar(64);index"` // 扫描发现的指纹，用于导入时去重
 	Description      string     `json:"description" gorm:"type:text"`
 	Type             VulnType   `json:"type" gorm:"type:varchar(30);not null;default:'other'"` // 漏洞类型
 	Severity         Severity   `json:"severity" gorm:"type:varchar(20);not null"`
 	Status           VulnStatus `json:"status" gorm:"type:varchar(20);not null"`
+	IsRegression     bool       `json:"is_regression"` // 修复后再次出现的回归漏洞
 	References       string     `json:"references" gorm:"type:text"`
 	Solution         string     `json:"solution" gorm:"type:text"`
 	StepsToReproduce string     `json:"steps_to_reproduce" gorm:"type:text"` // 重现步骤
+	Location         string     `json:"location" gorm:"type:varchar(500)"`   // 发现位置，如 path/to/file.go:12-15、依赖清单文件或DAST请求
+	Identifiers      string     `json:"identifiers" gorm:"type:text"`        // 逗号分隔的标识符，如 CVE-2021-44228, CWE-502, A06:2021
+	Component        string     `json:"component" gorm:"type:varchar(255)"`  // 受影响的依赖包或系统软件包
+	ComponentVersion string     `json:"component_version" gorm:"type:varchar(100)"`
+	FixedVersion     string     `json:"fixed_version" gorm:"type:varchar(100)"`    // 修复了该漏洞的组件版本
+	Image            string     `json:"image" gorm:"type:varchar(255)"`            // 受影响的容器镜像
+	OperatingSystem  string     `json:"operating_system" gorm:"type:varchar(100)"` // 容器镜像的操作系统
+	Scanner          string     `json:"scanner" gorm:"type:varchar(100)"`          // 发现该漏洞的扫描器及其厂商
 	Vector           string     `json:"vector" gorm:"type:varchar(255)"`
 	CVSS             float64    `json:"cvss" gorm:"type:float"`
 	Assets           []Asset    `json:"assets" gorm:"many2many:vulnerability_assets;"`
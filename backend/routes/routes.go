@@ -28,6 +28,27 @@ func SetupRouter(r *gin.Engine) {
 		public.POST("/auth/login", userController.LoginV2)
 	}
 
+	// 扫描代理接口，通过代理令牌认证
+	agent := r.Group("/api/v1/agent")
+	agent.Use(middleware.AgentAuthMiddleware())
+	{
+		scanAgentController := new(controllers.ScanAgentController)
+		agent.POST("/register", scanAgentController.RegisterAgent)
+		agent.POST("/heartbeat", scanAgentController.AgentHeartbeat)
+		agent.GET("/jobs/poll", scanAgentController.PollAgentJob)
+		agent.POST("/jobs/:run_id/events", scanAgentController.ReportAgentEvents)
+		agent.POST("/jobs/:run_id/results", scanAgentController.UploadAgentResults)
+		agent.POST("/jobs/:run_id/complete", scanAgentController.CompleteAgentJob)
+	}
+
+	// 接收CI/CD扫描结果和查询质量门禁的接口，不需要登录，由接口通过 X-API-Key 认证
+	webhooks := r.Group("/api/v1/webhooks")
+	{
+		integrationController := new(controllers.IntegrationController)
+		webhooks.POST("/:type", integrationController.ReceiveScanResult)
+		webhooks.GET("/:type/gate", integrationController.GetGateDecision)
+	}
+
 	// 需要认证的路由组
 	authorized := r.Group("/api/v1")
 	authorized.Use(middleware.JWTAuthMiddleware())
@@ -59,6 +80,12 @@ func SetupRouter(r *gin.Engine) {
 		authorized.DELETE("/assets/:id", assetController.DeleteAsset)
 		authorized.GET("/assets/:id/vulnerabilities", assetController.GetAssetVulnerabilities)
 
+		// 软件物料清单路由
+		sbomController := new(controllers.SBOMController)
+		authorized.GET("/assets/:id/sboms", sbomController.ListAssetSBOMs)
+		authorized.GET("/sboms/:id/components", sbomController.ListSBOMComponents)
+		authorized.GET("/components", sbomController.SearchComponents)
+
 		// 漏洞管理路由
 		vulnerabilityController := new(controllers.VulnerabilityController)
 		authorized.GET("/vulnerabilities", vulnerabilityController.ListVulnerabilities)
@@ -121,6 +148,7 @@ func SetupRouter(r *gin.Engine) {
 		authorized.GET("/dashboard/asset-vuln-distribution", dashboardController.GetAssetVulnDistribution)
 		authorized.GET("/dashboard/priority-vulns", dashboardController.GetPriorityVulns)
 		authorized.GET("/dashboard/recent-activities", dashboardController.GetRecentActivities)
+		authorized.GET("/dashboard/failed-scans", dashboardController.GetFailedScans)
 
 		// 系统设置路由 (仅管理员访问)
 		settingsRouter := authorized.Group("/settings")
@@ -152,7 +180,56 @@ func SetupRouter(r *gin.Engine) {
 		authorized.POST("/scans/:id/start", scanController.StartScanTask)
 		authorized.POST("/scans/:id/cancel", scanController.CancelScanTask)
 		authorized.GET("/scans/:id/results", scanController.GetScanResults)
+		authorized.GET("/scans/:id/runs", scanController.ListScanRuns)
+		authorized.GET("/scans/:id/runs/:run_id/diff", scanController.DiffScanRun)
+		authorized.POST("/scans/:id/runs/:run_id/retry", scanController.RetryScanRun)
+		authorized.GET("/scans/:id/events", scanController.ListScanEvents)
+		authorized.GET("/scans/:id/stream", scanController.StreamScanEvents)
 		authorized.POST("/scans/:id/import", scanController.ImportScanResults)
+		authorized.GET("/scans/:id/targets", scanController.GetScanTargets)
+		authorized.POST("/scan-targets/preview", scanController.PreviewScanTargets)
+
+		// 扫描结果和漏洞的证据
+		evidenceController := new(controllers.EvidenceController)
+		authorized.GET("/scans/:id/results/:result_id/evidence", evidenceController.ListScanResultEvidence)
+		authorized.GET("/vulnerabilities/:id/evidence", evidenceController.ListVulnerabilityEvidence)
+		authorized.POST("/vulnerabilities/:id/evidence", evidenceController.UploadVulnerabilityEvidence)
+		authorized.GET("/evidence/:id", evidenceController.DownloadEvidence)
+		authorized.DELETE("/evidence/:id", evidenceController.DeleteEvidence)
+
+		// 自定义扫描器定义路由，普通用户只能查看，增删改仅管理员
+		customScannerController := new(controllers.CustomScannerController)
+		authorized.GET("/custom-scanners", customScannerController.ListCustomScanners)
+		authorized.GET("/custom-scanners/:id", customScannerController.GetCustomScanner)
+		admin.POST("/custom-scanners", customScannerController.CreateCustomScanner)
+		admin.PUT("/custom-scanners/:id", customScannerController.UpdateCustomScanner)
+		admin.DELETE("/custom-scanners/:id", customScannerController.DeleteCustomScanner)
+
+		// 扫描器连接配置
+		scannerProfileController := new(controllers.ScannerProfileController)
+		authorized.GET("/scanner-profiles", scannerProfileController.ListScannerProfiles)
+		authorized.GET("/scanner-profiles/:id", scannerProfileController.GetScannerProfile)
+		admin.POST("/scanner-profiles", scannerProfileController.CreateScannerProfile)
+		admin.PUT("/scanner-profiles/:id", scannerProfileController.UpdateScannerProfile)
+		admin.DELETE("/scanner-profiles/:id", scannerProfileController.DeleteScannerProfile)
+		admin.POST("/scanner-profiles/:id/test", scannerProfileController.TestScannerProfile)
+
+		// 扫描时间窗口，普通用户只能查看，增删改仅管理员
+		scanWindowController := new(controllers.ScanWindowController)
+		authorized.GET("/scan-windows", scanWindowController.ListScanWindows)
+		authorized.GET("/scan-windows/:id", scanWindowController.GetScanWindow)
+		admin.POST("/scan-windows", scanWindowController.CreateScanWindow)
+		admin.PUT("/scan-windows/:id", scanWindowController.UpdateScanWindow)
+		admin.DELETE("/scan-windows/:id", scanWindowController.DeleteScanWindow)
+
+		// 扫描代理管理，仅管理员
+		scanAgentController := new(controllers.ScanAgentController)
+		admin.GET("/scan-agents", scanAgentController.ListScanAgents)
+		admin.GET("/scan-agents/:id", scanAgentController.GetScanAgent)
+		admin.POST("/scan-agents", scanAgentController.CreateScanAgent)
+		admin.PUT("/scan-agents/:id", scanAgentController.UpdateScanAgent)
+		admin.DELETE("/scan-agents/:id", scanAgentController.DeleteScanAgent)
+		admin.POST("/scan-agents/:id/token", scanAgentController.RegenerateScanAgentToken)
 
 		// AI风险评估路由
 		authorized.POST("/ai/risk-assessment", controllers.PerformRiskAssessment)
@@ -172,8 +249,5 @@ func SetupRouter(r *gin.Engine) {
 			cicdGroup.POST("/:id/api-key/regenerate", integrationController.RegenerateAPIKey)
 			cicdGroup.GET("/:id/history", integrationController.GetIntegrationHistory)
 		}
-
-		// 接收CI/CD扫描结果的接口 - 不需要登录，通过API Key认证
-		authorized.POST("/webhooks/:type", integrationController.ReceiveScanResult)
 	}
 }
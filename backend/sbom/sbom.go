@@ -0,0 +1,252 @@
+// Package sbom 解析 CycloneDX 和 SPDX JSON 格式的软件物料清单
+package sbom
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 支持的SBOM格式
+const (
+	FormatCycloneDX = "cyclonedx"
+	FormatSPDX      = "spdx"
+)
+
+// ErrUnknownFormat 无法识别的SBOM格式
+var ErrUnknownFormat = errors.New("无法识别的SBOM格式，仅支持 CycloneDX JSON 和 SPDX JSON")
+
+// Component SBOM中的组件
+type Component struct {
+	Type     string
+	Group    string
+	Name     string
+	Version  string
+	PURL     string
+	Licenses []string
+}
+
+// Key 组件的版本无关标识，优先使用去掉版本的purl
+func (c Component) Key() string {
+	if c.PURL != "" {
+		return PURLWithoutVersion(c.PURL)
+	}
+	if c.Group != "" {
+		return c.Group + "/" + c.Name
+	}
+	return c.Name
+}
+
+// Document 解析后的SBOM
+type Document struct {
+	Format       string
+	SpecVersion  string
+	SerialNumber string
+	Name         string    // 文档名称
+	Subject      Component // SBOM描述的应用本身
+	Components   []Component
+}
+
+// Parse 根据内容识别格式并解析SBOM
+func Parse(data []byte) (*Document, error) {
+	var probe struct {
+		BOMFormat   string `json:"bomFormat"`
+		SPDXVersion string `json:"spdxVersion"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	}
+	switch {
+	case probe.BOMFormat == "CycloneDX":
+		return ParseCycloneDX(data)
+	case strings.HasPrefix(probe.SPDXVersion, "SPDX-"):
+		return ParseSPDX(data)
+	}
+	return nil, ErrUnknownFormat
+}
+
+type cdxLicense struct {
+	License struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"license"`
+	Expression string `json:"expression"`
+}
+
+type cdxComponent struct {
+	Type       string         `json:"type"`
+	Group      string         `json:"group"`
+	Name       string         `json:"name"`
+	Version    string         `json:"version"`
+	PURL       string         `json:"purl"`
+	Licenses   []cdxLicense   `json:"licenses"`
+	Components []cdxComponent `json:"components"`
+}
+
+func (c cdxComponent) component() Component {
+	var licenses []string
+	for _, l := range c.Licenses {
+		switch {
+		case l.Expression != "":
+			licenses = append(licenses, l.Expression)
+		case l.License.ID != "":
+			licenses = append(licenses, l.License.ID)
+		case l.License.Name != "":
+			licenses = append(licenses, l.License.Name)
+		}
+	}
+	return Component{
+		Type:     c.Type,
+		Group:    c.Group,
+		Name:     c.Name,
+		Version:  c.Version,
+		PURL:     c.PURL,
+		Licenses: licenses,
+	}
+}
+
+// ParseCycloneDX 解析 CycloneDX JSON，嵌套的子组件展开为平铺的列表
+func ParseCycloneDX(data []byte) (*Document, error) {
+	var bom struct {
+		BOMFormat    string `json:"bomFormat"`
+		SpecVersion  string `json:"specVersion"`
+		SerialNumber string `json:"serialNumber"`
+		Metadata     struct {
+			Component cdxComponent `json:"component"`
+		} `json:"metadata"`
+		Components []cdxComponent `json:"components"`
+	}
+	if err := json.Unmarshal(data, &bom); err != nil {
+		return nil, err
+	}
+	if bom.BOMFormat != "CycloneDX" {
+		return nil, ErrUnknownFormat
+	}
+
+	doc := &Document{
+		Format:       FormatCycloneDX,
+		SpecVersion:  bom.SpecVersion,
+		SerialNumber: bom.SerialNumber,
+		Name:         bom.Metadata.Component.Name,
+		Subject:      bom.Metadata.Component.component(),
+	}
+	var walk func([]cdxComponent)
+	walk = func(components []cdxComponent) {
+		for _, c := range components {
+			if c.Name != "" {
+				doc.Components = append(doc.Components, c.component())
+			}
+			walk(c.Components)
+		}
+	}
+	walk(bom.Components)
+	return doc, nil
+}
+
+// ParseSPDX 解析 SPDX 2.x JSON，documentDescribes 或 DESCRIBES 关系指向的包作为应用本身，其余包作为组件
+func ParseSPDX(data []byte) (*Document, error) {
+	var spdx struct {
+		SPDXVersion       string   `json:"spdxVersion"`
+		SPDXID            string   `json:"SPDXID"`
+		Name              string   `json:"name"`
+		DocumentNamespace string   `json:"documentNamespace"`
+		DocumentDescribes []string `json:"documentDescribes"`
+		Packages          []struct {
+			SPDXID           string `json:"SPDXID"`
+			Name             string `json:"name"`
+			VersionInfo      string `json:"versionInfo"`
+			Supplier         string `json:"supplier"`
+			PrimaryPurpose   string `json:"primaryPackagePurpose"`
+			LicenseConcluded string `json:"licenseConcluded"`
+			LicenseDeclared  string `json:"licenseDeclared"`
+			ExternalRefs     []struct {
+				ReferenceType    string `json:"referenceType"`
+				ReferenceLocator string `json:"referenceLocator"`
+			} `json:"externalRefs"`
+		} `json:"packages"`
+		Relationships []struct {
+			Element string `json:"spdxElementId"`
+			Type    string `json:"relationshipType"`
+			Related string `json:"relatedSpdxElement"`
+		} `json:"relationships"`
+	}
+	if err := json.Unmarshal(data, &spdx); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(spdx.SPDXVersion, "SPDX-") {
+		return nil, ErrUnknownFormat
+	}
+
+	described := make(map[string]bool)
+	for _, id := range spdx.DocumentDescribes {
+		described[id] = true
+	}
+	for _, r := range spdx.Relationships {
+		if r.Element == spdx.SPDXID && r.Type == "DESCRIBES" {
+			described[r.Related] = true
+		}
+	}
+
+	doc := &Document{
+		Format:       FormatSPDX,
+		SpecVersion:  strings.TrimPrefix(spdx.SPDXVersion, "SPDX-"),
+		SerialNumber: spdx.DocumentNamespace,
+		Name:         spdx.Name,
+	}
+	for _, p := range spdx.Packages {
+		c := Component{
+			Type:    strings.ToLower(strings.Replace(p.PrimaryPurpose, "_", "-", -1)),
+			Name:    p.Name,
+			Version: p.VersionInfo,
+		}
+		for _, ref := range p.ExternalRefs {
+			if ref.ReferenceType == "purl" {
+				c.PURL = ref.ReferenceLocator
+				break
+			}
+		}
+		license := p.LicenseConcluded
+		if !spdxLicenseSet(license) {
+			license = p.LicenseDeclared
+		}
+		if spdxLicenseSet(license) {
+			c.Licenses = []string{license}
+		}
+
+		if described[p.SPDXID] && doc.Subject.Name == "" {
+			doc.Subject = c
+			continue
+		}
+		if c.Name != "" {
+			doc.Components = append(doc.Components, c)
+		}
+	}
+	return doc, nil
+}
+
+// spdxLicenseSet 判断SPDX许可证字段是否有值，NOASSERTION 和 NONE 视为未设置
+func spdxLicenseSet(license string) bool {
+	return license != "" && license != "NOASSERTION" && license != "NONE"
+}
+
+// PURLWithoutVersion 去掉purl中的版本、限定符和子路径，如
+// pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1?type=jar 返回 pkg:maven/org.apache.logging.log4j/log4j-core
+func PURLWithoutVersion(purl string) string {
+	if i := strings.IndexAny(purl, "?#"); i >= 0 {
+		purl = purl[:i]
+	}
+	if i := strings.LastIndex(purl, "@"); i > strings.LastIndex(purl, "/") {
+		purl = purl[:i]
+	}
+	return strings.ToLower(purl)
+}
+
+// Describe 返回组件的可读名称，如 org.apache.logging.log4j/log4j-core 2.14.1
+func Describe(c Component) string {
+	name := c.Name
+	if c.Group != "" {
+		name = c.Group + "/" + c.Name
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s %s", name, c.Version))
+}
@@ -0,0 +1,208 @@
+package sbom
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// CompareVersions 比较两个版本号，a<b 返回 -1，相等返回 0，a>b 返回 1
+//
+// 版本号按数字段和字母段逐段比较，数字段按数值比较，缺少的段按 0 补齐；较长的版本号多出的部分以字母开头时
+// 视为预发布版本，因此 2.0-beta9 < 2.0 = 2.0.0 < 2.0.1。前缀 v 和构建元数据（+ 之后的内容）被忽略。
+func CompareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		// 较短的版本号缺少的段按 0 补齐，对方在这里是字母段时说明对方是预发布版本
+		x, y := "0", "0"
+		if i < len(pa) {
+			x = pa[i]
+		} else if isAlphaPart(pb[i]) {
+			return 1
+		}
+		if i < len(pb) {
+			y = pb[i]
+		} else if isAlphaPart(pa[i]) {
+			return -1
+		}
+		if c := compareVersionPart(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// versionParts 将版本号拆分为数字段和字母段，分隔符本身不参与比较
+func versionParts(v string) []string {
+	v = strings.ToLower(strings.TrimSpace(v))
+	if i := strings.Index(v, "+"); i >= 0 {
+		v = v[:i]
+	}
+	v = strings.TrimPrefix(v, "v")
+
+	var parts []string
+	start := -1
+	digit := false
+	for i, r := range v {
+		isDigit := unicode.IsDigit(r)
+		if !isDigit && !unicode.IsLetter(r) {
+			if start >= 0 {
+				parts = append(parts, v[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start >= 0 && isDigit != digit {
+			parts = append(parts, v[start:i])
+			start = -1
+		}
+		if start < 0 {
+			start = i
+			digit = isDigit
+		}
+	}
+	if start >= 0 {
+		parts = append(parts, v[start:])
+	}
+	return parts
+}
+
+func isAlphaPart(p string) bool {
+	return p != "" && !unicode.IsDigit(rune(p[0]))
+}
+
+func compareVersionPart(a, b string) int {
+	na, errA := strconv.ParseUint(a, 10, 64)
+	nb, errB := strconv.ParseUint(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		if na < nb {
+			return -1
+		} else if na > nb {
+			return 1
+		}
+		return 0
+	case errA == nil:
+		// 数字段大于字母段：2.0.1 > 2.0.beta
+		return 1
+	case errB == nil:
+		return -1
+	}
+	return strings.Compare(a, b)
+}
+
+// InRange 判断版本是否落在受影响版本范围内，ok 为 false 表示范围无法解析
+//
+// 范围由 "||"、";" 或换行分隔的多个区间组成，满足任一区间即受影响。区间由逗号或空白分隔的约束组成，
+// 约束的形式为 <、<=、>、>=、=、!= 加版本号；不带运算符的版本号表示精确匹配，以 .x 或 .* 结尾表示前缀匹配；
+// "a - b" 表示闭区间。例如 ">=2.0-beta9, <2.15.0 || 2.3.x"。
+func InRange(version, ranges string) (affected bool, ok bool) {
+	if strings.TrimSpace(version) == "" {
+		return false, false
+	}
+	replacer := strings.NewReplacer("||", "\n", ";", "\n")
+	for _, interval := range strings.Split(replacer.Replace(ranges), "\n") {
+		interval = strings.TrimSpace(interval)
+		if interval == "" {
+			continue
+		}
+		match, valid := inInterval(version, interval)
+		if !valid {
+			return false, false
+		}
+		ok = true
+		if match {
+			return true, true
+		}
+	}
+	return false, ok
+}
+
+// inInterval 判断版本是否满足区间内的全部约束
+func inInterval(version, interval string) (bool, bool) {
+	if lo, hi, found := cutHyphenRange(interval); found {
+		return CompareVersions(version, lo) >= 0 && CompareVersions(version, hi) <= 0, true
+	}
+
+	fields := strings.FieldsFunc(interval, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	// 运算符和版本号之间允许有空格，如 ">= 2.0"
+	var constraints []string
+	for i := 0; i < len(fields); i++ {
+		if strings.Trim(fields[i], "<>=!") == "" && i+1 < len(fields) {
+			constraints = append(constraints, fields[i]+fields[i+1])
+			i++
+			continue
+		}
+		constraints = append(constraints, fields[i])
+	}
+	if len(constraints) == 0 {
+		return false, false
+	}
+
+	for _, c := range constraints {
+		op := c[:len(c)-len(strings.TrimLeft(c, "<>=!"))]
+		target := strings.TrimPrefix(c, op)
+		// ~> ^ 等不支持的运算符视为无法解析，不能当作精确匹配
+		if target == "" || !unicode.IsLetter(rune(target[0])) && !unicode.IsDigit(rune(target[0])) {
+			return false, false
+		}
+		cmp := CompareVersions(version, target)
+		var match bool
+		switch op {
+		case "<":
+			match = cmp < 0
+		case "<=":
+			match = cmp <= 0
+		case ">":
+			match = cmp > 0
+		case ">=":
+			match = cmp >= 0
+		case "!=":
+			match = cmp != 0
+		case "", "=", "==":
+			match = matchExact(version, target)
+		default:
+			return false, false
+		}
+		if !match {
+			return false, true
+		}
+	}
+	return true, true
+}
+
+// cutHyphenRange 解析 "a - b" 形式的闭区间，要求连字符两侧有空格，避免与 2.0-beta9 这类版本号混淆
+func cutHyphenRange(interval string) (string, string, bool) {
+	i := strings.Index(interval, " - ")
+	if i < 0 {
+		return "", "", false
+	}
+	lo, hi := strings.TrimSpace(interval[:i]), strings.TrimSpace(interval[i+3:])
+	if lo == "" || hi == "" {
+		return "", "", false
+	}
+	return lo, hi, true
+}
+
+// matchExact 精确匹配版本号，以 .x 或 .* 结尾时按前缀匹配
+func matchExact(version, target string) bool {
+	lower := strings.ToLower(target)
+	for _, suffix := range []string{".x", ".*"} {
+		if strings.HasSuffix(lower, suffix) {
+			prefix := versionParts(strings.TrimSuffix(lower, suffix))
+			parts := versionParts(version)
+			if len(parts) < len(prefix) {
+				return false
+			}
+			for i := range prefix {
+				if compareVersionPart(parts[i], prefix[i]) != 0 {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return CompareVersions(version, target) == 0
+}
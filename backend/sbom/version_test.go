@@ -0,0 +1,59 @@
+package sbom
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"2.14.1", "2.14.1", 0},
+		{"2.0", "2.0.0", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2.3+build.5", "1.2.3", 0},
+		{"2.14.1", "2.15.0", -1},
+		{"2.10", "2.9", 1},
+		{"2.0-beta9", "2.0", -1},
+		{"2.0-beta9", "2.0-rc1", -1},
+		{"2.0.1", "2.0.beta", 1},
+		{"1.0.0", "1.0.0.1", -1},
+	}
+	for _, c := range cases {
+		if got := CompareVersions(c.a, c.b); got != c.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+		if got := CompareVersions(c.b, c.a); got != -c.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", c.b, c.a, got, -c.want)
+		}
+	}
+}
+
+func TestInRange(t *testing.T) {
+	cases := []struct {
+		version, ranges string
+		affected, ok    bool
+	}{
+		{"2.14.1", ">=2.0-beta9, <2.15.0", true, true},
+		{"2.15.0", ">=2.0-beta9, <2.15.0", false, true},
+		{"2.0-beta9", ">=2.0-beta9 <2.15.0", true, true},
+		{"1.9", ">= 2.0, < 3.0", false, true},
+		{"2.3.2", "<2.0 || 2.3.x", true, true},
+		{"2.4.0", "<2.0 || 2.3.x", false, true},
+		{"2.3.1", "2.3.*", true, true},
+		{"1.2.3", "1.2.3", true, true},
+		{"1.2.3", "=1.2.4; ==1.2.3", true, true},
+		{"1.2.3", "!=1.2.3", false, true},
+		{"1.5", "1.0 - 2.0", true, true},
+		{"2.0.1", "1.0 - 2.0", false, true},
+		{"2.0", "", false, false},
+		{"", "<2.0", false, false},
+		{"2.0", "~>2.0", false, false},
+		{"2.0", "<", false, false},
+	}
+	for _, c := range cases {
+		affected, ok := InRange(c.version, c.ranges)
+		if affected != c.affected || ok != c.ok {
+			t.Errorf("InRange(%q, %q) = (%v, %v), want (%v, %v)", c.version, c.ranges, affected, ok, c.affected, c.ok)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package scanner
+
+import "github.com/vulnark/vulnark/models"
+
+// 扫描代理与服务端之间的接口数据，扫描代理通过 Authorization: Bearer {代理令牌} 访问 /api/v1/agent 下的接口：
+//
+//	POST /agent/register            注册，上报主机信息和支持的扫描器类型
+//	POST /agent/heartbeat           心跳，返回需要取消的扫描
+//	GET  /agent/jobs/poll?wait=30   长轮询领取本网络区域的扫描作业，没有作业时返回204
+//	POST /agent/jobs/:run_id/events   上报扫描日志和进度
+//	POST /agent/jobs/:run_id/results  分批上传扫描结果
+//	POST /agent/jobs/:run_id/complete 上报扫描结束
+
+// AgentRegistration 扫描代理注册和心跳时上报的信息
+type AgentRegistration struct {
+	Hostname     string               `json:"hostname"`
+	Version      string               `json:"version"`
+	ScannerTypes []models.ScannerType `json:"scanner_types"`
+	RunningRuns  []uint               `json:"running_runs"` // 正在执行的执行记录ID
+}
+
+// AgentConfig 服务端下发的扫描代理运行参数
+type AgentConfig struct {
+	AgentID           uint   `json:"agent_id"`
+	Name              string `json:"name"`
+	Zone              string `json:"zone"`
+	HeartbeatInterval int    `json:"heartbeat_interval"` // 心跳间隔（秒）
+	PollWait          int    `json:"poll_wait"`          // 长轮询最长等待时间（秒）
+	PollInterval      int    `json:"poll_interval"`      // 扫描状态轮询间隔（秒）
+	ResultBatchSize   int    `json:"result_batch_size"`  // 每批上传的最大结果数
+}
+
+// AgentCancel 需要扫描代理取消的扫描
+type AgentCancel struct {
+	RunID       uint `json:"run_id"`
+	KeepPartial bool `json:"keep_partial"`
+}
+
+// AgentHeartbeatResponse 心跳响应
+type AgentHeartbeatResponse struct {
+	Cancel []AgentCancel `json:"cancel"`
+}
+
+// AgentJob 扫描代理领取的扫描作业，Job 中包含扫描器凭据，代理不应落盘保存
+type AgentJob struct {
+	TaskID  uint               `json:"task_id"`
+	RunID   uint               `json:"run_id"`
+	Trigger models.ScanTrigger `json:"trigger"`
+	Job     *Job               `json:"job"`
+}
+
+// AgentEvent 扫描代理上报的扫描事件，只支持日志和进度事件
+type AgentEvent struct {
+	Type     models.ScanEventType `json:"type"`
+	Progress int                  `json:"progress"`
+	Message  string               `json:"message"`
+}
+
+// AgentEventBatch 一批扫描事件
+type AgentEventBatch struct {
+	Events []AgentEvent `json:"events"`
+}
+
+// AgentEventResponse 上报事件的响应，扫描已被取消时 Cancel 不为空
+type AgentEventResponse struct {
+	Cancel *AgentCancel `json:"cancel,omitempty"`
+}
+
+// AgentResultBatch 一批扫描结果
+type AgentResultBatch struct {
+	Results []models.ScanResult `json:"results"`
+}
+
+// AgentCompletion 扫描代理上报的扫描结束状态，结果需在此之前全部上传
+type AgentCompletion struct {
+	State         State                    `json:"state"` // completed、failed 或 cancelled
+	Progress      int                      `json:"progress"`
+	Error         string                   `json:"error"`
+	FailureReason models.ScanFailureReason `json:"failure_reason"` // 失败原因分类，见 ClassifyError
+	ScanLog       string                   `json:"scan_log"`
+}
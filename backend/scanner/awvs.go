@@ -0,0 +1,480 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/vulnark/vulnark/models"
+)
+
+// awvsFullScanProfile AWVS内置的“Full Scan”扫描配置ID
+const awvsFullScanProfile = "11111111-1111-1111-1111-111111111111"
+
+// AwvsDriver Acunetix/AWVS API 扫描器驱动
+//
+// ScannerURL 填写API根地址（如 https://awvs:3443/api/v1，省略 /api/v1 时自动补全），
+// ScannerAPIKey 填写AWVS个人资料中生成的API Key。
+// TargetURLs 中的每个地址创建一个AWVS目标并单独发起扫描，扫描ID形如
+// "目标ID:扫描ID,目标ID:扫描ID"。
+//
+// 支持的扫描参数：
+//
+//	profile_id     扫描配置ID，默认 Full Scan
+//	criticality    目标重要性(0/10/20/30)，默认 10
+//	delete_target  扫描结束（获取结果、取消或失败）后是否删除AWVS上的目标，默认 false
+//	include_info   是否导入信息级别的漏洞，默认 false
+type AwvsDriver struct{}
+
+func init() {
+	Register(models.ScannerTypeAwvs, AwvsDriver{})
+}
+
+// awvsScanRef 驱动扫描ID中的一对目标ID和扫描ID
+type awvsScanRef struct {
+	TargetID string
+	ScanID   string
+}
+
+// Launch 为每个目标地址创建AWVS目标并启动扫描
+func (d AwvsDriver) Launch(ctx context.Context, job *Job) (string, error) {
+	if job.ScannerURL == "" {
+		return "", errors.New("未配置AWVS API地址")
+	}
+	if job.APIKey == "" {
+		return "", errors.New("未配置AWVS API Key")
+	}
+	if len(job.TargetURLs) == 0 {
+		return "", errors.New("AWVS扫描需要至少一个目标URL")
+	}
+
+	profileID := job.StringParam("profile_id", awvsFullScanProfile)
+
+	var refs []awvsScanRef
+	for _, target := range job.TargetURLs {
+		var created struct {
+			TargetID string `json:"target_id"`
+		}
+		err := d.do(ctx, job, Request{
+			Method: http.MethodPost,
+			URL:    "/targets",
+			Body: map[string]interface{}{
+				"address":     target,
+				"description": fmt.Sprintf("VulnArk scan task %d", job.TaskID),
+				"type":        "default",
+				"criticality": job.IntParam("criticality", 10),
+			},
+			Result: &created,
+		})
+		if err != nil {
+			d.cleanup(ctx, job, refs)
+			return "", fmt.Errorf("创建AWVS目标失败(%s): %w", target, err)
+		}
+
+		var scan struct {
+			ScanID string `json:"scan_id"`
+		}
+		var headers http.Header
+		err = d.do(ctx, job, Request{
+			Method: http.MethodPost,
+			URL:    "/scans",
+			Body: map[string]interface{}{
+				"target_id":  created.TargetID,
+				"profile_id": profileID,
+				"schedule": map[string]interface{}{
+					"disable":        false,
+					"start_date":     nil,
+					"time_sensitive": false,
+				},
+			},
+			Result:  &scan,
+			Headers: &headers,
+		})
+		if err != nil {
+			d.cleanup(ctx, job, append(refs, awvsScanRef{TargetID: created.TargetID}))
+			return "", fmt.Errorf("启动AWVS扫描失败(%s): %w", target, err)
+		}
+
+		// 部分版本只在 Location 头中返回扫描ID
+		scanID := scan.ScanID
+		if scanID == "" {
+			scanID = path.Base(headers.Get("Location"))
+		}
+		if scanID == "" || scanID == "." || scanID == "/" {
+			d.cleanup(ctx, job, append(refs, awvsScanRef{TargetID: created.TargetID}))
+			return "", fmt.Errorf("启动AWVS扫描失败(%s): 未返回扫描ID", target)
+		}
+
+		job.Log("AWVS扫描已启动: target=%s, target_id=%s, scan_id=%s", target, created.TargetID, scanID)
+		refs = append(refs, awvsScanRef{TargetID: created.TargetID, ScanID: scanID})
+	}
+
+	return encodeAwvsRefs(refs), nil
+}
+
+// Status 汇总所有AWVS扫描的状态
+func (d AwvsDriver) Status(ctx context.Context, job *Job, scanID string) (*Status, error) {
+	refs, err := decodeAwvsRefs(scanID)
+	if err != nil {
+		return nil, err
+	}
+
+	progress, completed := 0, 0
+	var messages []string
+	for _, ref := range refs {
+		session, err := d.session(ctx, job, ref.ScanID)
+		if err != nil {
+			return nil, err
+		}
+
+		switch session.Status {
+		case "completed":
+			completed++
+			progress += 100
+		case "failed":
+			d.stopRemaining(ctx, job, refs, ref)
+			return &Status{State: StateFailed, Message: "AWVS扫描失败: scan_id=" + ref.ScanID}, nil
+		case "aborted":
+			d.stopRemaining(ctx, job, refs, ref)
+			return &Status{State: StateCancelled, Message: "AWVS扫描已中止: scan_id=" + ref.ScanID}, nil
+		default:
+			progress += session.Progress
+		}
+		messages = append(messages, fmt.Sprintf("%s:%s(%d%%)", ref.ScanID, session.Status, session.Progress))
+	}
+
+	status := &Status{
+		State:    StateRunning,
+		Progress: progress / len(refs),
+		Message:  strings.Join(messages, ", "),
+	}
+	if completed == len(refs) {
+		status.State = StateCompleted
+		status.Progress = 100
+	}
+	return status, nil
+}
+
+// Cancel 中止所有AWVS扫描
+func (d AwvsDriver) Cancel(ctx context.Context, job *Job, scanID string) error {
+	refs, err := decodeAwvsRefs(scanID)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, ref := range refs {
+		err := d.do(ctx, job, Request{
+			Method: http.MethodPost,
+			URL:    "/scans/" + ref.ScanID + "/abort",
+		})
+		if err != nil {
+			lastErr = err
+		}
+	}
+
+	if job.BoolParam("delete_target", false) {
+		d.cleanup(ctx, job, refs)
+	}
+	return lastErr
+}
+
+// stopRemaining 某个扫描失败或被中止后，整个扫描不会再调用 Results 或 Cancel，
+// 在这里中止其余目标的扫描，并按 delete_target 参数删除创建的目标
+func (d AwvsDriver) stopRemaining(ctx context.Context, job *Job, refs []awvsScanRef, ended awvsScanRef) {
+	for _, ref := range refs {
+		if ref.ScanID == ended.ScanID {
+			continue
+		}
+		// 其余扫描可能已经结束，中止失败不影响后续清理
+		d.do(ctx, job, Request{
+			Method: http.MethodPost,
+			URL:    "/scans/" + ref.ScanID + "/abort",
+		})
+	}
+
+	if job.BoolParam("delete_target", false) {
+		d.cleanup(ctx, job, refs)
+	}
+}
+
+// Results 获取所有AWVS扫描发现的漏洞及其请求/响应详情
+func (d AwvsDriver) Results(ctx context.Context, job *Job, scanID string) ([]models.ScanResult, error) {
+	refs, err := decodeAwvsRefs(scanID)
+	if err != nil {
+		return nil, err
+	}
+
+	includeInfo := job.BoolParam("include_info", false)
+
+	var results []models.ScanResult
+	for _, ref := range refs {
+		session, err := d.session(ctx, job, ref.ScanID)
+		if err != nil {
+			return nil, err
+		}
+		base := "/scans/" + ref.ScanID + "/results/" + session.SessionID + "/vulnerabilities"
+
+		cursor := ""
+		for {
+			query := url.Values{"l": {"100"}}
+			if cursor != "" {
+				query.Set("c", cursor)
+			}
+
+			var page struct {
+				Vulnerabilities []struct {
+					VulnID   string `json:"vuln_id"`
+					Severity int    `json:"severity"`
+				} `json:"vulnerabilities"`
+				Pagination struct {
+					NextCursor interface{} `json:"next_cursor"`
+				} `json:"pagination"`
+			}
+			err := d.do(ctx, job, Request{
+				Method: http.MethodGet,
+				URL:    base + "?" + query.Encode(),
+				Result: &page,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("获取AWVS漏洞列表失败: %w", err)
+			}
+
+			for _, item := range page.Vulnerabilities {
+				if item.Severity == 0 && !includeInfo {
+					continue
+				}
+				result, err := d.vulnerability(ctx, job, base, item.VulnID)
+				if err != nil {
+					return nil, err
+				}
+				results = append(results, result)
+			}
+
+			cursor = ""
+			if next := page.Pagination.NextCursor; next != nil {
+				cursor = fmt.Sprint(next)
+			}
+			if cursor == "" || len(page.Vulnerabilities) == 0 {
+				break
+			}
+		}
+	}
+
+	if job.BoolParam("delete_target", false) {
+		d.cleanup(ctx, job, refs)
+	}
+
+	return results, nil
+}
+
+// awvsSession 扫描的当前会话
+type awvsSession struct {
+	SessionID string `json:"scan_session_id"`
+	Status    string `json:"status"`
+	Progress  int    `json:"progress"`
+}
+
+// session 获取扫描的当前会话信息
+func (d AwvsDriver) session(ctx context.Context, job *Job, scanID string) (*awvsSession, error) {
+	var scan struct {
+		CurrentSession awvsSession `json:"current_session"`
+	}
+	err := d.do(ctx, job, Request{
+		Method: http.MethodGet,
+		URL:    "/scans/" + scanID,
+		Result: &scan,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("查询AWVS扫描状态失败: %w", err)
+	}
+	return &scan.CurrentSession, nil
+}
+
+// vulnerability 获取单个漏洞的详情，包括HTTP请求和响应
+func (d AwvsDriver) vulnerability(ctx context.Context, job *Job, base, vulnID string) (models.ScanResult, error) {
+	var vuln struct {
+		VtName         string   `json:"vt_name"`
+		Severity       int      `json:"severity"`
+		AffectsURL     string   `json:"affects_url"`
+		AffectsDetail  string   `json:"affects_detail"`
+		Description    string   `json:"description"`
+		LongDesc       string   `json:"long_description"`
+		Impact         string   `json:"impact"`
+		Recommendation string   `json:"recommendation"`
+		Details        string   `json:"details"`
+		Request        string   `json:"request"`
+		ResponseInfo   bool     `json:"response_info"`
+		CVSSScore      float64  `json:"cvss_score"`
+		Tags           []string `json:"tags"`
+		References     []struct {
+			Rel  string `json:"rel"`
+			Href string `json:"href"`
+		} `json:"references"`
+	}
+	err := d.do(ctx, job, Request{
+		Method: http.MethodGet,
+		URL:    base + "/" + vulnID,
+		Result: &vuln,
+	})
+	if err != nil {
+		return models.ScanResult{}, fmt.Errorf("获取AWVS漏洞详情失败: %w", err)
+	}
+
+	var response []byte
+	if vuln.ResponseInfo {
+		var raw []byte
+		err := d.do(ctx, job, Request{
+			Method:  http.MethodGet,
+			URL:     base + "/" + vulnID + "/http_response",
+			RawBody: &raw,
+		})
+		if err != nil {
+			job.Log("获取AWVS漏洞HTTP响应失败: vuln_id=%s, err=%v", vulnID, err)
+		} else {
+			response = raw
+		}
+	}
+
+	// 详情中保留HTTP请求，完整的请求和响应作为证据保存
+	var detail []string
+	if vuln.AffectsDetail != "" {
+		detail = append(detail, "Affects: "+vuln.AffectsDetail)
+	}
+	if vuln.Details != "" {
+		detail = append(detail, "Details:\n"+vuln.Details)
+	}
+	if vuln.Request != "" {
+		detail = append(detail, "Request:\n"+truncate(vuln.Request, maxDetailSize))
+	}
+	var evidence []models.ScanEvidence
+	if vuln.Request != "" {
+		evidence = append(evidence, HTTPRequestEvidence([]byte(vuln.Request)))
+	}
+	if len(response) > 0 {
+		evidence = append(evidence, HTTPResponseEvidence(response))
+	}
+
+	description := vuln.Description
+	if vuln.Impact != "" {
+		description += "\n\nImpact:\n" + vuln.Impact
+	}
+
+	var references []string
+	for _, ref := range vuln.References {
+		references = append(references, strings.TrimSpace(ref.Rel+" "+ref.Href))
+	}
+
+	category, cve := "", ""
+	for _, tag := range vuln.Tags {
+		upper := strings.ToUpper(tag)
+		switch {
+		case category == "" && strings.HasPrefix(upper, "CWE-"):
+			category = upper
+		case cve == "" && strings.HasPrefix(upper, "CVE-"):
+			cve = upper
+		}
+	}
+
+	return models.ScanResult{
+		VulnerabilityName: vuln.VtName,
+		Description:       strings.TrimSpace(description),
+		Severity:          awvsSeverity(vuln.Severity),
+		AffectedURL:       truncate(vuln.AffectsURL, 255),
+		Detail:            strings.Join(detail, "\n\n"),
+		Category:          category,
+		CVE:               cve,
+		CVSS:              vuln.CVSSScore,
+		Solution:          vuln.Recommendation,
+		References:        strings.Join(references, "\n"),
+		Evidence:          evidence,
+	}, nil
+}
+
+// cleanup 删除AWVS上的目标（同时删除其扫描）
+func (d AwvsDriver) cleanup(ctx context.Context, job *Job, refs []awvsScanRef) {
+	for _, ref := range refs {
+		if ref.TargetID == "" {
+			continue
+		}
+		err := d.do(ctx, job, Request{
+			Method: http.MethodDelete,
+			URL:    "/targets/" + ref.TargetID,
+		})
+		if err != nil {
+			job.Log("删除AWVS目标失败: target_id=%s, err=%v", ref.TargetID, err)
+		}
+	}
+}
+
+// TestConnection 读取当前API Key对应的账号以确认API地址和API Key可用
+func (d AwvsDriver) TestConnection(ctx context.Context, job *Job) (string, error) {
+	if job.ScannerURL == "" {
+		return "", errors.New("未配置AWVS API地址")
+	}
+	if job.APIKey == "" {
+		return "", errors.New("未配置AWVS API Key")
+	}
+
+	var me struct {
+		Email string `json:"email"`
+	}
+	if err := d.do(ctx, job, Request{Method: http.MethodGet, URL: "/me", Result: &me}); err != nil {
+		return "", fmt.Errorf("连接AWVS失败: %w", err)
+	}
+	return "AWVS 账号 " + me.Email, nil
+}
+
+// do 发送带API Key的请求，Request.URL 为相对API根地址的路径
+func (d AwvsDriver) do(ctx context.Context, job *Job, r Request) error {
+	base := job.ScannerURL
+	if !strings.HasSuffix(base, "/api/v1") {
+		base += "/api/v1"
+	}
+	r.URL = base + r.URL
+	r.Header = map[string]string{"X-Auth": job.APIKey}
+
+	return Do(ctx, NewHTTPClient(job), r)
+}
+
+// encodeAwvsRefs 将目标ID和扫描ID编码为驱动扫描ID
+func encodeAwvsRefs(refs []awvsScanRef) string {
+	parts := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		parts = append(parts, ref.TargetID+":"+ref.ScanID)
+	}
+	return strings.Join(parts, ",")
+}
+
+// decodeAwvsRefs 解析驱动扫描ID
+func decodeAwvsRefs(scanID string) ([]awvsScanRef, error) {
+	var refs []awvsScanRef
+	for _, part := range strings.Split(scanID, ",") {
+		ids := strings.SplitN(part, ":", 2)
+		if len(ids) != 2 || ids[0] == "" || ids[1] == "" {
+			return nil, fmt.Errorf("无效的AWVS扫描ID: %s", scanID)
+		}
+		refs = append(refs, awvsScanRef{TargetID: ids[0], ScanID: ids[1]})
+	}
+	return refs, nil
+}
+
+// awvsSeverity 将AWVS严重程度映射为系统严重程度
+func awvsSeverity(severity int) models.Severity {
+	switch severity {
+	case 4:
+		return models.SeverityCritical
+	case 3:
+		return models.SeverityHigh
+	case 2:
+		return models.SeverityMedium
+	case 1:
+		return models.SeverityLow
+	default:
+		return models.SeverityInfo
+	}
+}
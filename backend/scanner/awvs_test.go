@@ -0,0 +1,205 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vulnark/vulnark/models"
+)
+
+// fakeAWVS 模拟AWVS API，第n个目标的目标ID为 tn、扫描ID为 sn
+type fakeAWVS struct {
+	mu         sync.Mutex
+	statuses   map[string]string // 扫描ID -> 扫描状态，未设置时为 completed
+	failTarget string            // 创建该地址的目标时返回错误
+	targets    []string
+	deleted    []string
+	aborted    []string
+	authKeys   []string
+}
+
+func (f *fakeAWVS) handler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.authKeys = append(f.authKeys, r.Header.Get("X-Auth"))
+
+		path := strings.TrimPrefix(r.URL.Path, "/api/v1")
+		parts := strings.Split(strings.Trim(path, "/"), "/")
+		var resp interface{}
+		switch {
+		case r.Method == http.MethodPost && path == "/targets":
+			var body struct {
+				Address string `json:"address"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("解析创建目标请求失败: %v", err)
+			}
+			if body.Address == f.failTarget {
+				http.Error(w, `{"message":"invalid address"}`, http.StatusUnprocessableEntity)
+				return
+			}
+			f.targets = append(f.targets, body.Address)
+			resp = map[string]string{"target_id": "t" + strconv.Itoa(len(f.targets))}
+		case r.Method == http.MethodPost && path == "/scans":
+			var body struct {
+				TargetID string `json:"target_id"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			// 扫描ID只在 Location 头中返回
+			w.Header().Set("Location", "/api/v1/scans/s"+strings.TrimPrefix(body.TargetID, "t"))
+			w.WriteHeader(http.StatusCreated)
+			return
+		case r.Method == http.MethodDelete && len(parts) == 2 && parts[0] == "targets":
+			f.deleted = append(f.deleted, parts[1])
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case r.Method == http.MethodPost && len(parts) == 3 && parts[2] == "abort":
+			f.aborted = append(f.aborted, parts[1])
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case len(parts) == 2 && parts[0] == "scans":
+			status := f.statuses[parts[1]]
+			if status == "" {
+				status = "completed"
+			}
+			resp = map[string]interface{}{"current_session": map[string]interface{}{
+				"scan_session_id": "sess-" + parts[1], "status": status, "progress": 40,
+			}}
+		case len(parts) == 5 && parts[4] == "vulnerabilities":
+			resp = map[string]interface{}{
+				"vulnerabilities": []map[string]interface{}{
+					{"vuln_id": "v-" + parts[1], "severity": 3},
+					{"vuln_id": "info-" + parts[1], "severity": 0},
+				},
+				"pagination": map[string]interface{}{"next_cursor": nil},
+			}
+		case len(parts) == 6 && parts[4] == "vulnerabilities":
+			resp = map[string]interface{}{
+				"vt_name":        "Cross site scripting",
+				"severity":       3,
+				"affects_url":    "https://app.example.com/search?q=",
+				"affects_detail": "URL encoded GET input q",
+				"description":    "XSS",
+				"recommendation": "Encode output.",
+				"request":        "GET /search?q=%3Cscript%3E HTTP/1.1\r\n\r\n",
+				"response_info":  true,
+				"cvss_score":     6.1,
+				"tags":           []string{"CWE-79", "xss"},
+			}
+		case len(parts) == 7 && parts[6] == "http_response":
+			w.Write([]byte("HTTP/1.1 200 OK\r\n\r\n<script>"))
+			return
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+func newAWVSJob(server *httptest.Server, params map[string]interface{}) *Job {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	return &Job{
+		TaskID:     1,
+		Type:       models.ScannerTypeAwvs,
+		ScannerURL: server.URL,
+		APIKey:     "awvs-key",
+		TargetURLs: []string{"https://app.example.com", "https://api.example.com"},
+		Parameters: params,
+	}
+}
+
+func sortedStrings(values []string) []string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func TestAWVSDriverRun(t *testing.T) {
+	fake := &fakeAWVS{}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	job := newAWVSJob(server, map[string]interface{}{"delete_target": true})
+	results, err := Run(context.Background(), AwvsDriver{}, job, RunOptions{PollInterval: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Run 返回错误: %v", err)
+	}
+
+	// 每个目标一条漏洞，信息级别的漏洞默认不导入
+	if len(results) != 2 {
+		t.Fatalf("扫描结果数量 = %d, want 2", len(results))
+	}
+	r := results[0]
+	if r.VulnerabilityName != "Cross site scripting" || r.Severity != models.SeverityHigh ||
+		r.Category != "CWE-79" || r.CVSS != 6.1 || len(r.Evidence) != 2 {
+		t.Errorf("扫描结果 = %+v", r)
+	}
+	if !strings.Contains(r.Detail, "Request:\nGET /search?q=%3Cscript%3E HTTP/1.1") {
+		t.Errorf("详情中应保留HTTP请求: %q", r.Detail)
+	}
+	if got := sortedStrings(fake.deleted); strings.Join(got, ",") != "t1,t2" {
+		t.Errorf("delete_target=true 时应删除全部目标，实际删除 %v", got)
+	}
+	for _, key := range fake.authKeys {
+		if key != "awvs-key" {
+			t.Fatalf("X-Auth = %q", key)
+		}
+	}
+}
+
+func TestAWVSDriverFailedCleanup(t *testing.T) {
+	fake := &fakeAWVS{statuses: map[string]string{"s1": "failed", "s2": "processing"}}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	job := newAWVSJob(server, map[string]interface{}{"delete_target": true})
+	_, err := Run(context.Background(), AwvsDriver{}, job, RunOptions{PollInterval: 5 * time.Millisecond})
+	if reason := ClassifyError(err); reason != models.ScanFailureScanner {
+		t.Fatalf("失败原因 = %s (%v), want %s", reason, err, models.ScanFailureScanner)
+	}
+	if strings.Join(fake.aborted, ",") != "s2" {
+		t.Errorf("应中止其余仍在运行的扫描，实际中止 %v", fake.aborted)
+	}
+	if got := sortedStrings(fake.deleted); strings.Join(got, ",") != "t1,t2" {
+		t.Errorf("扫描失败时应删除全部目标，实际删除 %v", got)
+	}
+}
+
+func TestAWVSDriverAbortedKeepsTargets(t *testing.T) {
+	fake := &fakeAWVS{statuses: map[string]string{"s2": "aborted"}}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	_, err := Run(context.Background(), AwvsDriver{}, newAWVSJob(server, nil), RunOptions{PollInterval: 5 * time.Millisecond})
+	if err != ErrScanCancelled {
+		t.Fatalf("错误 = %v, want ErrScanCancelled", err)
+	}
+	if len(fake.deleted) != 0 {
+		t.Errorf("未设置 delete_target 时不应删除目标: %v", fake.deleted)
+	}
+}
+
+func TestAWVSDriverLaunchCleanup(t *testing.T) {
+	fake := &fakeAWVS{failTarget: "https://api.example.com"}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	if _, err := (AwvsDriver{}).Launch(context.Background(), newAWVSJob(server, nil)); err == nil {
+		t.Fatal("创建目标失败时 Launch 应返回错误")
+	}
+	if strings.Join(fake.deleted, ",") != "t1" {
+		t.Errorf("启动失败时应删除已创建的目标，实际删除 %v", fake.deleted)
+	}
+}
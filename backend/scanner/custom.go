@@ -0,0 +1,366 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vulnark/vulnark/models"
+)
+
+// customOutputLimit 保存到扫描日志中的单路输出上限
+const customOutputLimit = 16 * 1024
+
+// CustomDriver 自定义扫描器驱动，在本机以子进程方式运行管理员登记的扫描命令
+//
+// 命令模板按类shell规则拆分为参数，不经过shell执行，占位符在拆分后逐个参数替换：
+//
+//	{target}       单个目标，模板中包含时对每个目标各执行一次命令
+//	{targets}      全部目标（IP和URL），逗号分隔
+//	{ips}          目标IP列表，逗号分隔
+//	{urls}         目标URL列表，逗号分隔
+//	{target_file}  每行一个目标的临时文件路径
+//	{output_file}  输出文件路径，模板中包含时从该文件读取结果，否则读取标准输出
+//
+// 执行超时取扫描器定义中的 Timeout，stdout/stderr 通过作业日志写入扫描日志。
+type CustomDriver struct {
+	mu    sync.Mutex
+	scans map[string]*customScan
+	seq   int64
+}
+
+// customScan 一次自定义扫描的执行状态
+type customScan struct {
+	mu       sync.Mutex
+	progress int
+	done     bool
+	err      error
+	cancel   context.CancelFunc
+	workDir  string
+	outputs  [][]byte
+}
+
+func init() {
+	Register(models.ScannerTypeCustom, &CustomDriver{})
+}
+
+// Launch 校验扫描器定义并在后台启动扫描命令
+func (d *CustomDriver) Launch(ctx context.Context, job *Job) (string, error) {
+	def := job.Custom
+	if def == nil {
+		return "", errors.New("未指定自定义扫描器定义")
+	}
+	if !def.Enabled {
+		return "", fmt.Errorf("自定义扫描器 %s 已停用", def.Name)
+	}
+
+	args, err := SplitCommand(def.CommandTemplate)
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return "", errors.New("命令模板为空")
+	}
+
+	targets := append(append([]string{}, job.TargetIPs...), job.TargetURLs...)
+	if len(targets) == 0 {
+		return "", errors.New("自定义扫描需要至少一个目标")
+	}
+	// 参数不经过shell，但以"-"开头的目标仍可能被扫描工具当作选项解析
+	for _, target := range targets {
+		if strings.HasPrefix(target, "-") {
+			return "", fmt.Errorf("无效的扫描目标: %s", target)
+		}
+	}
+
+	workDir, err := ioutil.TempDir("", fmt.Sprintf("vulnark-scan-%d-", job.TaskID))
+	if err != nil {
+		return "", fmt.Errorf("创建工作目录失败: %v", err)
+	}
+
+	timeout := time.Duration(def.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = time.Hour
+	}
+	runCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	scan := &customScan{cancel: cancel, workDir: workDir}
+
+	d.mu.Lock()
+	if d.scans == nil {
+		d.scans = make(map[string]*customScan)
+	}
+	d.seq++
+	scanID := fmt.Sprintf("custom-%d-%d", job.TaskID, d.seq)
+	d.scans[scanID] = scan
+	d.mu.Unlock()
+
+	go d.run(runCtx, job, scan, args, targets)
+
+	return scanID, nil
+}
+
+// Status 返回命令的执行状态，进度按已完成的命令数计算
+func (d *CustomDriver) Status(ctx context.Context, job *Job, scanID string) (*Status, error) {
+	scan, err := d.scan(scanID)
+	if err != nil {
+		return nil, err
+	}
+
+	scan.mu.Lock()
+	var status *Status
+	switch {
+	case scan.err != nil && errors.Is(scan.err, context.Canceled):
+		status = &Status{State: StateCancelled, Progress: scan.progress}
+	case scan.err != nil:
+		status = &Status{State: StateFailed, Progress: scan.progress, Message: scan.err.Error()}
+	case scan.done:
+		status = &Status{State: StateCompleted, Progress: 100}
+	default:
+		status = &Status{State: StateRunning, Progress: scan.progress}
+	}
+	scan.mu.Unlock()
+
+	// 执行失败的扫描不会再调用 Results 或 Cancel，在这里清理状态和工作目录
+	if status.State == StateFailed {
+		d.forget(scanID)
+	}
+	return status, nil
+}
+
+// Cancel 终止正在运行的扫描命令
+// 已完成命令的输出会保留一段时间，以便调用方通过 Results 获取部分结果
+func (d *CustomDriver) Cancel(ctx context.Context, job *Job, scanID string) error {
+	scan, err := d.scan(scanID)
+	if err != nil {
+		return err
+	}
+	scan.cancel()
+	time.AfterFunc(10*time.Minute, func() { d.forget(scanID) })
+	return nil
+}
+
+// Results 按扫描器定义中的解析方式解析命令输出
+func (d *CustomDriver) Results(ctx context.Context, job *Job, scanID string) ([]models.ScanResult, error) {
+	scan, err := d.scan(scanID)
+	if err != nil {
+		return nil, err
+	}
+	defer d.forget(scanID)
+
+	scan.mu.Lock()
+	outputs := scan.outputs
+	scan.mu.Unlock()
+
+	var results []models.ScanResult
+	for _, output := range outputs {
+		parsed, err := ParseCustomOutput(job.Custom, output)
+		if err != nil {
+			return nil, WithReason(models.ScanFailureParse, err)
+		}
+		results = append(results, parsed...)
+	}
+	return results, nil
+}
+
+// run 在后台执行扫描命令，模板中包含 {target} 时逐个目标执行
+func (d *CustomDriver) run(ctx context.Context, job *Job, scan *customScan, args []string, targets []string) {
+	err := func() error {
+		targetFile := filepath.Join(scan.workDir, "targets.txt")
+		if err := ioutil.WriteFile(targetFile, []byte(strings.Join(targets, "\n")+"\n"), 0600); err != nil {
+			return fmt.Errorf("写入目标文件失败: %v", err)
+		}
+
+		values := map[string]string{
+			"{targets}":     strings.Join(targets, ","),
+			"{ips}":         strings.Join(job.TargetIPs, ","),
+			"{urls}":        strings.Join(job.TargetURLs, ","),
+			"{target_file}": targetFile,
+		}
+
+		perTarget := strings.Contains(job.Custom.CommandTemplate, "{target}")
+		batches := [][]string{nil}
+		if perTarget {
+			batches = batches[:0]
+			for _, target := range targets {
+				batches = append(batches, []string{target})
+			}
+		}
+
+		for i, batch := range batches {
+			if perTarget {
+				values["{target}"] = batch[0]
+			}
+			values["{output_file}"] = filepath.Join(scan.workDir, fmt.Sprintf("output-%d", i))
+
+			output, err := d.exec(ctx, job, scan.workDir, expandArgs(args, values), values["{output_file}"])
+			if err != nil {
+				return err
+			}
+
+			scan.mu.Lock()
+			scan.outputs = append(scan.outputs, output)
+			scan.progress = (i + 1) * 100 / len(batches)
+			scan.mu.Unlock()
+		}
+		return nil
+	}()
+
+	scan.mu.Lock()
+	scan.done = true
+	scan.err = err
+	scan.mu.Unlock()
+}
+
+// exec 运行一次命令并返回需要解析的输出
+func (d *CustomDriver) exec(ctx context.Context, job *Job, workDir string, args []string, outputFile string) ([]byte, error) {
+	job.Log("执行命令: %s", strings.Join(args, " "))
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = workDir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+
+	if stdout.Len() > 0 {
+		job.Log("stdout:\n%s", tail(stdout.String(), customOutputLimit))
+	}
+	if stderr.Len() > 0 {
+		job.Log("stderr:\n%s", tail(stderr.String(), customOutputLimit))
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		if errors.Is(ctxErr, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("命令执行超时（%s）", job.Custom.Name)
+		}
+		return nil, ctxErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("命令执行失败: %v", err)
+	}
+	job.Log("命令执行完成，耗时 %s", time.Since(start).Round(time.Second))
+
+	// 模板中使用了 {output_file} 时从输出文件读取结果
+	if strings.Contains(job.Custom.CommandTemplate, "{output_file}") {
+		data, err := ioutil.ReadFile(outputFile)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("读取输出文件失败: %v", err)
+		}
+		return data, nil
+	}
+	return stdout.Bytes(), nil
+}
+
+// scan 获取驱动内部的扫描状态
+func (d *CustomDriver) scan(scanID string) (*customScan, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	scan, ok := d.scans[scanID]
+	if !ok {
+		return nil, fmt.Errorf("自定义扫描不存在或服务已重启: %s", scanID)
+	}
+	return scan, nil
+}
+
+// forget 清理驱动内部的扫描状态和工作目录
+func (d *CustomDriver) forget(scanID string) {
+	d.mu.Lock()
+	scan, ok := d.scans[scanID]
+	delete(d.scans, scanID)
+	d.mu.Unlock()
+
+	if ok {
+		scan.cancel()
+		os.RemoveAll(scan.workDir)
+	}
+}
+
+// SplitCommand 按类shell规则拆分命令模板，支持单引号、双引号和反斜杠转义
+func SplitCommand(command string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+
+	for _, r := range command {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.New("命令模板中的引号未闭合")
+	}
+	if escaped {
+		return nil, errors.New("命令模板以转义符结尾")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// expandArgs 替换参数中的占位符
+func expandArgs(args []string, values map[string]string) []string {
+	expanded := make([]string, len(args))
+	for i, arg := range args {
+		for placeholder, value := range values {
+			arg = strings.Replace(arg, placeholder, value, -1)
+		}
+		expanded[i] = arg
+	}
+	return expanded
+}
+
+// tail 保留字符串末尾不超过 max 字节的内容
+func tail(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	start := len(s) - max
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	return "...\n" + s[start:]
+}
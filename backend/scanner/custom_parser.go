@@ -0,0 +1,274 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vulnark/vulnark/models"
+)
+
+// OutputMapping 自定义扫描器输出的字段映射配置
+//
+// Fields 的键为扫描结果字段名（与 ScanResult 的JSON字段一致，另有 request 和 response 两个键，
+// 对应的内容作为HTTP请求和响应证据保存），值为记录中的字段路径，
+// 路径以"."分隔，数组元素用下标访问，多个候选路径用"|"分隔，取第一个非空值。例如：
+//
+//	{
+//	  "records": "vulnerabilities",
+//	  "fields": {
+//	    "vulnerability_name": "info.name|template-id",
+//	    "severity": "info.severity",
+//	    "affected_url": "matched-at",
+//	    "cve": "info.classification.cve-id.0"
+//	  },
+//	  "severity_map": {"unknown": "info"}
+//	}
+type OutputMapping struct {
+	Records     string            `json:"records"`      // 结果数组所在路径，为空时输出本身为数组、单个对象或JSON Lines
+	Fields      map[string]string `json:"fields"`       // 扫描结果字段 -> 记录字段路径
+	SeverityMap map[string]string `json:"severity_map"` // 原始严重程度 -> critical/high/medium/low/info
+}
+
+// defaultOutputMapping JSON Lines 解析使用的默认映射，兼容常见字段名和 nuclei 的输出格式
+var defaultOutputMapping = OutputMapping{
+	Fields: map[string]string{
+		"vulnerability_name": "vulnerability_name|name|title|info.name|template-id|rule_id",
+		"description":        "description|info.description|message",
+		"severity":           "severity|info.severity|level|risk",
+		"affected_url":       "affected_url|url|matched-at",
+		"affected_ip":        "affected_ip|ip|host",
+		"affected_port":      "affected_port|port",
+		"detail":             "detail|evidence|extracted-results|matcher-name",
+		"category":           "category|type|info.classification.cwe-id.0",
+		"cve":                "cve|info.classification.cve-id.0",
+		"cvss":               "cvss|info.classification.cvss-score",
+		"solution":           "solution|remediation|info.remediation",
+		"references":         "references|reference|info.reference",
+		"request":            "request",
+		"response":           "response",
+	},
+}
+
+// ParseMappingConfig 解析扫描器定义中的映射配置
+func ParseMappingConfig(config string) (*OutputMapping, error) {
+	var mapping OutputMapping
+	if err := json.Unmarshal([]byte(config), &mapping); err != nil {
+		return nil, fmt.Errorf("映射配置不是有效的JSON: %v", err)
+	}
+	if len(mapping.Fields) == 0 {
+		return nil, errors.New("映射配置缺少 fields")
+	}
+	if _, ok := mapping.Fields["vulnerability_name"]; !ok {
+		return nil, errors.New("映射配置缺少 vulnerability_name 字段")
+	}
+	return &mapping, nil
+}
+
+// ParseCustomOutput 按扫描器定义的解析方式将命令输出转换为扫描结果
+func ParseCustomOutput(def *models.CustomScanner, data []byte) ([]models.ScanResult, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	switch def.OutputParser {
+	case models.CustomParserSARIF:
+		findings, err := ParseSARIF(data)
+		if err != nil {
+			return nil, err
+		}
+		results := make([]models.ScanResult, 0, len(findings))
+		for i := range findings {
+			results = append(results, findings[i].ToScanResult())
+		}
+		return results, nil
+
+	case models.CustomParserMapping:
+		mapping, err := ParseMappingConfig(def.MappingConfig)
+		if err != nil {
+			return nil, err
+		}
+		return parseMappedOutput(mapping, data)
+
+	case models.CustomParserJSONLines, "":
+		return parseMappedOutput(&defaultOutputMapping, data)
+
+	default:
+		return nil, fmt.Errorf("不支持的输出解析方式: %s", def.OutputParser)
+	}
+}
+
+// parseMappedOutput 按映射配置解析JSON文档或JSON Lines
+func parseMappedOutput(mapping *OutputMapping, data []byte) ([]models.ScanResult, error) {
+	records, err := decodeRecords(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if mapping.Records != "" {
+		var nested []interface{}
+		for _, record := range records {
+			if list, ok := lookupPath(record, mapping.Records).([]interface{}); ok {
+				nested = append(nested, list...)
+			}
+		}
+		records = nested
+	}
+
+	results := make([]models.ScanResult, 0, len(records))
+	for _, record := range records {
+		if _, ok := record.(map[string]interface{}); !ok {
+			continue
+		}
+		result := mapping.toScanResult(record)
+		if result.VulnerabilityName == "" {
+			continue
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// decodeRecords 将输出解析为记录列表：JSON数组、单个JSON对象或每行一个JSON对象
+func decodeRecords(data []byte) ([]interface{}, error) {
+	trimmed := bytes.TrimSpace(data)
+
+	var doc interface{}
+	if err := json.Unmarshal(trimmed, &doc); err == nil {
+		if list, ok := doc.([]interface{}); ok {
+			return list, nil
+		}
+		return []interface{}{doc}, nil
+	}
+
+	var records []interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		// 跳过工具输出中夹杂的非JSON行（如进度提示）
+		if len(text) == 0 || text[0] != '{' {
+			continue
+		}
+		var record interface{}
+		if err := json.Unmarshal(text, &record); err != nil {
+			return nil, fmt.Errorf("第 %d 行不是有效的JSON: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取扫描输出失败: %v", err)
+	}
+	return records, nil
+}
+
+// toScanResult 按映射配置将一条记录转换为扫描结果
+func (m *OutputMapping) toScanResult(record interface{}) models.ScanResult {
+	field := func(name string) string {
+		for _, path := range strings.Split(m.Fields[name], "|") {
+			if path = strings.TrimSpace(path); path == "" {
+				continue
+			}
+			if value := stringify(lookupPath(record, path)); value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+
+	result := models.ScanResult{
+		VulnerabilityName: truncate(field("vulnerability_name"), 255),
+		Description:       field("description"),
+		Severity:          m.severity(field("severity")),
+		AffectedURL:       truncate(field("affected_url"), 255),
+		AffectedIP:        truncate(field("affected_ip"), 50),
+		AffectedPort:      truncate(field("affected_port"), 50),
+		Detail:            field("detail"),
+		Category:          truncate(field("category"), 100),
+		CVE:               truncate(strings.ToUpper(field("cve")), 50),
+		Solution:          field("solution"),
+		References:        field("references"),
+	}
+	if cvss, err := strconv.ParseFloat(field("cvss"), 64); err == nil {
+		result.CVSS = cvss
+	}
+	if request := field("request"); request != "" {
+		result.Evidence = append(result.Evidence, HTTPRequestEvidence([]byte(request)))
+	}
+	if response := field("response"); response != "" {
+		result.Evidence = append(result.Evidence, HTTPResponseEvidence([]byte(response)))
+	}
+	return result
+}
+
+// severity 转换严重程度，无法识别时按信息级别处理
+func (m *OutputMapping) severity(raw string) models.Severity {
+	value := strings.ToLower(strings.TrimSpace(raw))
+	for k, v := range m.SeverityMap {
+		if strings.EqualFold(k, value) {
+			value = strings.ToLower(v)
+			break
+		}
+	}
+
+	switch value {
+	case "critical", "严重":
+		return models.SeverityCritical
+	case "high", "高危", "error":
+		return models.SeverityHigh
+	case "medium", "moderate", "中危", "warning":
+		return models.SeverityMedium
+	case "low", "低危", "note":
+		return models.SeverityLow
+	default:
+		return models.SeverityInfo
+	}
+}
+
+// lookupPath 按"."分隔的路径读取JSON值，数组元素用下标访问
+func lookupPath(value interface{}, path string) interface{} {
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[key]
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil
+			}
+			value = v[index]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+// stringify 将JSON值转换为字符串，数组以逗号连接
+func stringify(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			if s := stringify(item); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, ", ")
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
@@ -0,0 +1,88 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vulnark/vulnark/models"
+)
+
+func TestSplitCommand(t *testing.T) {
+	cases := []struct {
+		command string
+		want    []string
+	}{
+		{`nuclei -l {target_file} -jsonl`, []string{"nuclei", "-l", "{target_file}", "-jsonl"}},
+		{`tool --name "a b" 'c "d"' e\ f`, []string{"tool", "--name", "a b", `c "d"`, "e f"}},
+		{`tool ""`, []string{"tool", ""}},
+	}
+	for _, c := range cases {
+		got, err := SplitCommand(c.command)
+		if err != nil {
+			t.Errorf("SplitCommand(%q) 返回错误: %v", c.command, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("SplitCommand(%q) = %q, want %q", c.command, got, c.want)
+		}
+	}
+	for _, command := range []string{`tool "unclosed`, `tool \`} {
+		if _, err := SplitCommand(command); err == nil {
+			t.Errorf("SplitCommand(%q) 应返回错误", command)
+		}
+	}
+}
+
+func TestCustomDriverFailedCleanup(t *testing.T) {
+	if _, err := exec.LookPath("false"); err != nil {
+		t.Skip("缺少 false 命令")
+	}
+
+	driver := &CustomDriver{}
+	job := &Job{
+		TaskID:    1,
+		Type:      models.ScannerTypeCustom,
+		TargetIPs: []string{"10.0.0.1"},
+		Custom: &models.CustomScanner{
+			Name:            "fail",
+			CommandTemplate: "false {targets}",
+			OutputParser:    models.CustomParserJSONLines,
+			Enabled:         true,
+		},
+	}
+	scanID, err := driver.Launch(context.Background(), job)
+	if err != nil {
+		t.Fatalf("Launch 返回错误: %v", err)
+	}
+	scan, err := driver.scan(scanID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, err := driver.Status(context.Background(), job, scanID)
+		if err != nil {
+			t.Fatalf("Status 返回错误: %v", err)
+		}
+		if status.State == StateFailed {
+			break
+		}
+		if status.State != StateRunning || time.Now().After(deadline) {
+			t.Fatalf("扫描状态 = %+v, want failed", status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// 返回失败状态后不会再调用 Results 或 Cancel，驱动应自行清理
+	if _, err := driver.scan(scanID); err == nil {
+		t.Error("失败的扫描应清理扫描状态")
+	}
+	if _, err := os.Stat(scan.workDir); !os.IsNotExist(err) {
+		t.Errorf("失败的扫描应删除工作目录: %s", scan.workDir)
+	}
+}
@@ -0,0 +1,294 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vulnark/vulnark/models"
+)
+
+// State 远程扫描状态
+type State string
+
+const (
+	StateRunning   State = "running"   // 扫描中
+	StateCompleted State = "completed" // 已完成
+	StateFailed    State = "failed"    // 失败
+	StateCancelled State = "cancelled" // 已取消
+)
+
+// Status 扫描器返回的扫描进度
+type Status struct {
+	State    State  `json:"state"`
+	Progress int    `json:"progress"` // 进度百分比 0-100
+	Message  string `json:"message"`
+}
+
+// ScannerDriver 扫描器驱动接口，每种扫描器类型实现一个驱动
+//
+// Launch 返回的扫描ID会在后续调用中传回，驱动通过扫描ID区分不同的扫描，
+// 因此同一个驱动实例需要能同时服务多个扫描任务
+type ScannerDriver interface {
+	// Launch 在扫描器上创建并启动扫描，返回扫描器侧的扫描ID
+	Launch(ctx context.Context, job *Job) (string, error)
+	// Status 查询扫描进度
+	Status(ctx context.Context, job *Job, scanID string) (*Status, error)
+	// Results 获取扫描结果并转换为扫描结果模型
+	Results(ctx context.Context, job *Job, scanID string) ([]models.ScanResult, error)
+	// Cancel 停止扫描器上正在进行的扫描
+	Cancel(ctx context.Context, job *Job, scanID string) error
+}
+
+// ConnectionTester 可选接口，驱动实现后可测试扫描器地址和认证信息是否可用
+type ConnectionTester interface {
+	// TestConnection 连接扫描器并校验认证信息，成功时返回扫描器版本等说明信息
+	TestConnection(ctx context.Context, job *Job) (string, error)
+}
+
+// ErrConnectionTestUnsupported 驱动不支持连接测试
+var ErrConnectionTestUnsupported = errors.New("该扫描器类型不支持连接测试")
+
+// TestConnection 使用扫描器类型对应的驱动测试连接
+func TestConnection(ctx context.Context, job *Job) (string, error) {
+	driver, err := Lookup(job.Type)
+	if err != nil {
+		return "", err
+	}
+	tester, ok := driver.(ConnectionTester)
+	if !ok {
+		return "", ErrConnectionTestUnsupported
+	}
+	return tester.TestConnection(ctx, job)
+}
+
+// ErrDriverNotFound 未注册的扫描器类型
+var ErrDriverNotFound = errors.New("未找到扫描器驱动")
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[models.ScannerType]ScannerDriver)
+)
+
+// Register 注册扫描器驱动，重复注册会覆盖之前的驱动
+func Register(scannerType models.ScannerType, driver ScannerDriver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	if driver == nil {
+		delete(drivers, scannerType)
+		return
+	}
+	drivers[scannerType] = driver
+}
+
+// Lookup 获取扫描器类型对应的驱动
+func Lookup(scannerType models.ScannerType) (ScannerDriver, error) {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	driver, ok := drivers[scannerType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrDriverNotFound, scannerType)
+	}
+	return driver, nil
+}
+
+// Types 返回已注册驱动的扫描器类型
+func Types() []models.ScannerType {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	types := make([]models.ScannerType, 0, len(drivers))
+	for t := range drivers {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// Job 扫描作业，包含一次扫描所需的扫描器连接信息和扫描目标
+type Job struct {
+	TaskID     uint                   `json:"task_id"`
+	Type       models.ScannerType     `json:"type"`
+	ScannerURL string                 `json:"scanner_url"`
+	APIKey     string                 `json:"api_key"`
+	Username   string                 `json:"username"`
+	Password   string                 `json:"password"`
+	TargetIPs  []string               `json:"target_ips"`
+	TargetURLs []string               `json:"target_urls"`
+	Parameters map[string]interface{} `json:"parameters"`
+
+	// Custom 自定义扫描器定义，仅 custom 类型使用
+	Custom *models.CustomScanner `json:"custom,omitempty"`
+
+	// Logf 扫描过程日志输出，为空时不输出
+	Logf func(format string, args ...interface{}) `json:"-"`
+}
+
+// NewJob 根据扫描任务创建扫描作业
+func NewJob(task *models.ScanTask) (*Job, error) {
+	job := &Job{
+		TaskID:     task.ID,
+		Type:       task.Type,
+		ScannerURL: strings.TrimRight(strings.TrimSpace(task.ScannerURL), "/"),
+		APIKey:     task.ScannerAPIKey,
+		Username:   task.ScannerUsername,
+		Password:   task.ScannerPassword,
+		TargetIPs:  SplitList(task.TargetIPs),
+		TargetURLs: SplitList(task.TargetURLs),
+		Parameters: map[string]interface{}{},
+	}
+
+	if strings.TrimSpace(task.ScanParameters) != "" {
+		if err := json.Unmarshal([]byte(task.ScanParameters), &job.Parameters); err != nil {
+			return nil, fmt.Errorf("扫描参数不是有效的JSON: %v", err)
+		}
+	}
+
+	return job, nil
+}
+
+// ApplyProfile 使用扫描器连接配置中的地址和凭据，并以配置的默认参数作为任务参数的缺省值
+func (j *Job) ApplyProfile(profile *models.ScannerProfile) error {
+	defaults, err := profileParameters(profile)
+	if err != nil {
+		return err
+	}
+	for key, value := range defaults {
+		if _, ok := j.Parameters[key]; !ok {
+			j.Parameters[key] = value
+		}
+	}
+
+	j.ScannerURL = strings.TrimRight(strings.TrimSpace(profile.ScannerURL), "/")
+	j.APIKey = profile.APIKey
+	j.Username = profile.Username
+	j.Password = profile.Password
+	return nil
+}
+
+// NewProfileJob 根据扫描器连接配置创建不含扫描目标的作业，用于测试连接
+func NewProfileJob(profile *models.ScannerProfile) (*Job, error) {
+	job := &Job{
+		Type:       profile.Type,
+		Parameters: map[string]interface{}{},
+	}
+	if err := job.ApplyProfile(profile); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// profileParameters 连接配置的默认参数，verify_tls 未在默认参数中指定时取配置的TLS校验开关
+func profileParameters(profile *models.ScannerProfile) (map[string]interface{}, error) {
+	params := map[string]interface{}{}
+	if strings.TrimSpace(profile.DefaultParameters) != "" {
+		if err := json.Unmarshal([]byte(profile.DefaultParameters), &params); err != nil {
+			return nil, fmt.Errorf("扫描器连接配置的默认参数不是有效的JSON: %v", err)
+		}
+	}
+	if _, ok := params["verify_tls"]; !ok {
+		params["verify_tls"] = profile.VerifyTLS
+	}
+	return params, nil
+}
+
+// Log 输出扫描过程日志
+func (j *Job) Log(format string, args ...interface{}) {
+	if j.Logf != nil {
+		j.Logf(format, args...)
+	}
+}
+
+// StringParam 读取字符串类型的扫描参数
+func (j *Job) StringParam(key, fallback string) string {
+	if v, ok := j.Parameters[key]; ok && v != nil {
+		switch value := v.(type) {
+		case string:
+			if value != "" {
+				return value
+			}
+		case float64:
+			return strconv.FormatFloat(value, 'f', -1, 64)
+		case bool:
+			return strconv.FormatBool(value)
+		}
+	}
+	return fallback
+}
+
+// BoolParam 读取布尔类型的扫描参数
+func (j *Job) BoolParam(key string, fallback bool) bool {
+	if v, ok := j.Parameters[key]; ok && v != nil {
+		switch value := v.(type) {
+		case bool:
+			return value
+		case string:
+			if b, err := strconv.ParseBool(value); err == nil {
+				return b
+			}
+		case float64:
+			return value != 0
+		}
+	}
+	return fallback
+}
+
+// IntParam 读取整数类型的扫描参数
+func (j *Job) IntParam(key string, fallback int) int {
+	if v, ok := j.Parameters[key]; ok && v != nil {
+		switch value := v.(type) {
+		case float64:
+			return int(value)
+		case string:
+			if n, err := strconv.Atoi(value); err == nil {
+				return n
+			}
+		}
+	}
+	return fallback
+}
+
+// SplitList 拆分逗号或换行分隔的列表，去除空白项
+func SplitList(value string) []string {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r' || r == ';'
+	})
+
+	list := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if f = strings.TrimSpace(f); f != "" {
+			list = append(list, f)
+		}
+	}
+	return list
+}
+
+// sleepContext 等待指定时间，ctx 取消时提前返回
+func sleepContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// truncate 按字节截断字符串以适配数据库字段长度，不会截断多字节字符
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
@@ -0,0 +1,39 @@
+package scanner
+
+import "github.com/vulnark/vulnark/models"
+
+// maxDetailSize 扫描结果详情中保留的插件输出或HTTP请求长度，完整内容保存为证据
+const maxDetailSize = 16 * 1024
+
+// textContentType 文本证据的内容类型，HTTP报文也按纯文本保存，下载时不会被浏览器当作页面解析
+const textContentType = "text/plain; charset=utf-8"
+
+// HTTPRequestEvidence 触发漏洞的原始HTTP请求
+func HTTPRequestEvidence(raw []byte) models.ScanEvidence {
+	return models.ScanEvidence{
+		Kind:        models.EvidenceHTTPRequest,
+		Name:        "request.txt",
+		ContentType: textContentType,
+		Data:        raw,
+	}
+}
+
+// HTTPResponseEvidence 对应的原始HTTP响应
+func HTTPResponseEvidence(raw []byte) models.ScanEvidence {
+	return models.ScanEvidence{
+		Kind:        models.EvidenceHTTPResponse,
+		Name:        "response.txt",
+		ContentType: textContentType,
+		Data:        raw,
+	}
+}
+
+// PluginOutputEvidence 扫描插件的原始输出
+func PluginOutputEvidence(output string) models.ScanEvidence {
+	return models.ScanEvidence{
+		Kind:        models.EvidencePluginOutput,
+		Name:        "plugin_output.txt",
+		ContentType: textContentType,
+		Data:        []byte(output),
+	}
+}
@@ -0,0 +1,79 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/vulnark/vulnark/models"
+)
+
+// FailureError 带失败原因分类的错误
+type FailureError struct {
+	Reason models.ScanFailureReason
+	Err    error
+}
+
+func (e *FailureError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FailureError) Unwrap() error {
+	return e.Err
+}
+
+// WithReason 为错误标记失败原因，err 为 nil 时返回 nil
+func WithReason(reason models.ScanFailureReason, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &FailureError{Reason: reason, Err: err}
+}
+
+// ClassifyError 判断扫描失败的原因
+// 优先使用错误链上显式标记的原因，其次根据HTTP状态码和网络错误类型推断
+func ClassifyError(err error) models.ScanFailureReason {
+	if err == nil {
+		return ""
+	}
+
+	var failure *FailureError
+	if errors.As(err, &failure) {
+		return failure.Reason
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		switch httpErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return models.ScanFailureAuth
+		case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+			return models.ScanFailureTimeout
+		case http.StatusBadGateway, http.StatusServiceUnavailable:
+			return models.ScanFailureUnreachable
+		}
+		return models.ScanFailureUnknown
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return models.ScanFailureTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return models.ScanFailureTimeout
+	}
+
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	var urlErr *url.Error
+	if errors.As(err, &opErr) || errors.As(err, &dnsErr) || errors.As(err, &urlErr) {
+		return models.ScanFailureUnreachable
+	}
+
+	if errors.Is(err, ErrDriverNotFound) {
+		return models.ScanFailureConfig
+	}
+	return models.ScanFailureUnknown
+}
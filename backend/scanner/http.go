@@ -0,0 +1,116 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/vulnark/vulnark/models"
+)
+
+// HTTPError 扫描器API返回的非2xx响应
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	body := e.Body
+	if len(body) > 512 {
+		body = body[:512] + "..."
+	}
+	return fmt.Sprintf("%s %s 返回HTTP %d: %s", e.Method, e.URL, e.StatusCode, body)
+}
+
+// NewHTTPClient 创建访问扫描器API的HTTP客户端
+// 大部分自建扫描器使用自签名证书，可通过扫描参数 verify_tls=true 开启证书校验
+func NewHTTPClient(job *Job) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		InsecureSkipVerify: !job.BoolParam("verify_tls", false),
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(job.IntParam("http_timeout", 60)) * time.Second,
+	}
+}
+
+// Request 扫描器API请求
+type Request struct {
+	Method  string
+	URL     string
+	Header  map[string]string
+	Body    interface{}  // 非nil时序列化为JSON请求体；[]byte 原样发送
+	Result  interface{}  // 非nil时将响应体反序列化到该对象
+	RawBody *[]byte      // 非nil时保存原始响应体
+	Headers *http.Header // 非nil时保存响应头
+}
+
+// Do 发送扫描器API请求
+func Do(ctx context.Context, client *http.Client, r Request) error {
+	var body io.Reader
+	switch b := r.Body.(type) {
+	case nil:
+	case []byte:
+		body = bytes.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return fmt.Errorf("序列化请求体失败: %v", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.Method, r.URL, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range r.Header {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &HTTPError{
+			Method:     r.Method,
+			URL:        r.URL,
+			StatusCode: resp.StatusCode,
+			Body:       string(data),
+		}
+	}
+
+	if r.RawBody != nil {
+		*r.RawBody = data
+	}
+	if r.Headers != nil {
+		*r.Headers = resp.Header
+	}
+	if r.Result != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, r.Result); err != nil {
+			return WithReason(models.ScanFailureParse, fmt.Errorf("解析响应失败: %v", err))
+		}
+	}
+	return nil
+}
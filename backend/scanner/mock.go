@@ -0,0 +1,118 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vulnark/vulnark/models"
+)
+
+// mockScanDuration 模拟扫描耗时
+const mockScanDuration = 5 * time.Second
+
+// MockDriver 模拟扫描器驱动，用于尚未接入真实扫描器的类型和演示环境
+//
+// 模拟驱动生成的是示例结果，默认不注册，需要通过 EnableMockDriver 显式启用。
+type MockDriver struct{}
+
+// mockScannerTypes 尚未接入真实驱动、启用模拟驱动后由其执行的扫描器类型
+var mockScannerTypes = []models.ScannerType{
+	models.ScannerTypeXray,
+}
+
+// EnableMockDriver 为尚未接入真实驱动的扫描器类型注册模拟驱动，已注册真实驱动的类型不受影响
+func EnableMockDriver() {
+	for _, t := range mockScannerTypes {
+		if _, err := Lookup(t); err == nil {
+			continue
+		}
+		Register(t, MockDriver{})
+	}
+}
+
+// Launch 模拟启动扫描，扫描ID中记录启动时间
+func (MockDriver) Launch(ctx context.Context, job *Job) (string, error) {
+	return fmt.Sprintf("mock-%d", time.Now().UnixNano()), nil
+}
+
+// Status 根据启动时间计算模拟进度
+func (MockDriver) Status(ctx context.Context, job *Job, scanID string) (*Status, error) {
+	startedAt, err := strconv.ParseInt(strings.TrimPrefix(scanID, "mock-"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("无效的扫描ID: %s", scanID)
+	}
+
+	elapsed := time.Since(time.Unix(0, startedAt))
+	if elapsed >= mockScanDuration {
+		return &Status{State: StateCompleted, Progress: 100}, nil
+	}
+	return &Status{State: StateRunning, Progress: int(elapsed * 100 / mockScanDuration)}, nil
+}
+
+// Cancel 模拟扫描无需停止
+func (MockDriver) Cancel(ctx context.Context, job *Job, scanID string) error {
+	return nil
+}
+
+// Results 根据扫描器类型生成示例结果
+func (MockDriver) Results(ctx context.Context, job *Job, scanID string) ([]models.ScanResult, error) {
+	firstIP, firstURL := "", ""
+	if len(job.TargetIPs) > 0 {
+		firstIP = job.TargetIPs[0]
+	}
+	if len(job.TargetURLs) > 0 {
+		firstURL = job.TargetURLs[0]
+	}
+
+	results := []models.ScanResult{}
+
+	switch job.Type {
+	case models.ScannerTypeXray:
+		// 模拟Xray扫描结果
+		results = append(results, models.ScanResult{
+			VulnerabilityName: "SQL Injection Vulnerability",
+			Description:       "A SQL injection vulnerability was detected in the login form.",
+			Severity:          models.SeverityCritical,
+			AffectedURL:       firstURL + "/login.php",
+			Detail:            "SQL injection in the 'username' parameter allows authentication bypass.",
+			Category:          "SQLInjection",
+			CVSS:              9.5,
+			Solution:          "Implement proper input validation and parameterized queries.",
+			References:        "https://example.com/sql-injection",
+			Evidence: []models.ScanEvidence{
+				HTTPRequestEvidence([]byte("POST /login.php HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/x-www-form-urlencoded\r\n\r\nusername=admin'--&password=x")),
+				HTTPResponseEvidence([]byte("HTTP/1.1 302 Found\r\nLocation: /admin/\r\nSet-Cookie: session=mock\r\n\r\n")),
+			},
+		})
+		results = append(results, models.ScanResult{
+			VulnerabilityName: "Cross-Site Scripting (XSS)",
+			Description:       "A reflected XSS vulnerability was detected.",
+			Severity:          models.SeverityHigh,
+			AffectedURL:       firstURL + "/search.php",
+			Detail:            "Reflected XSS in the 'q' parameter.",
+			Category:          "XSS",
+			CVSS:              7.2,
+			Solution:          "Implement proper output encoding.",
+			References:        "https://example.com/xss",
+		})
+	default:
+		// 默认生成一些通用结果
+		results = append(results, models.ScanResult{
+			VulnerabilityName: "Information Disclosure",
+			Description:       "Server information is exposed in HTTP headers.",
+			Severity:          models.SeverityLow,
+			AffectedURL:       firstURL,
+			AffectedIP:        firstIP,
+			Detail:            "Server version information is disclosed in HTTP headers.",
+			Category:          "InfoDisclosure",
+			CVSS:              3.5,
+			Solution:          "Configure web server to hide version information.",
+			References:        "https://example.com/info-disclosure",
+		})
+	}
+
+	return results, nil
+}
@@ -0,0 +1,482 @@
+package scanner
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vulnark/vulnark/models"
+)
+
+// NessusDriver Nessus REST API 扫描器驱动
+//
+// 认证方式：ScannerAPIKey 填写 "accessKey:secretKey"（或完整的 "accessKey=xxx; secretKey=yyy"），
+// 未填写API密钥时使用 ScannerUsername/ScannerPassword 登录获取会话令牌。
+//
+// 支持的扫描参数：
+//
+//	template       扫描模板名称，默认 basic（Basic Network Scan）
+//	policy_id      使用已有扫描策略
+//	folder_id      扫描保存的文件夹
+//	include_info   是否导入信息级别(severity=0)的插件输出，默认 false
+//	delete_scan    扫描结果导出后是否删除Nessus上的扫描，默认 false
+//	export_timeout 等待报告导出完成的秒数，默认 600
+type NessusDriver struct {
+	mu       sync.Mutex
+	sessions map[string]string // 扫描器地址+用户名 -> 会话令牌
+}
+
+func init() {
+	Register(models.ScannerTypeNessus, &NessusDriver{})
+}
+
+// Launch 创建并启动Nessus扫描，返回Nessus扫描ID
+func (d *NessusDriver) Launch(ctx context.Context, job *Job) (string, error) {
+	if job.ScannerURL == "" {
+		return "", errors.New("未配置Nessus服务器地址")
+	}
+	if len(job.TargetIPs) == 0 {
+		return "", errors.New("Nessus扫描需要至少一个目标IP")
+	}
+
+	templateUUID, err := d.templateUUID(ctx, job, job.StringParam("template", "basic"))
+	if err != nil {
+		return "", err
+	}
+
+	settings := map[string]interface{}{
+		"name":         fmt.Sprintf("VulnArk-%d-%s", job.TaskID, time.Now().Format("20060102150405")),
+		"description":  "Created by VulnArk",
+		"text_targets": strings.Join(job.TargetIPs, ","),
+		"enabled":      false,
+	}
+	if policyID := job.IntParam("policy_id", 0); policyID > 0 {
+		settings["policy_id"] = policyID
+	}
+	if folderID := job.IntParam("folder_id", 0); folderID > 0 {
+		settings["folder_id"] = folderID
+	}
+
+	var created struct {
+		Scan struct {
+			ID int `json:"id"`
+		} `json:"scan"`
+	}
+	err = d.do(ctx, job, Request{
+		Method: http.MethodPost,
+		URL:    job.ScannerURL + "/scans",
+		Body: map[string]interface{}{
+			"uuid":     templateUUID,
+			"settings": settings,
+		},
+		Result: &created,
+	})
+	if err != nil {
+		return "", fmt.Errorf("创建Nessus扫描失败: %w", err)
+	}
+	if created.Scan.ID == 0 {
+		return "", errors.New("创建Nessus扫描失败: 未返回扫描ID")
+	}
+
+	scanID := strconv.Itoa(created.Scan.ID)
+	err = d.do(ctx, job, Request{
+		Method: http.MethodPost,
+		URL:    job.ScannerURL + "/scans/" + scanID + "/launch",
+	})
+	if err != nil {
+		return "", fmt.Errorf("启动Nessus扫描失败: %w", err)
+	}
+
+	job.Log("Nessus扫描已创建并启动: scan_id=%s, targets=%d", scanID, len(job.TargetIPs))
+	return scanID, nil
+}
+
+// Status 查询Nessus扫描状态
+func (d *NessusDriver) Status(ctx context.Context, job *Job, scanID string) (*Status, error) {
+	var detail struct {
+		Info struct {
+			Status string `json:"status"`
+		} `json:"info"`
+		Hosts []struct {
+			Progress string `json:"progress"`
+			Current  int    `json:"scanprogresscurrent"`
+			Total    int    `json:"scanprogresstotal"`
+		} `json:"hosts"`
+	}
+	err := d.do(ctx, job, Request{
+		Method: http.MethodGet,
+		URL:    job.ScannerURL + "/scans/" + scanID,
+		Result: &detail,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 以各主机扫描进度的平均值作为整体进度
+	progress := 0
+	if len(detail.Hosts) > 0 {
+		sum := 0
+		for _, host := range detail.Hosts {
+			if host.Total > 0 {
+				sum += host.Current * 100 / host.Total
+			}
+		}
+		progress = sum / len(detail.Hosts)
+	}
+
+	status := &Status{State: StateRunning, Progress: progress, Message: detail.Info.Status}
+	switch detail.Info.Status {
+	case "completed":
+		status.State = StateCompleted
+		status.Progress = 100
+	case "canceled", "cancelled", "aborted":
+		status.State = StateCancelled
+	case "empty", "imported":
+		status.State = StateFailed
+		status.Message = "Nessus扫描状态异常: " + detail.Info.Status
+	}
+	return status, nil
+}
+
+// Cancel 停止Nessus扫描
+func (d *NessusDriver) Cancel(ctx context.Context, job *Job, scanID string) error {
+	return d.do(ctx, job, Request{
+		Method: http.MethodPost,
+		URL:    job.ScannerURL + "/scans/" + scanID + "/stop",
+	})
+}
+
+// Results 导出 .nessus 报告并转换为扫描结果
+func (d *NessusDriver) Results(ctx context.Context, job *Job, scanID string) ([]models.ScanResult, error) {
+	var export struct {
+		File  int    `json:"file"`
+		Token string `json:"token"`
+	}
+	err := d.do(ctx, job, Request{
+		Method: http.MethodPost,
+		URL:    job.ScannerURL + "/scans/" + scanID + "/export",
+		Body:   map[string]string{"format": "nessus"},
+		Result: &export,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("导出Nessus报告失败: %w", err)
+	}
+	fileID := strconv.Itoa(export.File)
+
+	// 等待导出完成，Nessus导出失败时状态为 error
+	timeout := time.Duration(job.IntParam("export_timeout", 600)) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Minute
+	}
+	deadline := time.After(timeout)
+	for {
+		var status struct {
+			Status string `json:"status"`
+		}
+		err := d.do(ctx, job, Request{
+			Method: http.MethodGet,
+			URL:    job.ScannerURL + "/scans/" + scanID + "/export/" + fileID + "/status",
+			Result: &status,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("查询Nessus报告导出状态失败: %w", err)
+		}
+		if status.Status == "ready" {
+			break
+		}
+		if status.Status == "error" {
+			return nil, errors.New("Nessus报告导出失败")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline:
+			return nil, fmt.Errorf("等待Nessus报告导出超时（%s）", timeout)
+		case <-time.After(2 * time.Second):
+		}
+	}
+
+	var report []byte
+	err = d.do(ctx, job, Request{
+		Method:  http.MethodGet,
+		URL:     job.ScannerURL + "/scans/" + scanID + "/export/" + fileID + "/download",
+		RawBody: &report,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("下载Nessus报告失败: %w", err)
+	}
+
+	results, err := ParseNessusReport(report, job.BoolParam("include_info", false))
+	if err != nil {
+		return nil, WithReason(models.ScanFailureParse, err)
+	}
+
+	if job.BoolParam("delete_scan", false) {
+		err := d.do(ctx, job, Request{
+			Method: http.MethodDelete,
+			URL:    job.ScannerURL + "/scans/" + scanID,
+		})
+		if err != nil {
+			job.Log("删除Nessus扫描失败: %v", err)
+		}
+	}
+
+	return results, nil
+}
+
+// templateUUID 根据模板名称查找扫描模板UUID
+func (d *NessusDriver) templateUUID(ctx context.Context, job *Job, name string) (string, error) {
+	var templates struct {
+		Templates []struct {
+			UUID  string `json:"uuid"`
+			Name  string `json:"name"`
+			Title string `json:"title"`
+		} `json:"templates"`
+	}
+	err := d.do(ctx, job, Request{
+		Method: http.MethodGet,
+		URL:    job.ScannerURL + "/editor/scan/templates",
+		Result: &templates,
+	})
+	if err != nil {
+		return "", fmt.Errorf("获取Nessus扫描模板失败: %w", err)
+	}
+
+	for _, t := range templates.Templates {
+		if t.Name == name || t.UUID == name || strings.EqualFold(t.Title, name) {
+			return t.UUID, nil
+		}
+	}
+	return "", fmt.Errorf("Nessus上不存在扫描模板: %s", name)
+}
+
+// TestConnection 使用配置的认证信息读取Nessus服务器属性
+func (d *NessusDriver) TestConnection(ctx context.Context, job *Job) (string, error) {
+	if job.ScannerURL == "" {
+		return "", errors.New("未配置Nessus服务器地址")
+	}
+
+	var properties struct {
+		ServerVersion string `json:"server_version"`
+		UIVersion     string `json:"nessus_ui_version"`
+		Type          string `json:"nessus_type"`
+	}
+	err := d.do(ctx, job, Request{
+		Method: http.MethodGet,
+		URL:    job.ScannerURL + "/server/properties",
+		Result: &properties,
+	})
+	if err != nil {
+		return "", fmt.Errorf("连接Nessus失败: %w", err)
+	}
+
+	version := properties.UIVersion
+	if version == "" {
+		version = properties.ServerVersion
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s %s", properties.Type, version)), nil
+}
+
+// do 发送带认证信息的请求，会话过期时自动重新登录一次
+func (d *NessusDriver) do(ctx context.Context, job *Job, r Request) error {
+	client := NewHTTPClient(job)
+
+	header, err := d.authHeader(ctx, client, job, false)
+	if err != nil {
+		return err
+	}
+	r.Header = header
+
+	err = Do(ctx, client, r)
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusUnauthorized && job.APIKey == "" {
+		if r.Header, err = d.authHeader(ctx, client, job, true); err != nil {
+			return err
+		}
+		err = Do(ctx, client, r)
+	}
+	return err
+}
+
+// authHeader 生成认证请求头
+func (d *NessusDriver) authHeader(ctx context.Context, client *http.Client, job *Job, refresh bool) (map[string]string, error) {
+	if job.APIKey != "" {
+		keys := job.APIKey
+		if !strings.Contains(keys, "accessKey=") {
+			parts := strings.SplitN(keys, ":", 2)
+			if len(parts) != 2 {
+				return nil, errors.New("Nessus API密钥格式应为 accessKey:secretKey")
+			}
+			keys = fmt.Sprintf("accessKey=%s; secretKey=%s", parts[0], parts[1])
+		}
+		return map[string]string{"X-ApiKeys": keys}, nil
+	}
+
+	if job.Username == "" {
+		return nil, errors.New("未配置Nessus API密钥或用户名密码")
+	}
+
+	cacheKey := job.ScannerURL + "|" + job.Username
+	d.mu.Lock()
+	token := d.sessions[cacheKey]
+	d.mu.Unlock()
+
+	if token == "" || refresh {
+		var session struct {
+			Token string `json:"token"`
+		}
+		err := Do(ctx, client, Request{
+			Method: http.MethodPost,
+			URL:    job.ScannerURL + "/session",
+			Body: map[string]string{
+				"username": job.Username,
+				"password": job.Password,
+			},
+			Result: &session,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("登录Nessus失败: %w", err)
+		}
+		token = session.Token
+
+		d.mu.Lock()
+		if d.sessions == nil {
+			d.sessions = make(map[string]string)
+		}
+		d.sessions[cacheKey] = token
+		d.mu.Unlock()
+	}
+
+	return map[string]string{"X-Cookie": "token=" + token}, nil
+}
+
+// nessusReport .nessus (NessusClientData_v2) 报告结构
+type nessusReport struct {
+	Hosts []struct {
+		Name       string `xml:"name,attr"`
+		Properties []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"HostProperties>tag"`
+		Items []struct {
+			Port         string   `xml:"port,attr"`
+			Protocol     string   `xml:"protocol,attr"`
+			ServiceName  string   `xml:"svc_name,attr"`
+			Severity     int      `xml:"severity,attr"`
+			PluginID     string   `xml:"pluginID,attr"`
+			PluginName   string   `xml:"pluginName,attr"`
+			PluginFamily string   `xml:"pluginFamily,attr"`
+			Synopsis     string   `xml:"synopsis"`
+			Description  string   `xml:"description"`
+			Solution     string   `xml:"solution"`
+			SeeAlso      string   `xml:"see_also"`
+			PluginOutput string   `xml:"plugin_output"`
+			CVEs         []string `xml:"cve"`
+			CVSS3        float64  `xml:"cvss3_base_score"`
+			CVSS         float64  `xml:"cvss_base_score"`
+		} `xml:"ReportItem"`
+	} `xml:"Report>ReportHost"`
+}
+
+// ParseNessusReport 解析 .nessus 报告，每个插件输出生成一条扫描结果
+func ParseNessusReport(data []byte, includeInfo bool) ([]models.ScanResult, error) {
+	var report nessusReport
+	if err := xml.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("解析Nessus报告失败: %v", err)
+	}
+
+	var results []models.ScanResult
+	for _, host := range report.Hosts {
+		hostIP := host.Name
+		for _, prop := range host.Properties {
+			if prop.Name == "host-ip" && prop.Value != "" {
+				hostIP = prop.Value
+			}
+		}
+
+		for _, item := range host.Items {
+			if item.Severity == 0 && !includeInfo {
+				continue
+			}
+
+			description := strings.TrimSpace(item.Synopsis)
+			if desc := strings.TrimSpace(item.Description); desc != "" {
+				if description != "" {
+					description += "\n\n"
+				}
+				description += desc
+			}
+
+			port := item.Port
+			if port == "0" {
+				port = ""
+			} else if port != "" && item.Protocol != "" {
+				port += "/" + item.Protocol
+			}
+
+			cvss := item.CVSS3
+			if cvss == 0 {
+				cvss = item.CVSS
+			}
+
+			var references []string
+			if seeAlso := strings.TrimSpace(item.SeeAlso); seeAlso != "" {
+				references = append(references, seeAlso)
+			}
+			cve := ""
+			if len(item.CVEs) > 0 {
+				cve = item.CVEs[0]
+				if len(item.CVEs) > 1 {
+					references = append(references, "CVE: "+strings.Join(item.CVEs, ", "))
+				}
+			}
+			references = append(references, "Nessus Plugin ID: "+item.PluginID)
+
+			output := strings.TrimSpace(item.PluginOutput)
+			var evidence []models.ScanEvidence
+			if output != "" {
+				evidence = append(evidence, PluginOutputEvidence(output))
+			}
+
+			results = append(results, models.ScanResult{
+				VulnerabilityName: item.PluginName,
+				Description:       description,
+				Severity:          nessusSeverity(item.Severity),
+				AffectedIP:        hostIP,
+				AffectedPort:      port,
+				Detail:            truncate(output, maxDetailSize),
+				Category:          item.PluginFamily,
+				CVE:               cve,
+				CVSS:              cvss,
+				Solution:          strings.TrimSpace(item.Solution),
+				References:        strings.Join(references, "\n"),
+				Evidence:          evidence,
+			})
+		}
+	}
+
+	return results, nil
+}
+
+// nessusSeverity 将Nessus严重程度(0-4)映射为系统严重程度
+func nessusSeverity(severity int) models.Severity {
+	switch severity {
+	case 4:
+		return models.SeverityCritical
+	case 3:
+		return models.SeverityHigh
+	case 2:
+		return models.SeverityMedium
+	case 1:
+		return models.SeverityLow
+	default:
+		return models.SeverityInfo
+	}
+}
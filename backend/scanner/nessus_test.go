@@ -0,0 +1,290 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vulnark/vulnark/models"
+)
+
+const testNessusReport = `<?xml version="1.0" ?>
+<NessusClientData_v2>
+  <Report name="test">
+    <ReportHost name="web01">
+      <HostProperties><tag name="host-ip">10.0.0.5</tag></HostProperties>
+      <ReportItem port="443" svc_name="www" protocol="tcp" severity="4" pluginID="100" pluginName="OpenSSL Heartbleed" pluginFamily="Misc.">
+        <synopsis>Memory disclosure</synopsis>
+        <description>The remote service is affected by Heartbleed.</description>
+        <solution>Upgrade OpenSSL.</solution>
+        <cve>CVE-2014-0160</cve>
+        <cvss3_base_score>7.5</cvss3_base_score>
+        <plugin_output>heartbeat response leaked 16384 bytes</plugin_output>
+      </ReportItem>
+      <ReportItem port="0" svc_name="general" protocol="tcp" severity="0" pluginID="19506" pluginName="Nessus Scan Information" pluginFamily="Settings">
+      </ReportItem>
+    </ReportHost>
+  </Report>
+</NessusClientData_v2>`
+
+// fakeNessus 模拟Nessus REST API
+type fakeNessus struct {
+	mu           sync.Mutex
+	apiKeys      []string
+	statuses     []string // 扫描依次返回的状态
+	exportStatus string
+	created      map[string]interface{}
+	launched     bool
+	stopped      bool
+	deleted      bool
+	sessions     int
+	expireOnce   bool // 第一个带会话令牌的请求返回401
+}
+
+func (f *fakeNessus) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	auth := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			f.mu.Lock()
+			if key := r.Header.Get("X-ApiKeys"); key != "" {
+				f.apiKeys = append(f.apiKeys, key)
+			} else if cookie := r.Header.Get("X-Cookie"); cookie != "" && f.expireOnce {
+				f.expireOnce = false
+				f.mu.Unlock()
+				http.Error(w, `{"error":"Invalid Credentials"}`, http.StatusUnauthorized)
+				return
+			} else if cookie == "" {
+				f.mu.Unlock()
+				http.Error(w, `{"error":"Invalid Credentials"}`, http.StatusUnauthorized)
+				return
+			}
+			f.mu.Unlock()
+			next(w, r)
+		}
+	}
+
+	mux.HandleFunc("/session", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.sessions++
+		f.mu.Unlock()
+		w.Write([]byte(`{"token":"session-token"}`))
+	})
+	mux.HandleFunc("/editor/scan/templates", auth(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"templates":[{"uuid":"tpl-basic","name":"basic","title":"Basic Network Scan"}]}`))
+	}))
+	mux.HandleFunc("/scans", auth(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("解析创建扫描请求失败: %v", err)
+		}
+		f.mu.Lock()
+		f.created = body
+		f.mu.Unlock()
+		w.Write([]byte(`{"scan":{"id":7}}`))
+	}))
+	mux.HandleFunc("/scans/7/launch", auth(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.launched = true
+		f.mu.Unlock()
+		w.Write([]byte(`{"scan_uuid":"abc"}`))
+	}))
+	mux.HandleFunc("/scans/7/stop", auth(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.stopped = true
+		f.mu.Unlock()
+	}))
+	mux.HandleFunc("/scans/7", auth(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			f.mu.Lock()
+			f.deleted = true
+			f.mu.Unlock()
+			return
+		}
+		f.mu.Lock()
+		status := "completed"
+		if len(f.statuses) > 0 {
+			status, f.statuses = f.statuses[0], f.statuses[1:]
+		}
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"info":  map[string]string{"status": status},
+			"hosts": []map[string]interface{}{{"scanprogresscurrent": 50, "scanprogresstotal": 100}},
+		})
+	}))
+	mux.HandleFunc("/scans/7/export", auth(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"file":3,"token":"t"}`))
+	}))
+	mux.HandleFunc("/scans/7/export/3/status", auth(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		status := f.exportStatus
+		f.mu.Unlock()
+		w.Write([]byte(`{"status":"` + status + `"}`))
+	}))
+	mux.HandleFunc("/scans/7/export/3/download", auth(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testNessusReport))
+	}))
+	return mux
+}
+
+func newNessusJob(server *httptest.Server, params map[string]interface{}) *Job {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	return &Job{
+		TaskID:     1,
+		Type:       models.ScannerTypeNessus,
+		ScannerURL: server.URL,
+		APIKey:     "ak:sk",
+		TargetIPs:  []string{"10.0.0.5", "10.0.0.6"},
+		Parameters: params,
+	}
+}
+
+func TestNessusDriverRun(t *testing.T) {
+	fake := &fakeNessus{statuses: []string{"running", "completed"}, exportStatus: "ready"}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	job := newNessusJob(server, map[string]interface{}{"delete_scan": true})
+	var progress []int
+	results, err := Run(context.Background(), &NessusDriver{}, job, RunOptions{
+		PollInterval: 5 * time.Millisecond,
+		OnStatus:     func(s *Status) { progress = append(progress, s.Progress) },
+	})
+	if err != nil {
+		t.Fatalf("Run 返回错误: %v", err)
+	}
+
+	if !fake.launched {
+		t.Error("未启动Nessus扫描")
+	}
+	settings, _ := fake.created["settings"].(map[string]interface{})
+	if fake.created["uuid"] != "tpl-basic" || settings["text_targets"] != "10.0.0.5,10.0.0.6" {
+		t.Errorf("创建扫描的请求 = %v", fake.created)
+	}
+	if len(progress) != 2 || progress[0] != 50 || progress[1] != 100 {
+		t.Errorf("扫描进度 = %v", progress)
+	}
+	for _, key := range fake.apiKeys {
+		if key != "accessKey=ak; secretKey=sk" {
+			t.Fatalf("X-ApiKeys = %q", key)
+		}
+	}
+	if !fake.deleted {
+		t.Error("delete_scan=true 时应删除Nessus上的扫描")
+	}
+
+	// 信息级别的插件输出默认不导入
+	if len(results) != 1 {
+		t.Fatalf("扫描结果数量 = %d, want 1", len(results))
+	}
+	r := results[0]
+	if r.VulnerabilityName != "OpenSSL Heartbleed" || r.Severity != models.SeverityCritical ||
+		r.AffectedIP != "10.0.0.5" || r.AffectedPort != "443/tcp" || r.CVE != "CVE-2014-0160" || r.CVSS != 7.5 {
+		t.Errorf("扫描结果 = %+v", r)
+	}
+	if len(r.Evidence) != 1 {
+		t.Errorf("插件输出应作为证据保存: %+v", r.Evidence)
+	}
+}
+
+func TestNessusDriverSessionRelogin(t *testing.T) {
+	fake := &fakeNessus{}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	driver := &NessusDriver{}
+	job := newNessusJob(server, nil)
+	job.APIKey = ""
+	job.Username, job.Password = "admin", "secret"
+
+	if _, err := driver.Status(context.Background(), job, "7"); err != nil {
+		t.Fatalf("Status 返回错误: %v", err)
+	}
+	// 会话令牌被缓存，过期后自动重新登录一次
+	fake.expireOnce = true
+	status, err := driver.Status(context.Background(), job, "7")
+	if err != nil {
+		t.Fatalf("会话过期后 Status 返回错误: %v", err)
+	}
+	if status.State != StateCompleted {
+		t.Errorf("扫描状态 = %s", status.State)
+	}
+	if fake.sessions != 2 {
+		t.Errorf("登录次数 = %d, want 2", fake.sessions)
+	}
+}
+
+func TestNessusDriverStatusMapping(t *testing.T) {
+	cases := map[string]State{
+		"running":   StateRunning,
+		"paused":    StateRunning,
+		"completed": StateCompleted,
+		"canceled":  StateCancelled,
+		"aborted":   StateCancelled,
+		"empty":     StateFailed,
+	}
+	for nessusStatus, want := range cases {
+		fake := &fakeNessus{statuses: []string{nessusStatus}}
+		server := httptest.NewServer(fake.handler(t))
+		status, err := (&NessusDriver{}).Status(context.Background(), newNessusJob(server, nil), "7")
+		server.Close()
+		if err != nil {
+			t.Errorf("%s: Status 返回错误: %v", nessusStatus, err)
+			continue
+		}
+		if status.State != want {
+			t.Errorf("%s: State = %s, want %s", nessusStatus, status.State, want)
+		}
+	}
+}
+
+func TestNessusDriverExportError(t *testing.T) {
+	fake := &fakeNessus{exportStatus: "error"}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	_, err := (&NessusDriver{}).Results(context.Background(), newNessusJob(server, nil), "7")
+	if err == nil || !strings.Contains(err.Error(), "导出失败") {
+		t.Errorf("导出失败时应返回错误，实际为 %v", err)
+	}
+}
+
+func TestNessusDriverExportTimeout(t *testing.T) {
+	fake := &fakeNessus{exportStatus: "loading"}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	start := time.Now()
+	job := newNessusJob(server, map[string]interface{}{"export_timeout": float64(1)})
+	_, err := (&NessusDriver{}).Results(context.Background(), job, "7")
+	if err == nil || !strings.Contains(err.Error(), "超时") {
+		t.Errorf("导出超时应返回错误，实际为 %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("等待导出耗时 %s，超过了 export_timeout", elapsed)
+	}
+}
+
+func TestNessusDriverCancel(t *testing.T) {
+	fake := &fakeNessus{statuses: []string{"running", "running", "running"}}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := Run(ctx, &NessusDriver{}, newNessusJob(server, nil), RunOptions{
+		PollInterval: 5 * time.Millisecond,
+		OnStatus:     func(*Status) { cancel() },
+	})
+	if err != context.Canceled {
+		t.Errorf("错误 = %v, want context.Canceled", err)
+	}
+	if !fake.stopped {
+		t.Error("取消时应停止Nessus上的扫描")
+	}
+}
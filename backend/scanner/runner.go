@@ -0,0 +1,105 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vulnark/vulnark/models"
+)
+
+// ErrScanCancelled 扫描在扫描器侧被取消
+var ErrScanCancelled = errors.New("扫描已被取消")
+
+// RunOptions 扫描执行选项
+type RunOptions struct {
+	// PollInterval 轮询扫描状态的间隔，默认10秒
+	PollInterval time.Duration
+	// OnStatus 每次获取到扫描进度时回调
+	OnStatus func(status *Status)
+	// KeepPartial ctx 被取消时调用，返回 true 时在停止扫描后尝试获取已产生的部分结果
+	KeepPartial func() bool
+}
+
+// Run 通过驱动启动扫描并轮询直到结束，返回扫描结果
+// ctx 被取消时会停止扫描器上的扫描，并按 KeepPartial 决定是否返回部分结果，此时错误为 ctx.Err()
+func Run(ctx context.Context, driver ScannerDriver, job *Job, opts RunOptions) ([]models.ScanResult, error) {
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	scanID, err := driver.Launch(ctx, job)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("启动扫描失败: %w", err)
+	}
+	job.Log("扫描已启动: scan_id=%s", scanID)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return stop(ctx, driver, job, scanID, opts)
+		case <-ticker.C:
+		}
+
+		status, err := driver.Status(ctx, job, scanID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return stop(ctx, driver, job, scanID, opts)
+			}
+			return nil, fmt.Errorf("查询扫描状态失败: %w", err)
+		}
+		if opts.OnStatus != nil {
+			opts.OnStatus(status)
+		}
+
+		switch status.State {
+		case StateCompleted:
+			results, err := driver.Results(ctx, job, scanID)
+			if err != nil {
+				if ctx.Err() != nil {
+					return stop(ctx, driver, job, scanID, opts)
+				}
+				return nil, fmt.Errorf("获取扫描结果失败: %w", err)
+			}
+			job.Log("扫描完成，共获取%d条结果", len(results))
+			return results, nil
+		case StateFailed:
+			return nil, WithReason(models.ScanFailureScanner, fmt.Errorf("扫描器报告扫描失败: %s", status.Message))
+		case StateCancelled:
+			return nil, ErrScanCancelled
+		}
+	}
+}
+
+// stop 停止扫描器上的扫描，需要时获取部分结果
+func stop(ctx context.Context, driver ScannerDriver, job *Job, scanID string, opts RunOptions) ([]models.ScanResult, error) {
+	// ctx 已取消，使用独立的上下文与扫描器通信
+	stopCtx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	if err := driver.Cancel(stopCtx, job, scanID); err != nil {
+		job.Log("停止扫描器上的扫描失败: %v", err)
+	} else {
+		job.Log("已停止扫描器上的扫描: scan_id=%s", scanID)
+	}
+
+	if opts.KeepPartial == nil || !opts.KeepPartial() {
+		return nil, ctx.Err()
+	}
+
+	results, err := driver.Results(stopCtx, job, scanID)
+	if err != nil {
+		job.Log("获取部分扫描结果失败: %v", err)
+		return nil, ctx.Err()
+	}
+	job.Log("已获取%d条部分扫描结果", len(results))
+	return results, ctx.Err()
+}
@@ -0,0 +1,136 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vulnark/vulnark/models"
+)
+
+// fakeDriver 按预设的状态序列返回扫描状态，记录 Cancel 和 Results 的调用
+type fakeDriver struct {
+	mu        sync.Mutex
+	launchErr error
+	statuses  []State
+	polled    int
+	cancelled bool
+	fetched   bool
+	results   []models.ScanResult
+}
+
+func (d *fakeDriver) Launch(ctx context.Context, job *Job) (string, error) {
+	if d.launchErr != nil {
+		return "", d.launchErr
+	}
+	return "fake-1", nil
+}
+
+func (d *fakeDriver) Status(ctx context.Context, job *Job, scanID string) (*Status, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	state := StateRunning
+	if d.polled < len(d.statuses) {
+		state = d.statuses[d.polled]
+	}
+	d.polled++
+	return &Status{State: state, Progress: d.polled * 10, Message: "fake"}, nil
+}
+
+func (d *fakeDriver) Cancel(ctx context.Context, job *Job, scanID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.cancelled = true
+	return nil
+}
+
+func (d *fakeDriver) Results(ctx context.Context, job *Job, scanID string) ([]models.ScanResult, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.fetched = true
+	return d.results, nil
+}
+
+func testRunOptions() RunOptions {
+	return RunOptions{PollInterval: 5 * time.Millisecond}
+}
+
+func TestRunCompleted(t *testing.T) {
+	driver := &fakeDriver{
+		statuses: []State{StateRunning, StateRunning, StateCompleted},
+		results:  []models.ScanResult{{VulnerabilityName: "SQL Injection", Severity: models.SeverityHigh}},
+	}
+	var progress []int
+	opts := testRunOptions()
+	opts.OnStatus = func(status *Status) { progress = append(progress, status.Progress) }
+
+	results, err := Run(context.Background(), driver, &Job{}, opts)
+	if err != nil {
+		t.Fatalf("Run 返回错误: %v", err)
+	}
+	if len(results) != 1 || results[0].VulnerabilityName != "SQL Injection" {
+		t.Errorf("扫描结果 = %+v", results)
+	}
+	if len(progress) != 3 {
+		t.Errorf("OnStatus 调用次数 = %d, want 3", len(progress))
+	}
+	if driver.cancelled {
+		t.Error("正常完成的扫描不应调用 Cancel")
+	}
+}
+
+func TestRunFailed(t *testing.T) {
+	driver := &fakeDriver{statuses: []State{StateRunning, StateFailed}}
+
+	_, err := Run(context.Background(), driver, &Job{}, testRunOptions())
+	if err == nil {
+		t.Fatal("扫描器报告失败时应返回错误")
+	}
+	if reason := ClassifyError(err); reason != models.ScanFailureScanner {
+		t.Errorf("失败原因 = %s, want %s", reason, models.ScanFailureScanner)
+	}
+}
+
+func TestRunCancelledByScanner(t *testing.T) {
+	driver := &fakeDriver{statuses: []State{StateCancelled}}
+
+	if _, err := Run(context.Background(), driver, &Job{}, testRunOptions()); !errors.Is(err, ErrScanCancelled) {
+		t.Errorf("扫描器上的扫描被取消时应返回 ErrScanCancelled，实际为 %v", err)
+	}
+}
+
+func TestRunLaunchError(t *testing.T) {
+	driver := &fakeDriver{launchErr: errors.New("connection refused")}
+
+	if _, err := Run(context.Background(), driver, &Job{}, testRunOptions()); err == nil {
+		t.Fatal("启动失败时应返回错误")
+	}
+	if driver.polled != 0 {
+		t.Error("启动失败后不应查询状态")
+	}
+}
+
+func TestRunContextCancelled(t *testing.T) {
+	for _, keep := range []bool{false, true} {
+		driver := &fakeDriver{results: []models.ScanResult{{VulnerabilityName: "XSS"}}}
+		ctx, cancel := context.WithCancel(context.Background())
+		opts := testRunOptions()
+		opts.OnStatus = func(status *Status) { cancel() }
+		keepPartial := keep
+		opts.KeepPartial = func() bool { return keepPartial }
+
+		results, err := Run(ctx, driver, &Job{}, opts)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("keep=%v: 错误 = %v, want context.Canceled", keep, err)
+		}
+		if !driver.cancelled {
+			t.Errorf("keep=%v: 应停止扫描器上的扫描", keep)
+		}
+		if driver.fetched != keep || (len(results) == 1) != keep {
+			t.Errorf("keep=%v: 获取部分结果 = %v, results = %+v", keep, driver.fetched, results)
+		}
+	}
+}
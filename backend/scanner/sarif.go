@@ -0,0 +1,341 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/vulnark/vulnark/models"
+)
+
+// SarifFinding SARIF结果中的一条发现，已合并对应规则的信息
+type SarifFinding struct {
+	RunIndex    int
+	Tool        string
+	ToolVersion string
+
+	RuleID      string
+	Title       string
+	Message     string
+	Description string
+	Help        string
+	HelpURI     string
+
+	Level            string
+	SecuritySeverity float64
+	Severity         models.Severity
+	CWEs             []string // 形如 CWE-79
+	Tags             []string
+
+	URI         string
+	StartLine   int
+	EndLine     int
+	StartColumn int
+	Snippet     string
+	Fingerprint string
+}
+
+// Location 返回形如 "path/to/file.go:12-15" 的位置描述
+func (f *SarifFinding) Location() string {
+	if f.URI == "" {
+		return ""
+	}
+	location := f.URI
+	if f.StartLine > 0 {
+		location += ":" + strconv.Itoa(f.StartLine)
+		if f.EndLine > f.StartLine {
+			location += "-" + strconv.Itoa(f.EndLine)
+		}
+	}
+	return location
+}
+
+type sarifMessage struct {
+	Text     string `json:"text"`
+	Markdown string `json:"markdown"`
+}
+
+func (m sarifMessage) String() string {
+	if m.Text != "" {
+		return m.Text
+	}
+	return m.Markdown
+}
+
+type sarifRule struct {
+	ID                   string       `json:"id"`
+	Name                 string       `json:"name"`
+	ShortDescription     sarifMessage `json:"shortDescription"`
+	FullDescription      sarifMessage `json:"fullDescription"`
+	Help                 sarifMessage `json:"help"`
+	HelpURI              string       `json:"helpUri"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+	Properties    map[string]interface{} `json:"properties"`
+	Relationships []struct {
+		Target struct {
+			ID            string `json:"id"`
+			ToolComponent struct {
+				Name string `json:"name"`
+			} `json:"toolComponent"`
+		} `json:"target"`
+	} `json:"relationships"`
+}
+
+type sarifLog struct {
+	Version string `json:"version"`
+	Runs    []struct {
+		Tool struct {
+			Driver struct {
+				Name           string      `json:"name"`
+				Version        string      `json:"version"`
+				SemanticVer    string      `json:"semanticVersion"`
+				Rules          []sarifRule `json:"rules"`
+				InformationURI string      `json:"informationUri"`
+			} `json:"driver"`
+		} `json:"tool"`
+		Results []struct {
+			RuleID    string       `json:"ruleId"`
+			RuleIndex *int         `json:"ruleIndex"`
+			Level     string       `json:"level"`
+			Message   sarifMessage `json:"message"`
+			Locations []struct {
+				PhysicalLocation struct {
+					ArtifactLocation struct {
+						URI string `json:"uri"`
+					} `json:"artifactLocation"`
+					Region struct {
+						StartLine   int `json:"startLine"`
+						EndLine     int `json:"endLine"`
+						StartColumn int `json:"startColumn"`
+						Snippet     struct {
+							Text string `json:"text"`
+						} `json:"snippet"`
+					} `json:"region"`
+				} `json:"physicalLocation"`
+			} `json:"locations"`
+			PartialFingerprints map[string]string      `json:"partialFingerprints"`
+			Properties          map[string]interface{} `json:"properties"`
+		} `json:"results"`
+	} `json:"runs"`
+}
+
+// cweTagPattern 匹配 "CWE-79"、"external/cwe/cwe-079"、"cwe:79" 等CWE标签
+var cweTagPattern = regexp.MustCompile(`(?i)cwe[-/:_ ]?0*(\d+)`)
+
+// ParseSARIF 解析SARIF 2.1.0日志，返回所有运行中的发现
+func ParseSARIF(data []byte) ([]SarifFinding, error) {
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		return nil, fmt.Errorf("解析SARIF失败: %v", err)
+	}
+	if log.Version != "" && !strings.HasPrefix(log.Version, "2.1") {
+		return nil, fmt.Errorf("不支持的SARIF版本: %s", log.Version)
+	}
+
+	var findings []SarifFinding
+	for runIndex, run := range log.Runs {
+		driver := run.Tool.Driver
+		version := driver.Version
+		if version == "" {
+			version = driver.SemanticVer
+		}
+
+		rulesByID := make(map[string]*sarifRule, len(driver.Rules))
+		for i := range driver.Rules {
+			rulesByID[driver.Rules[i].ID] = &driver.Rules[i]
+		}
+
+		for _, result := range run.Results {
+			var rule *sarifRule
+			if result.RuleIndex != nil && *result.RuleIndex >= 0 && *result.RuleIndex < len(driver.Rules) {
+				rule = &driver.Rules[*result.RuleIndex]
+			} else if r, ok := rulesByID[result.RuleID]; ok {
+				rule = r
+			}
+
+			finding := SarifFinding{
+				RunIndex:    runIndex,
+				Tool:        driver.Name,
+				ToolVersion: version,
+				RuleID:      result.RuleID,
+				Message:     result.Message.String(),
+				Level:       result.Level,
+			}
+
+			// 合并规则与结果上的属性，结果属性优先
+			var tags []string
+			if rule != nil {
+				if finding.RuleID == "" {
+					finding.RuleID = rule.ID
+				}
+				finding.Title = rule.ShortDescription.String()
+				if finding.Title == "" {
+					finding.Title = rule.Name
+				}
+				finding.Description = rule.FullDescription.String()
+				finding.Help = rule.Help.String()
+				finding.HelpURI = rule.HelpURI
+				if finding.Level == "" {
+					finding.Level = rule.DefaultConfiguration.Level
+				}
+				finding.SecuritySeverity = sarifSecuritySeverity(rule.Properties)
+				tags = append(tags, sarifTags(rule.Properties)...)
+
+				for _, rel := range rule.Relationships {
+					if strings.EqualFold(rel.Target.ToolComponent.Name, "CWE") && rel.Target.ID != "" {
+						finding.CWEs = appendUnique(finding.CWEs, "CWE-"+strings.TrimLeft(rel.Target.ID, "0"))
+					}
+				}
+			}
+			if s := sarifSecuritySeverity(result.Properties); s > 0 {
+				finding.SecuritySeverity = s
+			}
+			tags = append(tags, sarifTags(result.Properties)...)
+
+			for _, tag := range tags {
+				finding.Tags = appendUnique(finding.Tags, tag)
+				if m := cweTagPattern.FindStringSubmatch(tag); m != nil {
+					finding.CWEs = appendUnique(finding.CWEs, "CWE-"+m[1])
+				}
+			}
+
+			if finding.Title == "" {
+				finding.Title = finding.RuleID
+			}
+			if finding.Title == "" {
+				finding.Title = finding.Message
+			}
+			if finding.Level == "" {
+				finding.Level = "warning"
+			}
+			finding.Severity = SarifSeverity(finding.Level, finding.SecuritySeverity)
+
+			if len(result.Locations) > 0 {
+				loc := result.Locations[0].PhysicalLocation
+				finding.URI = loc.ArtifactLocation.URI
+				finding.StartLine = loc.Region.StartLine
+				finding.EndLine = loc.Region.EndLine
+				finding.StartColumn = loc.Region.StartColumn
+				finding.Snippet = loc.Region.Snippet.Text
+			}
+			for _, key := range []string{"primaryLocationLineHash", "primaryLocationStartColumnFingerprint"} {
+				if fp := result.PartialFingerprints[key]; fp != "" {
+					finding.Fingerprint = fp
+					break
+				}
+			}
+
+			findings = append(findings, finding)
+		}
+	}
+
+	return findings, nil
+}
+
+// SarifSeverity 根据 security-severity（CVSS分值）或 level 计算严重程度
+// 分值区间与GitHub代码扫描一致：>=9.0 严重，>=7.0 高危，>=4.0 中危，>0 低危
+func SarifSeverity(level string, securitySeverity float64) models.Severity {
+	if securitySeverity > 0 {
+		switch {
+		case securitySeverity >= 9.0:
+			return models.SeverityCritical
+		case securitySeverity >= 7.0:
+			return models.SeverityHigh
+		case securitySeverity >= 4.0:
+			return models.SeverityMedium
+		default:
+			return models.SeverityLow
+		}
+	}
+
+	switch strings.ToLower(level) {
+	case "error":
+		return models.SeverityHigh
+	case "warning":
+		return models.SeverityMedium
+	case "note":
+		return models.SeverityLow
+	default:
+		return models.SeverityInfo
+	}
+}
+
+// ToScanResult 将SARIF发现转换为扫描结果
+func (f *SarifFinding) ToScanResult() models.ScanResult {
+	var detail []string
+	if location := f.Location(); location != "" {
+		detail = append(detail, "Location: "+location)
+	}
+	if f.Snippet != "" {
+		detail = append(detail, "Snippet:\n"+f.Snippet)
+	}
+	if f.Tool != "" {
+		detail = append(detail, "Tool: "+strings.TrimSpace(f.Tool+" "+f.ToolVersion))
+	}
+
+	description := f.Message
+	if f.Description != "" && f.Description != f.Message {
+		description += "\n\n" + f.Description
+	}
+
+	category := f.RuleID
+	if len(f.CWEs) > 0 {
+		category = f.CWEs[0]
+	}
+
+	result := models.ScanResult{
+		VulnerabilityName: truncate(f.Title, 255),
+		Description:       strings.TrimSpace(description),
+		Severity:          f.Severity,
+		Detail:            strings.Join(detail, "\n"),
+		Category:          truncate(category, 100),
+		CVSS:              f.SecuritySeverity,
+		Solution:          f.Help,
+		References:        f.HelpURI,
+	}
+	if strings.HasPrefix(f.URI, "http://") || strings.HasPrefix(f.URI, "https://") {
+		result.AffectedURL = truncate(f.URI, 255)
+	}
+	return result
+}
+
+// sarifSecuritySeverity 读取 properties 中的 security-severity
+func sarifSecuritySeverity(props map[string]interface{}) float64 {
+	switch v := props["security-severity"].(type) {
+	case string:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f
+	case float64:
+		return v
+	}
+	return 0
+}
+
+// sarifTags 读取 properties 中的 tags
+func sarifTags(props map[string]interface{}) []string {
+	raw, ok := props["tags"].([]interface{})
+	if !ok {
+		return nil
+	}
+	tags := make([]string, 0, len(raw))
+	for _, t := range raw {
+		if s, ok := t.(string); ok && s != "" {
+			tags = append(tags, s)
+		}
+	}
+	return tags
+}
+
+// appendUnique 追加不重复的字符串
+func appendUnique(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
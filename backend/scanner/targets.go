@@ -0,0 +1,245 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxTargets 单个任务展开后的默认最大目标数
+const DefaultMaxTargets = 65536
+
+// ErrTooManyTargets 展开后的目标数超过上限
+var ErrTooManyTargets = errors.New("扫描目标数量超过上限")
+
+// ExpandIPTargets 展开IP目标列表，支持单个IP、主机名、CIDR（如 10.0.0.0/24）
+// 和IPv4范围（如 10.0.0.1-10.0.0.20 或 10.0.0.1-20），结果去重并保持原有顺序
+func ExpandIPTargets(entries []string, limit int) ([]string, error) {
+	if limit <= 0 {
+		limit = DefaultMaxTargets
+	}
+
+	seen := make(map[string]bool)
+	var targets []string
+	add := func(target string) error {
+		if seen[target] {
+			return nil
+		}
+		if len(targets) >= limit {
+			return fmt.Errorf("%w（%d）", ErrTooManyTargets, limit)
+		}
+		seen[target] = true
+		targets = append(targets, target)
+		return nil
+	}
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		switch {
+		case strings.Contains(entry, "/"):
+			_, ipNet, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("无效的CIDR: %s", entry)
+			}
+			ones, bits := ipNet.Mask.Size()
+			if bits-ones > 30 || 1<<uint(bits-ones) > limit {
+				return nil, fmt.Errorf("%w（%d）: %s", ErrTooManyTargets, limit, entry)
+			}
+			for ip := ipNet.IP.Mask(ipNet.Mask); ipNet.Contains(ip); ip = nextIP(ip) {
+				// IPv4 网段跳过网络地址和广播地址（/31、/32 除外）
+				if ip4 := ip.To4(); ip4 != nil && ones < 31 && (ip4.Equal(ipNet.IP.Mask(ipNet.Mask)) || isBroadcast(ip4, ipNet)) {
+					continue
+				}
+				if err := add(ip.String()); err != nil {
+					return nil, err
+				}
+			}
+
+		case strings.Contains(entry, "-") && net.ParseIP(strings.SplitN(entry, "-", 2)[0]) != nil:
+			start, end, err := parseIPRange(entry)
+			if err != nil {
+				return nil, err
+			}
+			if int(end-start)+1 > limit {
+				return nil, fmt.Errorf("%w（%d）: %s", ErrTooManyTargets, limit, entry)
+			}
+			for n := start; n <= end; n++ {
+				if err := add(uint32ToIP(n).String()); err != nil {
+					return nil, err
+				}
+				if n == end {
+					break
+				}
+			}
+
+		default:
+			if strings.HasPrefix(entry, "-") {
+				return nil, fmt.Errorf("无效的扫描目标: %s", entry)
+			}
+			if ip := net.ParseIP(entry); ip != nil {
+				entry = ip.String()
+			}
+			if err := add(strings.ToLower(entry)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return targets, nil
+}
+
+// parseIPRange 解析IPv4范围，结束地址可以只写最后一段
+func parseIPRange(entry string) (uint32, uint32, error) {
+	parts := strings.SplitN(entry, "-", 2)
+	startIP := net.ParseIP(strings.TrimSpace(parts[0])).To4()
+	if startIP == nil {
+		return 0, 0, fmt.Errorf("IP范围只支持IPv4: %s", entry)
+	}
+
+	endPart := strings.TrimSpace(parts[1])
+	var endIP net.IP
+	if n, err := strconv.Atoi(endPart); err == nil && n >= 0 && n <= 255 {
+		endIP = net.IPv4(startIP[0], startIP[1], startIP[2], byte(n)).To4()
+	} else {
+		endIP = net.ParseIP(endPart).To4()
+	}
+	if endIP == nil {
+		return 0, 0, fmt.Errorf("无效的IP范围: %s", entry)
+	}
+
+	start, end := binary.BigEndian.Uint32(startIP), binary.BigEndian.Uint32(endIP)
+	if end < start {
+		return 0, 0, fmt.Errorf("IP范围的结束地址小于起始地址: %s", entry)
+	}
+	return start, end, nil
+}
+
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+func isBroadcast(ip net.IP, ipNet *net.IPNet) bool {
+	network := ipNet.IP.To4()
+	mask := ipNet.Mask
+	if len(mask) == net.IPv6len {
+		mask = mask[12:]
+	}
+	for i := range ip {
+		if ip[i] != network[i]|^mask[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func uint32ToIP(n uint32) net.IP {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, n)
+	return ip
+}
+
+// Exclusions 排除列表，支持IP、CIDR、IPv4范围和主机名
+type Exclusions struct {
+	nets   []*net.IPNet
+	ranges [][2]uint32
+	hosts  map[string]bool
+}
+
+// ParseExclusions 解析排除列表
+func ParseExclusions(entries []string) (*Exclusions, error) {
+	e := &Exclusions{hosts: make(map[string]bool)}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		switch {
+		case strings.Contains(entry, "://"):
+			u, err := url.Parse(entry)
+			if err != nil || u.Hostname() == "" {
+				return nil, fmt.Errorf("无效的排除目标: %s", entry)
+			}
+			e.hosts[strings.ToLower(u.Hostname())] = true
+		case strings.Contains(entry, "/"):
+			_, ipNet, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("无效的排除CIDR: %s", entry)
+			}
+			e.nets = append(e.nets, ipNet)
+		case strings.Contains(entry, "-") && net.ParseIP(strings.SplitN(entry, "-", 2)[0]) != nil:
+			start, end, err := parseIPRange(entry)
+			if err != nil {
+				return nil, err
+			}
+			e.ranges = append(e.ranges, [2]uint32{start, end})
+		default:
+			if ip := net.ParseIP(entry); ip != nil {
+				entry = ip.String()
+			}
+			e.hosts[strings.ToLower(entry)] = true
+		}
+	}
+	return e, nil
+}
+
+// Empty 是否没有任何排除项
+func (e *Exclusions) Empty() bool {
+	return e == nil || (len(e.nets) == 0 && len(e.ranges) == 0 && len(e.hosts) == 0)
+}
+
+// ExcludesHost 判断IP或主机名是否被排除
+func (e *Exclusions) ExcludesHost(host string) bool {
+	if e.Empty() {
+		return false
+	}
+	host = strings.ToLower(strings.Trim(strings.TrimSpace(host), "[]"))
+	ip := net.ParseIP(host)
+	if ip != nil {
+		host = ip.String()
+	}
+	if e.hosts[host] {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range e.nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		n := binary.BigEndian.Uint32(ip4)
+		for _, r := range e.ranges {
+			if n >= r[0] && n <= r[1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ExcludesURL 判断URL的主机是否被排除
+func (e *Exclusions) ExcludesURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	return e.ExcludesHost(u.Hostname())
+}
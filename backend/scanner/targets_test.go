@@ -0,0 +1,106 @@
+package scanner
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestExpandIPTargets(t *testing.T) {
+	cases := []struct {
+		entries []string
+		want    []string
+	}{
+		{[]string{"10.0.0.1", " Example.COM ", ""}, []string{"10.0.0.1", "example.com"}},
+		{[]string{"192.168.1.0/30"}, []string{"192.168.1.1", "192.168.1.2"}},
+		{[]string{"192.168.1.0/31"}, []string{"192.168.1.0", "192.168.1.1"}},
+		{[]string{"10.0.0.1-3"}, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+		{[]string{"10.0.0.254-10.0.1.1"}, []string{"10.0.0.254", "10.0.0.255", "10.0.1.0", "10.0.1.1"}},
+		{[]string{"10.0.0.2", "10.0.0.1-3", "10.0.0.2"}, []string{"10.0.0.2", "10.0.0.1", "10.0.0.3"}},
+		{[]string{"2001:db8::1"}, []string{"2001:db8::1"}},
+	}
+	for _, c := range cases {
+		got, err := ExpandIPTargets(c.entries, 0)
+		if err != nil {
+			t.Errorf("ExpandIPTargets(%v) 返回错误: %v", c.entries, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("ExpandIPTargets(%v) = %v, want %v", c.entries, got, c.want)
+		}
+	}
+}
+
+func TestExpandIPTargetsInvalid(t *testing.T) {
+	for _, entry := range []string{"10.0.0.0/33", "10.0.0.5-10.0.0.1", "10.0.0.1-300", "-oX"} {
+		if _, err := ExpandIPTargets([]string{entry}, 0); err == nil {
+			t.Errorf("ExpandIPTargets(%q) 应返回错误", entry)
+		}
+	}
+}
+
+func TestExpandIPTargetsLimit(t *testing.T) {
+	for _, entries := range [][]string{
+		{"10.0.0.0/24"},
+		{"10.0.0.1-101"},
+		{"10.0.0.0/26", "10.0.1.0/26"},
+	} {
+		if _, err := ExpandIPTargets(entries, 100); !errors.Is(err, ErrTooManyTargets) {
+			t.Errorf("ExpandIPTargets(%v, 100) 应返回 ErrTooManyTargets，实际为 %v", entries, err)
+		}
+	}
+	if got, err := ExpandIPTargets([]string{"10.0.0.1-100"}, 100); err != nil || len(got) != 100 {
+		t.Errorf("恰好达到上限时不应报错: len=%d, err=%v", len(got), err)
+	}
+}
+
+func TestExclusions(t *testing.T) {
+	e, err := ParseExclusions([]string{
+		"10.0.0.0/24",
+		"192.168.1.10-20",
+		"Internal.example.com",
+		"https://admin.example.com:8443/login",
+		"2001:db8::1",
+	})
+	if err != nil {
+		t.Fatalf("ParseExclusions 返回错误: %v", err)
+	}
+
+	hosts := map[string]bool{
+		"10.0.0.1":             true,
+		"10.0.1.1":             false,
+		"192.168.1.15":         true,
+		"192.168.1.21":         false,
+		"internal.example.com": true,
+		"admin.example.com":    true,
+		"www.example.com":      false,
+		"[2001:db8::1]":        true,
+		"2001:db8:0::1":        true,
+	}
+	for host, want := range hosts {
+		if got := e.ExcludesHost(host); got != want {
+			t.Errorf("ExcludesHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+
+	urls := map[string]bool{
+		"http://10.0.0.8:8080/app":       true,
+		"https://INTERNAL.example.com/":  true,
+		"https://www.example.com/login":  false,
+		"not a url":                      false,
+		"https://[2001:db8::1]:8443/api": true,
+	}
+	for u, want := range urls {
+		if got := e.ExcludesURL(u); got != want {
+			t.Errorf("ExcludesURL(%q) = %v, want %v", u, got, want)
+		}
+	}
+
+	var empty *Exclusions
+	if !empty.Empty() || empty.ExcludesHost("10.0.0.1") {
+		t.Error("nil 排除列表不应排除任何目标")
+	}
+	if _, err := ParseExclusions([]string{"10.0.0.0/99"}); err == nil {
+		t.Error("无效的排除CIDR应返回错误")
+	}
+}
@@ -0,0 +1,590 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vulnark/vulnark/models"
+)
+
+// ZapDriver OWASP ZAP JSON API 扫描器驱动
+//
+// 对 TargetURLs 中的每个地址依次执行：传统爬虫 -> AJAX爬虫（可选）-> 主动扫描，
+// 全部完成后按地址拉取告警。ZAP的多阶段流程在驱动内部的后台协程中推进，
+// Status 返回的进度为所有阶段的整体百分比。
+//
+// ZAP只有一个全局的AJAX爬虫，同一ZAP地址上的扫描依次使用，其余扫描在AJAX爬虫阶段排队等待。
+//
+// 支持的扫描参数：
+//
+//	scan_policy      主动扫描使用的策略名称，默认使用ZAP的默认策略
+//	ajax_spider      是否运行AJAX爬虫，默认 false
+//	max_children     爬虫每个节点的最大子节点数，默认不限制
+//	context_name     爬虫使用的上下文名称
+//	include_info     是否导入信息级别的告警，默认 false
+//	include_messages 是否获取告警对应的HTTP请求和响应作为证据，默认 true
+type ZapDriver struct {
+	mu        sync.Mutex
+	scans     map[string]*zapScan
+	seq       int64
+	ajaxLocks map[string]chan struct{} // ZAP地址 -> AJAX爬虫使用权
+
+	pollInterval time.Duration // 轮询爬虫和主动扫描状态的间隔，默认5秒
+}
+
+// zapScan 一次ZAP扫描的执行状态
+type zapScan struct {
+	mu       sync.Mutex
+	progress int
+	phase    string
+	done     bool
+	err      error
+	cancel   context.CancelFunc
+	ascanIDs []string
+}
+
+func init() {
+	Register(models.ScannerTypeZap, &ZapDriver{})
+}
+
+// Launch 启动ZAP扫描流程，返回驱动内部的扫描ID
+func (d *ZapDriver) Launch(ctx context.Context, job *Job) (string, error) {
+	if job.ScannerURL == "" {
+		return "", errors.New("未配置ZAP API地址")
+	}
+	if len(job.TargetURLs) == 0 {
+		return "", errors.New("ZAP扫描需要至少一个目标URL")
+	}
+
+	// 先确认ZAP可访问且API密钥正确
+	var version struct {
+		Version string `json:"version"`
+	}
+	if err := d.call(ctx, job, "core/view/version", nil, &version); err != nil {
+		return "", fmt.Errorf("连接ZAP失败: %w", err)
+	}
+	job.Log("已连接ZAP %s", version.Version)
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	scan := &zapScan{phase: "starting", cancel: cancel}
+
+	d.mu.Lock()
+	if d.scans == nil {
+		d.scans = make(map[string]*zapScan)
+	}
+	d.seq++
+	scanID := fmt.Sprintf("zap-%d-%d", job.TaskID, d.seq)
+	d.scans[scanID] = scan
+	d.mu.Unlock()
+
+	go d.run(runCtx, job, scan)
+
+	return scanID, nil
+}
+
+// TestConnection 读取ZAP版本以确认API地址和API密钥可用
+func (d *ZapDriver) TestConnection(ctx context.Context, job *Job) (string, error) {
+	if job.ScannerURL == "" {
+		return "", errors.New("未配置ZAP API地址")
+	}
+
+	var version struct {
+		Version string `json:"version"`
+	}
+	if err := d.call(ctx, job, "core/view/version", nil, &version); err != nil {
+		return "", fmt.Errorf("连接ZAP失败: %w", err)
+	}
+	return "ZAP " + version.Version, nil
+}
+
+// Status 返回ZAP扫描的整体进度
+func (d *ZapDriver) Status(ctx context.Context, job *Job, scanID string) (*Status, error) {
+	scan, err := d.scan(scanID)
+	if err != nil {
+		return nil, err
+	}
+
+	scan.mu.Lock()
+	var status *Status
+	switch {
+	case scan.err != nil && errors.Is(scan.err, context.Canceled):
+		status = &Status{State: StateCancelled, Progress: scan.progress, Message: scan.phase}
+	case scan.err != nil:
+		status = &Status{State: StateFailed, Progress: scan.progress, Message: scan.err.Error()}
+	case scan.done:
+		status = &Status{State: StateCompleted, Progress: 100, Message: scan.phase}
+	default:
+		status = &Status{State: StateRunning, Progress: scan.progress, Message: scan.phase}
+	}
+	scan.mu.Unlock()
+
+	// 失败或取消的扫描不会再调用 Results，在这里清理状态
+	if status.State == StateFailed || status.State == StateCancelled {
+		d.forget(scanID)
+	}
+	return status, nil
+}
+
+// Cancel 停止ZAP上正在进行的爬虫和主动扫描
+func (d *ZapDriver) Cancel(ctx context.Context, job *Job, scanID string) error {
+	scan, err := d.scan(scanID)
+	if err != nil {
+		return err
+	}
+	scan.cancel()
+
+	scan.mu.Lock()
+	ascanIDs := append([]string(nil), scan.ascanIDs...)
+	scan.mu.Unlock()
+
+	var lastErr error
+	for _, id := range ascanIDs {
+		if err := d.call(ctx, job, "ascan/action/stop", url.Values{"scanId": {id}}, nil); err != nil {
+			lastErr = err
+		}
+	}
+	// AJAX爬虫由后台协程在退出前停止，此时它可能已属于其他扫描
+
+	d.forget(scanID)
+	return lastErr
+}
+
+// Results 拉取目标地址下的全部告警并转换为扫描结果
+func (d *ZapDriver) Results(ctx context.Context, job *Job, scanID string) ([]models.ScanResult, error) {
+	defer d.forget(scanID)
+
+	includeInfo := job.BoolParam("include_info", false)
+	includeMessages := job.BoolParam("include_messages", true)
+	const pageSize = 500
+
+	var results []models.ScanResult
+	for _, target := range job.TargetURLs {
+		for start := 0; ; start += pageSize {
+			var page struct {
+				Alerts []zapAlert `json:"alerts"`
+			}
+			params := url.Values{
+				"baseurl": {target},
+				"start":   {strconv.Itoa(start)},
+				"count":   {strconv.Itoa(pageSize)},
+			}
+			if err := d.call(ctx, job, "core/view/alerts", params, &page); err != nil {
+				return nil, fmt.Errorf("获取ZAP告警失败: %w", err)
+			}
+
+			for _, alert := range page.Alerts {
+				if strings.EqualFold(alert.Confidence, "False Positive") {
+					continue
+				}
+				severity := zapSeverity(alert.Risk)
+				if severity == models.SeverityInfo && !includeInfo {
+					continue
+				}
+				result := alert.toScanResult(severity)
+				if includeMessages && alert.MessageID != "" {
+					result.Evidence = d.messageEvidence(ctx, job, alert.MessageID)
+				}
+				results = append(results, result)
+			}
+
+			if len(page.Alerts) < pageSize {
+				break
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// run 在后台依次推进爬虫和主动扫描
+func (d *ZapDriver) run(ctx context.Context, job *Job, scan *zapScan) {
+	ajax := job.BoolParam("ajax_spider", false)
+
+	// 每个目标的阶段数，用于计算整体进度
+	phases := 2
+	if ajax {
+		phases = 3
+	}
+	total := len(job.TargetURLs) * phases
+	finished := 0
+
+	update := func(phase string, phaseProgress int) {
+		scan.mu.Lock()
+		scan.phase = phase
+		scan.progress = (finished*100 + phaseProgress) / total
+		scan.mu.Unlock()
+	}
+
+	fail := func(err error) {
+		scan.mu.Lock()
+		scan.err = err
+		scan.mu.Unlock()
+	}
+
+	for _, target := range job.TargetURLs {
+		// 传统爬虫
+		update("spider "+target, 0)
+		job.Log("ZAP开始爬取: %s", target)
+		spiderID, err := d.startSpider(ctx, job, target)
+		if err != nil {
+			fail(fmt.Errorf("启动ZAP爬虫失败: %w", err))
+			return
+		}
+		err = d.waitPercent(ctx, job, "spider/view/status", spiderID, func(p int) {
+			update("spider "+target, p)
+		})
+		if err != nil {
+			fail(err)
+			return
+		}
+		finished++
+
+		// AJAX爬虫
+		if ajax {
+			update("ajax spider "+target, 0)
+			job.Log("ZAP开始AJAX爬取: %s", target)
+			if err := d.runAjaxSpider(ctx, job, target); err != nil {
+				fail(err)
+				return
+			}
+			finished++
+		}
+
+		// 主动扫描
+		job.Log("ZAP开始主动扫描: %s", target)
+		ascanID, err := d.startActiveScan(ctx, job, target)
+		if err != nil {
+			fail(fmt.Errorf("启动ZAP主动扫描失败: %w", err))
+			return
+		}
+		scan.mu.Lock()
+		scan.ascanIDs = append(scan.ascanIDs, ascanID)
+		scan.mu.Unlock()
+		// Cancel 可能在记录扫描ID之前已读取列表，此时由这里停止刚启动的主动扫描
+		if ctx.Err() != nil {
+			d.stopActiveScan(job, ascanID)
+			fail(ctx.Err())
+			return
+		}
+		update("active scan "+target, 0)
+
+		err = d.waitPercent(ctx, job, "ascan/view/status", ascanID, func(p int) {
+			update("active scan "+target, p)
+		})
+		if err != nil {
+			fail(err)
+			return
+		}
+		finished++
+	}
+
+	scan.mu.Lock()
+	scan.done = true
+	scan.phase = "completed"
+	scan.progress = 100
+	scan.mu.Unlock()
+}
+
+// startSpider 启动传统爬虫
+func (d *ZapDriver) startSpider(ctx context.Context, job *Job, target string) (string, error) {
+	params := url.Values{"url": {target}, "recurse": {"true"}}
+	if maxChildren := job.IntParam("max_children", 0); maxChildren > 0 {
+		params.Set("maxChildren", strconv.Itoa(maxChildren))
+	}
+	if contextName := job.StringParam("context_name", ""); contextName != "" {
+		params.Set("contextName", contextName)
+	}
+
+	var resp struct {
+		Scan string `json:"scan"`
+	}
+	if err := d.call(ctx, job, "spider/action/scan", params, &resp); err != nil {
+		return "", err
+	}
+	return resp.Scan, nil
+}
+
+// runAjaxSpider 取得AJAX爬虫使用权后运行AJAX爬虫直到结束
+func (d *ZapDriver) runAjaxSpider(ctx context.Context, job *Job, target string) (err error) {
+	release, err := d.acquireAjaxSpider(ctx, job)
+	if err != nil {
+		return err
+	}
+	started := false
+	defer func() {
+		// 出错或取消时先停止本扫描启动的AJAX爬虫再释放使用权，避免停止其他扫描随后启动的爬虫
+		if started && err != nil {
+			stopCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if stopErr := d.call(stopCtx, job, "ajaxSpider/action/stop", nil, nil); stopErr != nil {
+				job.Log("停止ZAP AJAX爬虫失败: %v", stopErr)
+			}
+		}
+		release()
+	}()
+
+	params := url.Values{"url": {target}}
+	if contextName := job.StringParam("context_name", ""); contextName != "" {
+		params.Set("contextName", contextName)
+	}
+	if err := d.call(ctx, job, "ajaxSpider/action/scan", params, nil); err != nil {
+		return fmt.Errorf("启动ZAP AJAX爬虫失败: %w", err)
+	}
+	started = true
+
+	for {
+		var status struct {
+			Status string `json:"status"`
+		}
+		if err := d.call(ctx, job, "ajaxSpider/view/status", nil, &status); err != nil {
+			return fmt.Errorf("查询ZAP AJAX爬虫状态失败: %w", err)
+		}
+		if status.Status != "running" {
+			return nil
+		}
+		if err := sleepContext(ctx, d.interval()); err != nil {
+			return err
+		}
+	}
+}
+
+// acquireAjaxSpider 等待取得ZAP地址上AJAX爬虫的使用权，返回释放函数
+func (d *ZapDriver) acquireAjaxSpider(ctx context.Context, job *Job) (func(), error) {
+	key := strings.TrimRight(job.ScannerURL, "/")
+	d.mu.Lock()
+	if d.ajaxLocks == nil {
+		d.ajaxLocks = make(map[string]chan struct{})
+	}
+	lock, ok := d.ajaxLocks[key]
+	if !ok {
+		lock = make(chan struct{}, 1)
+		d.ajaxLocks[key] = lock
+	}
+	d.mu.Unlock()
+
+	select {
+	case lock <- struct{}{}:
+		return func() { <-lock }, nil
+	default:
+	}
+	job.Log("ZAP的AJAX爬虫正被其他扫描使用，等待其结束")
+	select {
+	case lock <- struct{}{}:
+		return func() { <-lock }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// interval 轮询状态的间隔
+func (d *ZapDriver) interval() time.Duration {
+	if d.pollInterval > 0 {
+		return d.pollInterval
+	}
+	return 5 * time.Second
+}
+
+// stopActiveScan 停止主动扫描，扫描的上下文已取消，使用独立的超时
+func (d *ZapDriver) stopActiveScan(job *Job, ascanID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := d.call(ctx, job, "ascan/action/stop", url.Values{"scanId": {ascanID}}, nil); err != nil {
+		job.Log("停止ZAP主动扫描失败: scan_id=%s, err=%v", ascanID, err)
+	}
+}
+
+// startActiveScan 启动主动扫描
+func (d *ZapDriver) startActiveScan(ctx context.Context, job *Job, target string) (string, error) {
+	params := url.Values{"url": {target}, "recurse": {"true"}}
+	if policy := job.StringParam("scan_policy", ""); policy != "" {
+		params.Set("scanPolicyName", policy)
+	}
+
+	var resp struct {
+		Scan string `json:"scan"`
+	}
+	if err := d.call(ctx, job, "ascan/action/scan", params, &resp); err != nil {
+		return "", err
+	}
+	return resp.Scan, nil
+}
+
+// waitPercent 轮询返回百分比状态的ZAP任务直到100%
+func (d *ZapDriver) waitPercent(ctx context.Context, job *Job, endpoint, scanID string, onProgress func(int)) error {
+	for {
+		var status struct {
+			Status string `json:"status"`
+		}
+		if err := d.call(ctx, job, endpoint, url.Values{"scanId": {scanID}}, &status); err != nil {
+			return fmt.Errorf("查询ZAP任务状态失败(%s): %w", endpoint, err)
+		}
+
+		percent, _ := strconv.Atoi(status.Status)
+		onProgress(percent)
+		if percent >= 100 {
+			return nil
+		}
+
+		if err := sleepContext(ctx, d.interval()); err != nil {
+			return err
+		}
+	}
+}
+
+// call 调用ZAP JSON API，endpoint 形如 "spider/action/scan"
+func (d *ZapDriver) call(ctx context.Context, job *Job, endpoint string, params url.Values, result interface{}) error {
+	if params == nil {
+		params = url.Values{}
+	}
+
+	header := map[string]string{}
+	if job.APIKey != "" {
+		header["X-ZAP-API-Key"] = job.APIKey
+	}
+
+	return Do(ctx, NewHTTPClient(job), Request{
+		Method: http.MethodGet,
+		URL:    job.ScannerURL + "/JSON/" + strings.Trim(endpoint, "/") + "/?" + params.Encode(),
+		Header: header,
+		Result: result,
+	})
+}
+
+// messageEvidence 获取告警对应的HTTP请求和响应作为证据，获取失败时只记录日志
+func (d *ZapDriver) messageEvidence(ctx context.Context, job *Job, messageID string) []models.ScanEvidence {
+	var resp struct {
+		Message struct {
+			RequestHeader  string `json:"requestHeader"`
+			RequestBody    string `json:"requestBody"`
+			ResponseHeader string `json:"responseHeader"`
+			ResponseBody   string `json:"responseBody"`
+		} `json:"message"`
+	}
+	if err := d.call(ctx, job, "core/view/message", url.Values{"id": {messageID}}, &resp); err != nil {
+		job.Log("获取ZAP告警的HTTP消息失败: message_id=%s, err=%v", messageID, err)
+		return nil
+	}
+
+	var evidence []models.ScanEvidence
+	if request := resp.Message.RequestHeader + resp.Message.RequestBody; request != "" {
+		evidence = append(evidence, HTTPRequestEvidence([]byte(request)))
+	}
+	if response := resp.Message.ResponseHeader + resp.Message.ResponseBody; response != "" {
+		evidence = append(evidence, HTTPResponseEvidence([]byte(response)))
+	}
+	return evidence
+}
+
+// scan 获取驱动内部的扫描状态
+func (d *ZapDriver) scan(scanID string) (*zapScan, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	scan, ok := d.scans[scanID]
+	if !ok {
+		return nil, fmt.Errorf("ZAP扫描不存在或服务已重启: %s", scanID)
+	}
+	return scan, nil
+}
+
+// forget 清理驱动内部的扫描状态
+func (d *ZapDriver) forget(scanID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.scans, scanID)
+}
+
+// zapAlert ZAP告警
+type zapAlert struct {
+	PluginID    string `json:"pluginId"`
+	MessageID   string `json:"messageId"`
+	Name        string `json:"name"`
+	Alert       string `json:"alert"`
+	Risk        string `json:"risk"`
+	Confidence  string `json:"confidence"`
+	URL         string `json:"url"`
+	Method      string `json:"method"`
+	Param       string `json:"param"`
+	Attack      string `json:"attack"`
+	Evidence    string `json:"evidence"`
+	Other       string `json:"other"`
+	Description string `json:"description"`
+	Solution    string `json:"solution"`
+	Reference   string `json:"reference"`
+	CWEID       string `json:"cweid"`
+	WASCID      string `json:"wascid"`
+}
+
+// toScanResult 将ZAP告警转换为扫描结果
+func (a zapAlert) toScanResult(severity models.Severity) models.ScanResult {
+	name := a.Name
+	if name == "" {
+		name = a.Alert
+	}
+
+	var detail []string
+	if a.Method != "" {
+		detail = append(detail, "Method: "+a.Method)
+	}
+	detail = append(detail, "URL: "+a.URL)
+	if a.Param != "" {
+		detail = append(detail, "Parameter: "+a.Param)
+	}
+	if a.Attack != "" {
+		detail = append(detail, "Attack: "+a.Attack)
+	}
+	if a.Evidence != "" {
+		detail = append(detail, "Evidence: "+a.Evidence)
+	}
+	if a.Confidence != "" {
+		detail = append(detail, "Confidence: "+a.Confidence)
+	}
+	if a.Other != "" {
+		detail = append(detail, "", a.Other)
+	}
+
+	category := ""
+	if a.CWEID != "" && a.CWEID != "0" && a.CWEID != "-1" {
+		category = "CWE-" + a.CWEID
+	}
+
+	references := strings.TrimSpace(a.Reference)
+	if a.PluginID != "" {
+		if references != "" {
+			references += "\n"
+		}
+		references += "ZAP Plugin ID: " + a.PluginID
+	}
+
+	return models.ScanResult{
+		VulnerabilityName: name,
+		Description:       a.Description,
+		Severity:          severity,
+		AffectedURL:       truncate(a.URL, 255),
+		Detail:            strings.Join(detail, "\n"),
+		Category:          category,
+		Solution:          a.Solution,
+		References:        references,
+	}
+}
+
+// zapSeverity 将ZAP风险等级映射为系统严重程度
+func zapSeverity(risk string) models.Severity {
+	switch strings.ToLower(risk) {
+	case "high":
+		return models.SeverityHigh
+	case "medium":
+		return models.SeverityMedium
+	case "low":
+		return models.SeverityLow
+	default:
+		return models.SeverityInfo
+	}
+}
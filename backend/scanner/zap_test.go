@@ -0,0 +1,306 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vulnark/vulnark/models"
+)
+
+// fakeZAP 模拟ZAP JSON API
+type fakeZAP struct {
+	mu          sync.Mutex
+	ascanStatus string // 主动扫描返回的进度
+	spiderFail  bool   // 启动爬虫时返回500
+	ajaxPolls   int    // 每次AJAX爬虫返回 running 的次数
+	apiKeys     []string
+	calls       []string
+
+	ajaxRunning bool // 是否有AJAX爬虫正在运行
+	ajaxLeft    int
+	ajaxOverlap bool // AJAX爬虫运行期间又被启动
+}
+
+func (f *fakeZAP) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		endpoint := strings.Trim(strings.TrimPrefix(r.URL.Path, "/JSON/"), "/")
+		f.mu.Lock()
+		f.apiKeys = append(f.apiKeys, r.Header.Get("X-ZAP-API-Key"))
+		f.calls = append(f.calls, endpoint)
+		ascanStatus, spiderFail := f.ascanStatus, f.spiderFail
+		f.mu.Unlock()
+
+		var resp interface{}
+		switch endpoint {
+		case "core/view/version":
+			resp = map[string]string{"version": "2.14.0"}
+		case "spider/action/scan":
+			if spiderFail {
+				http.Error(w, `{"code":"internal_error"}`, http.StatusInternalServerError)
+				return
+			}
+			resp = map[string]string{"scan": "1"}
+		case "spider/view/status":
+			resp = map[string]string{"status": "100"}
+		case "ajaxSpider/action/scan":
+			f.mu.Lock()
+			if f.ajaxRunning {
+				f.ajaxOverlap = true
+			}
+			f.ajaxRunning, f.ajaxLeft = true, f.ajaxPolls
+			f.mu.Unlock()
+			resp = map[string]string{"Result": "OK"}
+		case "ajaxSpider/view/status":
+			f.mu.Lock()
+			status := "stopped"
+			if f.ajaxRunning && f.ajaxLeft > 0 {
+				f.ajaxLeft--
+				status = "running"
+			} else {
+				f.ajaxRunning = false
+			}
+			f.mu.Unlock()
+			resp = map[string]string{"status": status}
+		case "ajaxSpider/action/stop":
+			f.mu.Lock()
+			f.ajaxRunning = false
+			f.mu.Unlock()
+			resp = map[string]string{"Result": "OK"}
+		case "ascan/action/scan":
+			resp = map[string]string{"scan": "2"}
+		case "ascan/view/status":
+			resp = map[string]string{"status": ascanStatus}
+		case "ascan/action/stop":
+			resp = map[string]string{"Result": "OK"}
+		case "core/view/alerts":
+			if r.URL.Query().Get("baseurl") != "https://app.example.com" {
+				resp = map[string]interface{}{"alerts": []interface{}{}}
+				break
+			}
+			resp = map[string]interface{}{"alerts": []map[string]string{
+				{"pluginId": "40018", "messageId": "9", "name": "SQL Injection", "risk": "High", "confidence": "Medium",
+					"url": "https://app.example.com/item?id=1", "method": "GET", "param": "id", "cweid": "89"},
+				{"pluginId": "10021", "name": "X-Content-Type-Options Header Missing", "risk": "Informational",
+					"confidence": "Medium", "url": "https://app.example.com/"},
+				{"pluginId": "10020", "name": "Missing Anti-clickjacking Header", "risk": "Medium",
+					"confidence": "False Positive", "url": "https://app.example.com/"},
+			}}
+		case "core/view/message":
+			resp = map[string]interface{}{"message": map[string]string{
+				"requestHeader":  "GET /item?id=1 HTTP/1.1\r\nHost: app.example.com\r\n\r\n",
+				"responseHeader": "HTTP/1.1 500 Internal Server Error\r\n\r\n",
+				"responseBody":   "SQL syntax error",
+			}}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+func (f *fakeZAP) called(endpoint string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.calls {
+		if c == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeZAP) count(endpoint string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, c := range f.calls {
+		if c == endpoint {
+			n++
+		}
+	}
+	return n
+}
+
+func newZAPJob(server *httptest.Server) *Job {
+	return &Job{
+		TaskID:     1,
+		Type:       models.ScannerTypeZap,
+		ScannerURL: server.URL,
+		APIKey:     "zap-key",
+		TargetURLs: []string{"https://app.example.com"},
+		Parameters: map[string]interface{}{},
+	}
+}
+
+func zapScanCount(d *ZapDriver) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.scans)
+}
+
+func TestZAPDriverRun(t *testing.T) {
+	fake := &fakeZAP{ascanStatus: "100"}
+	server := httptest.NewServer(fake.handler())
+	defer server.Close()
+
+	driver := &ZapDriver{}
+	results, err := Run(context.Background(), driver, newZAPJob(server), RunOptions{PollInterval: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Run 返回错误: %v", err)
+	}
+
+	// 信息级别和误报的告警不导入
+	if len(results) != 1 {
+		t.Fatalf("扫描结果数量 = %d, want 1: %+v", len(results), results)
+	}
+	r := results[0]
+	if r.VulnerabilityName != "SQL Injection" || r.Severity != models.SeverityHigh ||
+		r.AffectedURL != "https://app.example.com/item?id=1" || r.Category != "CWE-89" {
+		t.Errorf("扫描结果 = %+v", r)
+	}
+	if !strings.Contains(r.Detail, "Parameter: id") || !strings.Contains(r.References, "ZAP Plugin ID: 40018") {
+		t.Errorf("扫描结果详情 = %q, 参考 = %q", r.Detail, r.References)
+	}
+	if len(r.Evidence) != 2 {
+		t.Errorf("HTTP请求和响应应作为证据保存: %d", len(r.Evidence))
+	}
+
+	for _, key := range fake.apiKeys {
+		if key != "zap-key" {
+			t.Fatalf("X-ZAP-API-Key = %q", key)
+		}
+	}
+	if n := zapScanCount(driver); n != 0 {
+		t.Errorf("获取结果后应清理扫描状态，剩余 %d 个", n)
+	}
+}
+
+func TestZAPDriverFailed(t *testing.T) {
+	fake := &fakeZAP{spiderFail: true}
+	server := httptest.NewServer(fake.handler())
+	defer server.Close()
+
+	driver := &ZapDriver{}
+	_, err := Run(context.Background(), driver, newZAPJob(server), RunOptions{PollInterval: 5 * time.Millisecond})
+	if err == nil || !strings.Contains(err.Error(), "启动ZAP爬虫失败") {
+		t.Fatalf("启动爬虫失败时应返回错误，实际为 %v", err)
+	}
+	if reason := ClassifyError(err); reason != models.ScanFailureScanner {
+		t.Errorf("失败原因 = %s, want %s", reason, models.ScanFailureScanner)
+	}
+	if n := zapScanCount(driver); n != 0 {
+		t.Errorf("失败的扫描应清理扫描状态，剩余 %d 个", n)
+	}
+}
+
+func TestZAPDriverCancel(t *testing.T) {
+	fake := &fakeZAP{ascanStatus: "10"}
+	server := httptest.NewServer(fake.handler())
+	defer server.Close()
+
+	driver := &ZapDriver{}
+	ctx, cancel := context.WithCancel(context.Background())
+	results, err := Run(ctx, driver, newZAPJob(server), RunOptions{
+		PollInterval: 5 * time.Millisecond,
+		OnStatus: func(s *Status) {
+			if strings.HasPrefix(s.Message, "active scan") {
+				cancel()
+			}
+		},
+		KeepPartial: func() bool { return true },
+	})
+	if err != context.Canceled {
+		t.Fatalf("错误 = %v, want context.Canceled", err)
+	}
+	if !fake.called("ascan/action/stop") {
+		t.Error("取消时应停止ZAP上的主动扫描")
+	}
+	if len(results) != 1 {
+		t.Errorf("保留部分结果时应返回已发现的告警: %+v", results)
+	}
+	if n := zapScanCount(driver); n != 0 {
+		t.Errorf("取消后应清理扫描状态，剩余 %d 个", n)
+	}
+}
+
+func TestZAPDriverAjaxSpiderSerialized(t *testing.T) {
+	fake := &fakeZAP{ascanStatus: "100", ajaxPolls: 3}
+	server := httptest.NewServer(fake.handler())
+	defer server.Close()
+
+	// 两个任务共用同一个ZAP，AJAX爬虫阶段应依次执行
+	driver := &ZapDriver{pollInterval: time.Millisecond}
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			job := newZAPJob(server)
+			job.TaskID = uint(i + 1)
+			job.Parameters["ajax_spider"] = true
+			_, errs[i] = Run(context.Background(), driver, job, RunOptions{PollInterval: 5 * time.Millisecond})
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("任务 %d: Run 返回错误: %v", i+1, err)
+		}
+	}
+	if fake.count("ajaxSpider/action/scan") != 2 {
+		t.Errorf("AJAX爬虫启动次数 = %d, want 2", fake.count("ajaxSpider/action/scan"))
+	}
+	if fake.ajaxOverlap {
+		t.Error("AJAX爬虫运行期间不应被其他任务再次启动")
+	}
+}
+
+func TestZAPDriverCancelAjaxSpider(t *testing.T) {
+	fake := &fakeZAP{ascanStatus: "100", ajaxPolls: 1 << 30}
+	server := httptest.NewServer(fake.handler())
+	defer server.Close()
+
+	driver := &ZapDriver{pollInterval: time.Millisecond}
+	job := newZAPJob(server)
+	job.Parameters["ajax_spider"] = true
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := Run(ctx, driver, job, RunOptions{
+		PollInterval: 5 * time.Millisecond,
+		OnStatus: func(s *Status) {
+			if strings.HasPrefix(s.Message, "ajax spider") && fake.called("ajaxSpider/view/status") {
+				cancel()
+			}
+		},
+	})
+	if err != context.Canceled {
+		t.Fatalf("错误 = %v, want context.Canceled", err)
+	}
+
+	// 取消后AJAX爬虫被停止，使用权释放给后续任务
+	deadline := time.Now().Add(5 * time.Second)
+	for !fake.called("ajaxSpider/action/stop") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !fake.called("ajaxSpider/action/stop") {
+		t.Fatal("取消时应停止AJAX爬虫")
+	}
+	fake.mu.Lock()
+	fake.ajaxPolls = 0
+	fake.mu.Unlock()
+	next := newZAPJob(server)
+	next.Parameters["ajax_spider"] = true
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer waitCancel()
+	if _, err := Run(waitCtx, driver, next, RunOptions{PollInterval: 5 * time.Millisecond}); err != nil {
+		t.Errorf("后续任务 Run 返回错误: %v", err)
+	}
+}
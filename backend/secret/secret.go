@@ -0,0 +1,345 @@
+// Package secret 提供敏感配置（扫描器凭据、集成令牌等）的静态加密
+//
+// 采用信封加密：每个值使用随机生成的数据密钥（AES-256-GCM）加密，数据密钥再由主密钥加密后与密文一起保存。
+// 密文格式为 "enc:v1:<主密钥ID>:<加密的数据密钥>:<密文>"，后两段为 base64 编码，
+// 主密钥ID用于密钥轮换后找到对应的旧密钥解密。未加 "enc:" 前缀的值视为尚未加密的明文。
+//
+// 主密钥通过配置 security.encryption 或环境变量设置：
+//
+//	VULNARK_MASTER_KEY       base64 编码的32字节主密钥
+//	VULNARK_MASTER_KEY_ID    主密钥ID，默认为 default
+//	VULNARK_PREVIOUS_KEYS    轮换后仍需用于解密的旧密钥，格式为 "id:key,id:key"
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	prefix       = "enc:v1:"
+	keySize      = 32
+	defaultKeyID = "default"
+)
+
+var (
+	// ErrNoMasterKey 未配置主密钥
+	ErrNoMasterKey = errors.New("未配置加密主密钥")
+	// ErrUnknownKey 密文使用的主密钥不在当前密钥环中
+	ErrUnknownKey = errors.New("未找到密文对应的主密钥")
+	// ErrMalformed 密文格式错误
+	ErrMalformed = errors.New("密文格式错误")
+)
+
+// keyring 主密钥环，primary 用于加密，其余密钥只用于解密
+type keyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+var (
+	ringOnce sync.Once
+	ring     *keyring
+	ringErr  error
+	warnOnce sync.Once
+)
+
+// loadKeyring 从环境变量或配置中加载主密钥，环境变量优先
+func loadKeyring() (*keyring, error) {
+	ringOnce.Do(func() {
+		ring, ringErr = newKeyring(
+			configValue("VULNARK_MASTER_KEY", "security.encryption.master_key"),
+			configValue("VULNARK_MASTER_KEY_ID", "security.encryption.key_id"),
+			previousKeys(),
+		)
+	})
+	return ring, ringErr
+}
+
+// configValue 优先读取环境变量，没有时读取配置文件
+func configValue(envKey, configKey string) string {
+	if value := os.Getenv(envKey); value != "" {
+		return value
+	}
+	return viper.GetString(configKey)
+}
+
+// previousKeys 轮换后保留的旧密钥，键为密钥ID
+func previousKeys() map[string]string {
+	keys := make(map[string]string)
+	for id := range viper.GetStringMapString("security.encryption.previous_keys") {
+		keys[id] = viper.GetString("security.encryption.previous_keys." + id)
+	}
+	for _, pair := range strings.Split(os.Getenv("VULNARK_PREVIOUS_KEYS"), ",") {
+		if id, key := splitPair(pair); id != "" && key != "" {
+			keys[id] = key
+		}
+	}
+	return keys
+}
+
+func splitPair(pair string) (string, string) {
+	parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+}
+
+// newKeyring 根据主密钥和旧密钥创建密钥环，主密钥为空时返回 nil
+func newKeyring(masterKey, keyID string, previous map[string]string) (*keyring, error) {
+	if keyID == "" {
+		keyID = defaultKeyID
+	}
+	if masterKey == "" {
+		if len(previous) > 0 {
+			return nil, fmt.Errorf("配置了旧密钥但未配置主密钥")
+		}
+		return nil, nil
+	}
+
+	r := &keyring{primary: keyID, keys: make(map[string][]byte)}
+	for id, encoded := range previous {
+		key, err := decodeKey(id, encoded)
+		if err != nil {
+			return nil, err
+		}
+		r.keys[id] = key
+	}
+	key, err := decodeKey(keyID, masterKey)
+	if err != nil {
+		return nil, err
+	}
+	r.keys[keyID] = key
+	return r, nil
+}
+
+// decodeKey 解码并校验 base64 编码的主密钥
+func decodeKey(id, encoded string) ([]byte, error) {
+	if strings.Contains(id, ":") {
+		return nil, fmt.Errorf("主密钥ID %q 不能包含冒号", id)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("主密钥 %s 不是有效的 base64 编码: %v", id, err)
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("主密钥 %s 长度应为%d字节，实际为%d字节", id, keySize, len(key))
+	}
+	return key, nil
+}
+
+// Validate 校验主密钥配置，配置错误时返回错误
+func Validate() error {
+	_, err := loadKeyring()
+	return err
+}
+
+// Enabled 是否已配置主密钥，未配置时敏感字段以明文保存
+func Enabled() bool {
+	r, err := loadKeyring()
+	return err == nil && r != nil
+}
+
+// PrimaryKeyID 当前用于加密的主密钥ID，未配置主密钥时返回空字符串
+func PrimaryKeyID() string {
+	r, err := loadKeyring()
+	if err != nil || r == nil {
+		return ""
+	}
+	return r.primary
+}
+
+// IsEncrypted 判断值是否为密文
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// KeyID 返回密文使用的主密钥ID，值不是密文时返回空字符串
+func KeyID(value string) string {
+	if !IsEncrypted(value) {
+		return ""
+	}
+	parts := strings.SplitN(strings.TrimPrefix(value, prefix), ":", 3)
+	if len(parts) != 3 {
+		return ""
+	}
+	return parts[0]
+}
+
+// NeedsRotation 判断值是否需要用当前主密钥重新加密：明文或由旧密钥加密的密文
+func NeedsRotation(value string) bool {
+	if value == "" || !Enabled() {
+		return false
+	}
+	return KeyID(value) != PrimaryKeyID()
+}
+
+// Encrypt 使用当前主密钥加密，空值和已加密的值原样返回
+//
+// 未配置主密钥时原样返回明文并记录一次警告。
+func Encrypt(plaintext string) (string, error) {
+	if plaintext == "" || IsEncrypted(plaintext) {
+		return plaintext, nil
+	}
+
+	r, err := loadKeyring()
+	if err != nil {
+		return "", err
+	}
+	if r == nil {
+		warnOnce.Do(func() {
+			log.Printf("警告: 未配置加密主密钥（security.encryption.master_key 或 VULNARK_MASTER_KEY），敏感字段将以明文保存")
+		})
+		return plaintext, nil
+	}
+
+	dataKey := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", fmt.Errorf("生成数据密钥失败: %v", err)
+	}
+	wrapped, err := seal(r.keys[r.primary], dataKey)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+
+	return prefix + r.primary + ":" +
+		base64.RawURLEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密密文，明文原样返回
+func Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(value, prefix), ":", 3)
+	if len(parts) != 3 {
+		return "", ErrMalformed
+	}
+	wrapped, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrMalformed
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrMalformed
+	}
+
+	r, err := loadKeyring()
+	if err != nil {
+		return "", err
+	}
+	if r == nil {
+		return "", ErrNoMasterKey
+	}
+	masterKey, ok := r.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, parts[0])
+	}
+
+	dataKey, err := open(masterKey, wrapped)
+	if err != nil {
+		return "", fmt.Errorf("解密数据密钥失败: %v", err)
+	}
+	plaintext, err := open(dataKey, sealed)
+	if err != nil {
+		return "", fmt.Errorf("解密数据失败: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+// Rotate 使用当前主密钥重新加密，明文会被加密，已使用当前主密钥的密文原样返回
+func Rotate(value string) (string, error) {
+	if !NeedsRotation(value) {
+		return value, nil
+	}
+	plaintext, err := Decrypt(value)
+	if err != nil {
+		return "", err
+	}
+	return Encrypt(plaintext)
+}
+
+// EncryptFields 原地加密多个字段，任一字段失败时返回错误
+func EncryptFields(fields ...*string) error {
+	for _, field := range fields {
+		value, err := Encrypt(*field)
+		if err != nil {
+			return err
+		}
+		*field = value
+	}
+	return nil
+}
+
+// DecryptFields 原地解密多个字段，任一字段失败时返回错误
+func DecryptFields(fields ...*string) error {
+	for _, field := range fields {
+		value, err := Decrypt(*field)
+		if err != nil {
+			return err
+		}
+		*field = value
+	}
+	return nil
+}
+
+// GenerateKey 生成 base64 编码的随机主密钥
+func GenerateKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// seal 使用 AES-256-GCM 加密，返回 nonce 与密文的拼接
+func seal(key, plaintext []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("生成随机数失败: %v", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// open 解密 seal 的输出
+func open(key, data []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,147 @@
+package secret
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// useKeyring 替换测试使用的密钥环，测试结束后恢复
+func useKeyring(t *testing.T, masterKey, keyID string, previous map[string]string) {
+	t.Helper()
+	ringOnce.Do(func() {})
+
+	r, err := newKeyring(masterKey, keyID, previous)
+	if err != nil {
+		t.Fatalf("创建密钥环失败: %v", err)
+	}
+	saved := ring
+	ring, ringErr = r, nil
+	t.Cleanup(func() { ring, ringErr = saved, nil })
+}
+
+func generateKey(t *testing.T) string {
+	t.Helper()
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("生成主密钥失败: %v", err)
+	}
+	return key
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	useKeyring(t, generateKey(t), "k1", nil)
+
+	encrypted, err := Encrypt("accessKey=abc; secretKey=def")
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	if !IsEncrypted(encrypted) || KeyID(encrypted) != "k1" {
+		t.Fatalf("密文格式错误: %s", encrypted)
+	}
+	if strings.Contains(encrypted, "secretKey") {
+		t.Fatalf("密文中包含明文: %s", encrypted)
+	}
+
+	again, err := Encrypt("accessKey=abc; secretKey=def")
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	if again == encrypted {
+		t.Error("每次加密应使用不同的数据密钥和随机数")
+	}
+
+	plaintext, err := Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("解密失败: %v", err)
+	}
+	if plaintext != "accessKey=abc; secretKey=def" {
+		t.Errorf("解密结果 = %q", plaintext)
+	}
+
+	// 空值和已加密的值原样返回，明文解密原样返回
+	if v, _ := Encrypt(""); v != "" {
+		t.Errorf("Encrypt(\"\") = %q", v)
+	}
+	if v, _ := Encrypt(encrypted); v != encrypted {
+		t.Error("已加密的值不应重复加密")
+	}
+	if v, _ := Decrypt("plain"); v != "plain" {
+		t.Errorf("Decrypt(plain) = %q", v)
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	useKeyring(t, generateKey(t), "k1", nil)
+
+	encrypted, err := Encrypt("token")
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	tampered := encrypted[:len(encrypted)-2] + "AA"
+	if tampered == encrypted {
+		tampered = encrypted[:len(encrypted)-2] + "BB"
+	}
+	if _, err := Decrypt(tampered); err == nil {
+		t.Error("篡改后的密文应解密失败")
+	}
+	if _, err := Decrypt("enc:v1:k1:only-two"); !errors.Is(err, ErrMalformed) {
+		t.Errorf("格式错误的密文应返回 ErrMalformed，实际为 %v", err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	oldKey, newKey := generateKey(t), generateKey(t)
+
+	useKeyring(t, oldKey, "old", nil)
+	encrypted, err := Encrypt("password")
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+
+	// 轮换后旧密钥只用于解密
+	useKeyring(t, newKey, "new", map[string]string{"old": oldKey})
+	if !NeedsRotation(encrypted) || !NeedsRotation("plain") {
+		t.Fatal("旧密钥加密的密文和明文都需要轮换")
+	}
+	rotated, err := Rotate(encrypted)
+	if err != nil {
+		t.Fatalf("轮换失败: %v", err)
+	}
+	if KeyID(rotated) != "new" || NeedsRotation(rotated) {
+		t.Fatalf("轮换后应使用新主密钥: %s", rotated)
+	}
+	if same, _ := Rotate(rotated); same != rotated {
+		t.Error("已使用当前主密钥的密文应原样返回")
+	}
+	plaintext, err := Decrypt(rotated)
+	if err != nil || plaintext != "password" {
+		t.Fatalf("解密轮换后的密文 = %q, %v", plaintext, err)
+	}
+
+	// 移除旧密钥后无法再解密旧密文
+	useKeyring(t, newKey, "new", nil)
+	if _, err := Decrypt(encrypted); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("缺少旧密钥时应返回 ErrUnknownKey，实际为 %v", err)
+	}
+}
+
+func TestNewKeyringInvalid(t *testing.T) {
+	key := generateKey(t)
+	cases := []struct {
+		name      string
+		masterKey string
+		keyID     string
+		previous  map[string]string
+	}{
+		{"非base64", "not-base64!", "", nil},
+		{"长度错误", "c2hvcnQ=", "", nil},
+		{"密钥ID包含冒号", key, "a:b", nil},
+		{"只有旧密钥", "", "", map[string]string{"old": key}},
+	}
+	for _, c := range cases {
+		if _, err := newKeyring(c.masterKey, c.keyID, c.previous); err == nil {
+			t.Errorf("%s: 应返回错误", c.name)
+		}
+	}
+}
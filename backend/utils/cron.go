@@ -0,0 +1,233 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule 解析后的标准5段Cron表达式（分 时 日 月 周）
+type CronSchedule struct {
+	minute  uint64
+	hour    uint64
+	dom     uint64
+	month   uint64
+	dow     uint64
+	domStar bool
+	dowStar bool
+}
+
+// cron字段的取值范围
+type cronBounds struct {
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	cronMinute = cronBounds{0, 59, nil}
+	cronHour   = cronBounds{0, 23, nil}
+	cronDom    = cronBounds{1, 31, nil}
+	cronMonth  = cronBounds{1, 12, map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	cronDow = cronBounds{0, 7, map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// 预定义的Cron宏
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron 解析标准5段Cron表达式，支持 * , - / 以及月份和星期的英文缩写
+func ParseCron(expr string) (*CronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Cron表达式必须包含5个字段，实际为%d个: %q", len(fields), expr)
+	}
+
+	var (
+		schedule CronSchedule
+		err      error
+	)
+
+	if schedule.minute, err = parseCronField(fields[0], cronMinute); err != nil {
+		return nil, fmt.Errorf("分钟字段无效: %v", err)
+	}
+	if schedule.hour, err = parseCronField(fields[1], cronHour); err != nil {
+		return nil, fmt.Errorf("小时字段无效: %v", err)
+	}
+	if schedule.dom, err = parseCronField(fields[2], cronDom); err != nil {
+		return nil, fmt.Errorf("日期字段无效: %v", err)
+	}
+	if schedule.month, err = parseCronField(fields[3], cronMonth); err != nil {
+		return nil, fmt.Errorf("月份字段无效: %v", err)
+	}
+	if schedule.dow, err = parseCronField(fields[4], cronDow); err != nil {
+		return nil, fmt.Errorf("星期字段无效: %v", err)
+	}
+
+	// 星期字段中的7与0同为周日
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow |= 1
+	}
+
+	schedule.domStar = strings.HasPrefix(fields[2], "*")
+	schedule.dowStar = strings.HasPrefix(fields[4], "*")
+
+	return &schedule, nil
+}
+
+// parseCronField 将单个字段解析为位集合
+func parseCronField(field string, bounds cronBounds) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		if part == "" {
+			return 0, fmt.Errorf("存在空的列表项: %q", field)
+		}
+
+		rangePart, step := part, uint(1)
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			n, err := strconv.Atoi(part[idx+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("无效的步长: %q", part)
+			}
+			rangePart, step = part[:idx], uint(n)
+		}
+
+		var start, end uint
+		switch {
+		case rangePart == "*":
+			start, end = bounds.min, bounds.max
+		case strings.Contains(rangePart, "-"):
+			ends := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if start, err = parseCronValue(ends[0], bounds); err != nil {
+				return 0, err
+			}
+			if end, err = parseCronValue(ends[1], bounds); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("范围起点大于终点: %q", rangePart)
+			}
+		default:
+			value, err := parseCronValue(rangePart, bounds)
+			if err != nil {
+				return 0, err
+			}
+			start, end = value, value
+			// 形如 5/10 表示从5开始到最大值，每10个单位一次
+			if strings.Contains(part, "/") {
+				end = bounds.max
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << v
+		}
+	}
+
+	return bits, nil
+}
+
+// parseCronValue 解析字段中的单个取值
+func parseCronValue(value string, bounds cronBounds) (uint, error) {
+	if bounds.names != nil {
+		if v, ok := bounds.names[strings.ToLower(value)]; ok {
+			return v, nil
+		}
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("无法解析的取值: %q", value)
+	}
+	if n < int(bounds.min) || n > int(bounds.max) {
+		return 0, fmt.Errorf("取值%d超出范围[%d, %d]", n, bounds.min, bounds.max)
+	}
+	return uint(n), nil
+}
+
+// Next 计算严格晚于给定时间的下一次触发时间，找不到时返回零值
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	// 从下一分钟开始，秒和纳秒清零
+	t = t.Add(time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+
+	// 最多向后查找5年，避免诸如 2月30日 这样的表达式死循环
+	yearLimit := t.Year() + 5
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for s.month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+
+	for !s.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+
+	for s.hour&(1<<uint(t.Hour())) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+
+	for s.minute&(1<<uint(t.Minute())) == 0 {
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+
+	return t
+}
+
+// dayMatches 判断日期是否同时满足“日”和“周”字段
+// 与标准cron一致：两个字段都有限制时，满足任意一个即可
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// NextCronTime 解析Cron表达式并计算给定时间之后的下一次触发时间
+func NextCronTime(expr string, from time.Time) (time.Time, error) {
+	schedule, err := ParseCron(expr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next := schedule.Next(from)
+	if next.IsZero() {
+		return next, fmt.Errorf("Cron表达式在未来5年内没有触发时间: %q", expr)
+	}
+	return next, nil
+}
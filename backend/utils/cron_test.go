@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"1,,2 * * * *",
+		"* * * foo *",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) 应返回错误", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	loc := time.UTC
+	from := time.Date(2024, 1, 31, 10, 17, 42, 0, loc) // 周三
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 31, 10, 18, 0, 0, loc)},
+		{"*/15 * * * *", time.Date(2024, 1, 31, 10, 30, 0, 0, loc)},
+		{"5/20 * * * *", time.Date(2024, 1, 31, 10, 25, 0, 0, loc)},
+		{"0 2 * * *", time.Date(2024, 2, 1, 2, 0, 0, 0, loc)},
+		{"@hourly", time.Date(2024, 1, 31, 11, 0, 0, 0, loc)},
+		{"@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, loc)},
+		{"0 9 * * mon-fri", time.Date(2024, 2, 1, 9, 0, 0, 0, loc)},
+		{"0 0 * * 7", time.Date(2024, 2, 4, 0, 0, 0, 0, loc)},
+		{"30 8 1,15 * *", time.Date(2024, 2, 1, 8, 30, 0, 0, loc)},
+		{"0 0 29 feb *", time.Date(2024, 2, 29, 0, 0, 0, 0, loc)},
+		{"0 0 31 * *", time.Date(2024, 3, 31, 0, 0, 0, 0, loc)},
+		// 日和周都有限制时满足任意一个即可
+		{"0 0 15 * fri", time.Date(2024, 2, 2, 0, 0, 0, 0, loc)},
+	}
+	for _, c := range cases {
+		got, err := NextCronTime(c.expr, from)
+		if err != nil {
+			t.Errorf("NextCronTime(%q) 返回错误: %v", c.expr, err)
+			continue
+		}
+		if !got.Equal(c.want) {
+			t.Errorf("NextCronTime(%q) = %s, want %s", c.expr, got, c.want)
+		}
+	}
+}
+
+func TestCronNextNeverFires(t *testing.T) {
+	if _, err := NextCronTime("0 0 30 feb *", time.Now()); err == nil {
+		t.Error("2月30日的表达式应返回错误")
+	}
+}
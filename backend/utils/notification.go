@@ -87,6 +87,10 @@ func NewNotificationManager() (*NotificationManager, error) {
 		settings.AI = getDefaultSettings().AI
 	}
 
+	if err := settings.DecryptSecrets(); err != nil {
+		log.Printf("解密设置中的密钥失败: %v", err)
+	}
+
 	log.Printf("通知管理器初始化成功")
 
 	return &NotificationManager{
@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/vulnark/vulnark/models"
+	"github.com/vulnark/vulnark/secret"
+)
+
+// SecretStats 敏感字段加密迁移统计
+type SecretStats struct {
+	ScanTasks       int // 重新加密的扫描任务数
+	ScannerProfiles int // 重新加密的扫描器连接配置数
+	Settings        int // 重新加密的系统设置记录数
+}
+
+// MigrateSecrets 启动时加密已有的明文敏感字段
+//
+// 扫描器凭据列扩展为 text 以容纳密文；未配置主密钥时只扩展列，不加密。
+func MigrateSecrets() {
+	if DBType != "mysql" || DB == nil {
+		return
+	}
+
+	for _, column := range []string{"scanner_api_key", "scanner_password"} {
+		widenColumnToText("scan_tasks", column)
+	}
+
+	if !secret.Enabled() {
+		log.Printf("未配置加密主密钥，跳过敏感字段加密迁移")
+		return
+	}
+
+	stats, err := reencryptSecrets(false, false)
+	if err != nil {
+		log.Printf("敏感字段加密迁移失败: %v", err)
+		return
+	}
+	if stats.ScanTasks > 0 || stats.ScannerProfiles > 0 || stats.Settings > 0 {
+		log.Printf("已加密明文敏感字段: 扫描任务=%d, 扫描器连接配置=%d, 系统设置=%d",
+			stats.ScanTasks, stats.ScannerProfiles, stats.Settings)
+	}
+}
+
+// RotateSecrets 使用当前主密钥重新加密所有敏感字段，dryRun 为 true 时只统计不写入
+func RotateSecrets(dryRun bool) (SecretStats, error) {
+	if !secret.Enabled() {
+		return SecretStats{}, secret.ErrNoMasterKey
+	}
+	return reencryptSecrets(true, dryRun)
+}
+
+// reencryptSecrets 加密明文字段，rotate 为 true 时同时重新加密旧密钥加密的字段
+//
+// 直接读写原始列值，不经过模型的加解密钩子。
+func reencryptSecrets(rotate, dryRun bool) (SecretStats, error) {
+	var stats SecretStats
+
+	convert := func(value string) (string, error) {
+		if rotate {
+			return secret.Rotate(value)
+		}
+		return secret.Encrypt(value)
+	}
+
+	// 扫描任务和扫描器连接配置中的凭据，包括已删除的记录
+	var err error
+	stats.ScanTasks, err = reencryptColumns("scan_tasks", []string{"scanner_api_key", "scanner_password"}, convert, dryRun)
+	if err != nil {
+		return stats, err
+	}
+	stats.ScannerProfiles, err = reencryptColumns("scanner_profiles", []string{"api_key", "password"}, convert, dryRun)
+	if err != nil {
+		return stats, err
+	}
+
+	// 系统设置中的集成和通知密钥
+	var (
+		id                                          uint
+		integrationsJSON, notificationsJSON, aiJSON []byte
+	)
+	row := DB.Raw("SELECT id, integrations, notifications, ai FROM settings WHERE id = ? LIMIT 1", 1).Row()
+	if err := row.Scan(&id, &integrationsJSON, &notificationsJSON, &aiJSON); err != nil {
+		// 还没有保存过设置
+		return stats, nil
+	}
+
+	var settings models.Settings
+	json.Unmarshal(integrationsJSON, &settings.Integrations)
+	json.Unmarshal(notificationsJSON, &settings.Notifications)
+	json.Unmarshal(aiJSON, &settings.AI)
+
+	changed := false
+	for _, field := range settings.SecretFields() {
+		value, err := convert(*field)
+		if err != nil {
+			return stats, fmt.Errorf("系统设置: %v", err)
+		}
+		if value != *field {
+			*field = value
+			changed = true
+		}
+	}
+	if !changed {
+		return stats, nil
+	}
+	stats.Settings++
+	if dryRun {
+		return stats, nil
+	}
+
+	integrations, _ := json.Marshal(settings.Integrations)
+	notifications, _ := json.Marshal(settings.Notifications)
+	ai, _ := json.Marshal(settings.AI)
+	err = DB.Exec("UPDATE settings SET integrations = ?, notifications = ?, ai = ? WHERE id = ?",
+		string(integrations), string(notifications), string(ai), id).Error
+	if err != nil {
+		return stats, fmt.Errorf("更新系统设置失败: %v", err)
+	}
+	return stats, nil
+}
+
+// reencryptColumns 逐行转换表中的敏感列，返回发生变化的行数
+func reencryptColumns(table string, columns []string, convert func(string) (string, error), dryRun bool) (int, error) {
+	rows, err := DB.Raw(fmt.Sprintf("SELECT id, %s FROM %s", strings.Join(columns, ", "), table)).Rows()
+	if err != nil {
+		return 0, fmt.Errorf("查询%s失败: %v", table, err)
+	}
+
+	type record struct {
+		id     uint
+		values []string
+	}
+	var records []record
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := []interface{}{new(uint)}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("读取%s失败: %v", table, err)
+		}
+		r := record{id: *dest[0].(*uint)}
+		for _, value := range values {
+			r.values = append(r.values, value.String)
+		}
+		records = append(records, r)
+	}
+	rows.Close()
+
+	changed := 0
+	for _, r := range records {
+		updates := make(map[string]interface{})
+		for i, column := range columns {
+			value, err := convert(r.values[i])
+			if err != nil {
+				return changed, fmt.Errorf("%s #%d: %v", table, r.id, err)
+			}
+			if value != r.values[i] {
+				updates[column] = value
+			}
+		}
+		if len(updates) == 0 {
+			continue
+		}
+		changed++
+		if dryRun {
+			continue
+		}
+		if err := DB.Table(table).Where("id = ?", r.id).UpdateColumns(updates).Error; err != nil {
+			return changed, fmt.Errorf("更新%s #%d 失败: %v", table, r.id, err)
+		}
+	}
+	return changed, nil
+}
+
+// widenColumnToText 将 varchar 列扩展为 text，AutoMigrate 不会修改已有列的类型
+func widenColumnToText(table, column string) {
+	var dataType string
+	err := DB.Raw("SELECT DATA_TYPE FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?",
+		table, column).Row().Scan(&dataType)
+	if err != nil || dataType != "varchar" {
+		return
+	}
+	if err := DB.Exec(fmt.Sprintf("ALTER TABLE %s MODIFY %s TEXT", table, column)).Error; err != nil {
+		log.Printf("修改%s.%s列类型为TEXT失败: %v", table, column, err)
+		return
+	}
+	log.Printf("%s.%s列类型已修改为TEXT", table, column)
+}